@@ -0,0 +1,374 @@
+// Package bondtest provides helpers for testing code built on Bond without
+// calling real model APIs. Its Recorder is an http.RoundTripper that records
+// the requests providers send and the responses they get to a cassette file,
+// and replays them later so that tests are deterministic and run offline.
+package bondtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Mode selects whether a Recorder records or replays interactions
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette and fails on requests
+	// that were not recorded
+	ModeReplay Mode = iota
+
+	// ModeRecord sends requests to the real API and records them
+	ModeRecord
+)
+
+// redacted replaces secrets in recorded interactions
+const redacted = "REDACTED"
+
+// RecordEnv is the environment variable that switches UseCassette to
+// recording, e.g. BOND_RECORD=1 go test ./...
+const RecordEnv = "BOND_RECORD"
+
+// DefaultRedactHeaders lists the headers providers use to send credentials
+var DefaultRedactHeaders = []string{"Authorization", "X-Api-Key", "Api-Key", "X-Goog-Api-Key", "Set-Cookie", "Cookie"}
+
+// DefaultRedactParams lists the query parameters some APIs accept credentials in
+var DefaultRedactParams = []string{"key", "api_key", "api-key"}
+
+// Interaction is a recorded request and the response it received
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the part of a request kept in a cassette
+type RecordedRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// RecordedResponse is the part of a response kept in a cassette. Streamed
+// responses are stored whole and replayed in one piece.
+type RecordedResponse struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body"`
+}
+
+// Recorder is an http.RoundTripper that records interactions to a cassette
+// file or replays them from it. Plug it into a provider through its HTTP
+// client, e.g. provider.HTTPClient = recorder.Client() for Claude or
+// client.HttpClient = recorder.Client() for providers built on common.BaseClient.
+//
+// Requests are matched on method, URL and body. JSON bodies are normalised so
+// that key order and whitespace do not matter, and identical requests are
+// replayed in the order they were recorded. Credentials in headers and query
+// parameters are redacted before anything is written to disk.
+// A Recorder is safe for concurrent use.
+type Recorder struct {
+	// Path is the cassette file
+	Path string
+
+	// Mode selects recording or replaying
+	Mode Mode
+
+	// Transport sends requests while recording, http.DefaultTransport if nil
+	Transport http.RoundTripper
+
+	// RedactHeaders lists the headers whose values are replaced by REDACTED
+	RedactHeaders []string
+
+	// RedactParams lists the query parameters whose values are replaced by REDACTED
+	RedactParams []string
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewRecorder creates a recorder for the cassette at path. In ModeReplay the
+// cassette is loaded and must exist; in ModeRecord it is written by Save.
+func NewRecorder(path string, mode Mode) (*Recorder, error) {
+	recorder := &Recorder{
+		Path:          path,
+		Mode:          mode,
+		RedactHeaders: DefaultRedactHeaders,
+		RedactParams:  DefaultRedactParams,
+	}
+
+	if mode == ModeReplay {
+		if err := recorder.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	return recorder, nil
+}
+
+// UseCassette returns an HTTP client that replays the cassette at path, or
+// records it when the BOND_RECORD environment variable is set. Recorded
+// cassettes are saved when the test finishes. Cassettes conventionally live
+// under testdata, e.g. UseCassette(t, "testdata/weather_tool.json").
+func UseCassette(t testing.TB, path string) *http.Client {
+	t.Helper()
+
+	mode := ModeReplay
+	if os.Getenv(RecordEnv) != "" {
+		mode = ModeRecord
+	}
+
+	recorder, err := NewRecorder(path, mode)
+	if err != nil {
+		t.Fatalf("failed to load cassette: %v", err)
+	}
+
+	if mode == ModeRecord {
+		t.Cleanup(func() {
+			if err := recorder.Save(); err != nil {
+				t.Errorf("failed to save cassette: %v", err)
+			}
+		})
+	}
+
+	return recorder.Client()
+}
+
+// Client returns an HTTP client that sends its requests through the recorder.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Interactions returns the interactions recorded or loaded so far.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.interactions...)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := RecordedRequest{
+		Method:  req.Method,
+		URL:     r.redactURL(req.URL),
+		Headers: r.redactHeaders(req.Header),
+		Body:    string(body),
+	}
+
+	if r.Mode == ModeRecord {
+		return r.record(req, recorded)
+	}
+	return r.replay(req, recorded)
+}
+
+// record sends the request with the real transport and keeps the interaction
+func (r *Recorder) record(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	// The body of req was consumed by readBody, the transport is sent a copy
+	if req.Body != nil && req.Body != http.NoBody {
+		body := recorded.Body
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(strings.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(body)), nil
+		}
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response while recording: %w", err)
+	}
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    r.redactHeaders(resp.Header),
+			Body:       string(body),
+		},
+	})
+	r.used = append(r.used, true)
+	r.mu.Unlock()
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// replay serves the first unused interaction matching the request
+func (r *Recorder) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := matchKey(recorded)
+	for i, interaction := range r.interactions {
+		if r.used[i] || matchKey(interaction.Request) != key {
+			continue
+		}
+		r.used[i] = true
+
+		header := make(http.Header, len(interaction.Response.Headers))
+		for name, value := range interaction.Response.Headers {
+			header.Set(name, value)
+		}
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("bondtest: no recorded interaction in %s for %s %s", r.Path, recorded.Method, recorded.URL)
+}
+
+// Save writes the recorded interactions to the cassette file, creating its
+// directory if needed.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	data, err := json.MarshalIndent(struct {
+		Interactions []Interaction `json:"interactions"`
+	}{r.interactions}, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(r.Path, append(data, '\n'), 0o644)
+}
+
+// load reads the interactions from the cassette file
+func (r *Recorder) load() error {
+	data, err := os.ReadFile(r.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var cassette struct {
+		Interactions []Interaction `json:"interactions"`
+	}
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return fmt.Errorf("failed to parse cassette %s: %w", r.Path, err)
+	}
+
+	r.interactions = cassette.Interactions
+	r.used = make([]bool, len(cassette.Interactions))
+	return nil
+}
+
+// redactHeaders flattens the headers, replacing the values of secret ones
+func (r *Recorder) redactHeaders(header http.Header) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+
+	flat := make(map[string]string, len(header))
+	for name := range header {
+		flat[name] = header.Get(name)
+	}
+
+	for _, name := range r.RedactHeaders {
+		canonical := http.CanonicalHeaderKey(name)
+		if _, ok := flat[canonical]; ok {
+			flat[canonical] = redacted
+		}
+	}
+
+	return flat
+}
+
+// redactURL returns the URL with the values of secret query parameters replaced
+func (r *Recorder) redactURL(u *url.URL) string {
+	query := u.Query()
+	changed := false
+	for _, param := range r.RedactParams {
+		if query.Has(param) {
+			query.Set(param, redacted)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return u.String()
+	}
+
+	clean := *u
+	clean.RawQuery = query.Encode()
+	return clean.String()
+}
+
+// readBody reads and closes the request body. The request itself is left
+// untouched, as http.RoundTripper requires.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	return body, nil
+}
+
+// matchKey identifies a request by method, URL and normalised body
+func matchKey(req RecordedRequest) string {
+	return req.Method + " " + req.URL + "\n" + normaliseBody(req.Body)
+}
+
+// normaliseBody re-encodes JSON bodies so that key order and whitespace are
+// irrelevant when matching. Other bodies are only trimmed.
+func normaliseBody(body string) string {
+	var decoded any
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return strings.TrimSpace(body)
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return strings.TrimSpace(body)
+	}
+
+	normalised, err := json.Marshal(decoded)
+	if err != nil {
+		return strings.TrimSpace(body)
+	}
+	return string(normalised)
+}
@@ -0,0 +1,169 @@
+package bondtest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/devOpifex/bond/models"
+	"github.com/devOpifex/bond/providers/claude"
+	"github.com/devOpifex/bond/providers/openai"
+)
+
+// TestRecordReplay tests that a recorded exchange replays without the server and without secrets
+func TestRecordReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Request-Id", "req_123")
+		w.Write([]byte(`{
+			"id": "msg_0",
+			"model": "claude-3-5-sonnet-20241022",
+			"content": [{"type": "text", "text": "Hello there!"}],
+			"stop_reason": "end_turn",
+			"usage": {"input_tokens": 10, "output_tokens": 3}
+		}`))
+	}))
+
+	path := filepath.Join(t.TempDir(), "testdata", "claude_hello.json")
+	message := models.Message{Role: models.RoleUser, Content: "Hello"}
+
+	recorder, err := NewRecorder(path, ModeRecord)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+
+	provider := claude.NewClient("sk-ant-secret")
+	provider.BaseURL = server.URL
+	provider.HTTPClient = recorder.Client()
+
+	if _, err := provider.SendMessage(context.Background(), message); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("Failed to save cassette: %v", err)
+	}
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read cassette: %v", err)
+	}
+	if strings.Contains(string(data), "sk-ant-secret") {
+		t.Errorf("Expected the API key to be redacted, got %s", data)
+	}
+
+	replayer, err := NewRecorder(path, ModeReplay)
+	if err != nil {
+		t.Fatalf("Failed to load cassette: %v", err)
+	}
+	provider.HTTPClient = replayer.Client()
+
+	response, err := provider.SendMessage(context.Background(), message)
+	if err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+
+	if response != "Hello there!" {
+		t.Errorf("Expected 'Hello there!', got '%s'", response)
+	}
+
+	// Each interaction is replayed once
+	if _, err := provider.SendMessage(context.Background(), message); err == nil {
+		t.Errorf("Expected an error once the cassette is exhausted")
+	}
+}
+
+// TestReplayMatching tests that JSON bodies match regardless of formatting but not of content
+func TestReplayMatching(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "openai.json")
+	cassette := `{"interactions": [{
+		"request": {
+			"method": "POST",
+			"url": "https://api.openai.com/v1/chat/completions",
+			"body": "{\"model\": \"gpt-4o\", \"temperature\": 0.7, \"max_tokens\": 1000, \"messages\": [{\"role\": \"user\", \"content\": \"Hi\"}]}"
+		},
+		"response": {
+			"status_code": 200,
+			"headers": {"Content-Type": "application/json"},
+			"body": "{\"model\": \"gpt-4o\", \"choices\": [{\"message\": {\"role\": \"assistant\", \"content\": \"Hi!\"}, \"finish_reason\": \"stop\"}]}"
+		}
+	}]}`
+	if err := os.WriteFile(path, []byte(cassette), 0o644); err != nil {
+		t.Fatalf("Failed to write cassette: %v", err)
+	}
+
+	recorder, err := NewRecorder(path, ModeReplay)
+	if err != nil {
+		t.Fatalf("Failed to load cassette: %v", err)
+	}
+
+	client := openai.NewClient("sk-secret")
+	client.SetModel("gpt-4o")
+	client.HttpClient = recorder.Client()
+
+	if _, err := client.SendMessage(context.Background(), models.Message{Role: models.RoleUser, Content: "Bye"}); err == nil {
+		t.Errorf("Expected a different body not to match")
+	}
+
+	response, err := client.SendMessage(context.Background(), models.Message{Role: models.RoleUser, Content: "Hi"})
+	if err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+
+	if response != "Hi!" {
+		t.Errorf("Expected 'Hi!', got '%s'", response)
+	}
+}
+
+// TestRedactURL tests that credentials in query parameters are redacted
+func TestRedactURL(t *testing.T) {
+	recorder := &Recorder{RedactParams: DefaultRedactParams}
+
+	req := httptest.NewRequest("POST", "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:generateContent?key=AIza-secret", nil)
+	redactedURL := recorder.redactURL(req.URL)
+
+	if strings.Contains(redactedURL, "AIza-secret") || !strings.Contains(redactedURL, "key=REDACTED") {
+		t.Errorf("Expected the key to be redacted, got %s", redactedURL)
+	}
+}
+
+// TestRecordLeavesRequest tests that recording sends the body without modifying the caller's request
+func TestRecordLeavesRequest(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	recorder, err := NewRecorder(filepath.Join(t.TempDir(), "cassette.json"), ModeRecord)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", server.URL, strings.NewReader(`{"model":"gpt-4o"}`))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	body := req.Body
+
+	resp, err := recorder.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	resp.Body.Close()
+
+	if received != `{"model":"gpt-4o"}` {
+		t.Errorf("Expected the body to be sent, got '%s'", received)
+	}
+	if req.Body != body {
+		t.Errorf("Expected the request body to be left in place")
+	}
+}
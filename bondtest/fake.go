@@ -0,0 +1,494 @@
+package bondtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// ErrNoResponse is returned by a FakeProvider asked for more responses than scripted
+var ErrNoResponse = errors.New("bondtest: no scripted response left")
+
+// Response is a scripted answer of a FakeProvider to a single request
+type Response struct {
+	// Text is the text of the answer
+	Text string
+
+	// Thinking is the reasoning preceding the answer, as returned by providers
+	// with extended thinking
+	Thinking string
+
+	// ToolCalls are the tools the model calls. With tools enabled, the fake
+	// executes them with the registered tools and answers the follow-up
+	// request with the next scripted response, as real providers do.
+	ToolCalls []models.ToolUse
+
+	// Err, if set, is returned instead of an answer
+	Err error
+
+	// Delay is waited before answering, or until the context is done
+	Delay time.Duration
+
+	// Usage is reported to the context's usage tracker
+	Usage models.Usage
+}
+
+// Reply scripts a text answer.
+func Reply(text string) Response {
+	return Response{Text: text}
+}
+
+// CallTool scripts an answer calling a single tool with the given input,
+// which is encoded to JSON.
+func CallTool(name string, input any) Response {
+	return Response{ToolCalls: []models.ToolUse{{Name: name, Input: input}}}
+}
+
+// Fail scripts a request failing with err.
+func Fail(err error) Response {
+	return Response{Err: err}
+}
+
+// Request is a request received by a FakeProvider
+type Request struct {
+	// Messages are the messages sent, including earlier turns of the conversation
+	Messages []models.Message
+
+	// SystemPrompt is the system prompt in effect for the request
+	SystemPrompt string
+
+	// Tools are the names of the tools offered to the model, nil without tools
+	Tools []string
+
+	// Model is the configured model
+	Model string
+
+	// Options are the generation options carried by the request's context
+	Options models.GenerationOptions
+}
+
+// LastMessage returns the last message of the request.
+func (r Request) LastMessage() models.Message {
+	if len(r.Messages) == 0 {
+		return models.Message{}
+	}
+	return r.Messages[len(r.Messages)-1]
+}
+
+// ToolExecution records a tool run by a FakeProvider on behalf of the model
+type ToolExecution struct {
+	Call   models.ToolUse
+	Result string
+	Err    error
+}
+
+// FakeProvider is a models.Provider answering from a queue of scripted
+// responses, for testing agents and chains offline. It records every request,
+// system prompt and tool it receives. Like real providers it checks the
+// context's budget and reports usage, and it runs the tool loop itself when
+// tools are enabled. A FakeProvider is safe for concurrent use.
+type FakeProvider struct {
+	mu           sync.Mutex
+	responses    []Response
+	requests     []Request
+	executions   []ToolExecution
+	tools        []models.ToolExecutor
+	mcps         []string
+	systemPrompt string
+	model        string
+	maxTokens    int
+	temperature  float64
+	calls        int
+}
+
+// FakeProvider must satisfy the models.Provider interface
+var _ models.Provider = (*FakeProvider)(nil)
+
+// NewFakeProvider creates a fake provider answering with the given responses in order.
+func NewFakeProvider(responses ...Response) *FakeProvider {
+	return &FakeProvider{
+		responses: responses,
+		model:     "fake-model",
+	}
+}
+
+// Enqueue appends responses to the script and returns the provider for method chaining.
+func (f *FakeProvider) Enqueue(responses ...Response) *FakeProvider {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses = append(f.responses, responses...)
+	return f
+}
+
+// SendMessage answers with the next scripted response, without tools.
+// This implements part of the models.Provider interface.
+func (f *FakeProvider) SendMessage(ctx context.Context, message models.Message) (string, error) {
+	return f.send(ctx, models.NewConversation("").Append(message), false)
+}
+
+// SendMessageWithTools answers with the next scripted response, running any
+// scripted tool calls.
+// This implements part of the models.Provider interface.
+func (f *FakeProvider) SendMessageWithTools(ctx context.Context, message models.Message) (string, error) {
+	return f.send(ctx, models.NewConversation("").Append(message), true)
+}
+
+// SendConversation answers with the next scripted response, running any scripted
+// tool calls, and appends every turn to the conversation.
+// This implements part of the models.Provider interface.
+func (f *FakeProvider) SendConversation(ctx context.Context, conversation *models.Conversation) (string, error) {
+	return f.send(ctx, conversation, true)
+}
+
+// send runs the tool loop over the scripted responses
+func (f *FakeProvider) send(ctx context.Context, conversation *models.Conversation, withTools bool) (string, error) {
+	for {
+		if err := models.CheckBudget(ctx, conversation.Messages); err != nil {
+			return "", err
+		}
+
+		if err := models.FitConversation(ctx, conversation); err != nil {
+			return "", err
+		}
+
+		response, err := f.next(ctx, conversation, withTools)
+		if err != nil {
+			return "", err
+		}
+
+		assistant := f.assistantMessage(response)
+		conversation.Append(assistant)
+
+		if len(response.ToolCalls) == 0 || !withTools {
+			return assistant.Text(), nil
+		}
+
+		conversation.Append(f.runTools(ctx, assistant.Blocks))
+	}
+}
+
+// next records the request and returns the next scripted response
+func (f *FakeProvider) next(ctx context.Context, conversation *models.Conversation, withTools bool) (Response, error) {
+	f.mu.Lock()
+	request := Request{
+		Messages:     append([]models.Message(nil), conversation.Messages...),
+		SystemPrompt: f.systemPrompt,
+		Model:        f.model,
+		Options:      models.OptionsFrom(ctx),
+	}
+	if conversation.SystemPrompt != "" {
+		request.SystemPrompt = conversation.SystemPrompt
+	}
+	if withTools {
+		request.Tools = make([]string, 0, len(f.tools))
+		for _, tool := range f.tools {
+			request.Tools = append(request.Tools, tool.GetName())
+		}
+	}
+	f.requests = append(f.requests, request)
+
+	if len(f.responses) == 0 {
+		f.mu.Unlock()
+		return Response{}, ErrNoResponse
+	}
+	response := f.responses[0]
+	f.responses = f.responses[1:]
+	model := f.model
+	f.mu.Unlock()
+
+	if response.Delay > 0 {
+		select {
+		case <-time.After(response.Delay):
+		case <-ctx.Done():
+			return Response{}, ctx.Err()
+		}
+	}
+
+	if response.Err != nil {
+		return Response{}, response.Err
+	}
+
+	models.ReportUsage(ctx, model, response.Usage)
+	return response, nil
+}
+
+// assistantMessage converts a scripted response to the assistant's turn,
+// giving tool calls without an ID a generated one
+func (f *FakeProvider) assistantMessage(response Response) models.Message {
+	if len(response.ToolCalls) == 0 && response.Thinking == "" {
+		return models.Message{Role: models.RoleAssistant, Content: response.Text}
+	}
+
+	message := models.Message{Role: models.RoleAssistant}
+	if response.Thinking != "" {
+		message.Blocks = append(message.Blocks, models.ThinkingBlock(response.Thinking, "fake_signature"))
+	}
+	if response.Text != "" {
+		message.Blocks = append(message.Blocks, models.TextBlock(response.Text))
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, call := range response.ToolCalls {
+		f.calls++
+		id := call.ID
+		if id == "" {
+			id = fmt.Sprintf("fake_call_%d", f.calls)
+		}
+
+		input, err := json.Marshal(call.Input)
+		if err != nil {
+			input = []byte("{}")
+		}
+		message.Blocks = append(message.Blocks, models.ToolUseBlock(id, call.Name, input))
+	}
+
+	return message
+}
+
+// runTools executes the tool calls with the registered tools and returns the
+// user message carrying their results
+func (f *FakeProvider) runTools(ctx context.Context, blocks []models.ContentBlock) models.Message {
+	results := models.Message{Role: models.RoleUser}
+
+	for _, block := range blocks {
+		if block.Type != models.BlockToolUse {
+			continue
+		}
+
+		if err := models.StartToolCall(ctx); err != nil {
+			results.Blocks = append(results.Blocks, models.ToolResultBlock(block.ID, err.Error(), true))
+			continue
+		}
+
+		var result string
+		var err error
+		if tool, ok := f.findTool(block.Name); ok {
+			result, err = tool.Execute(block.Input)
+		} else {
+			err = fmt.Errorf("tool '%s' not found", block.Name)
+		}
+
+		f.mu.Lock()
+		f.executions = append(f.executions, ToolExecution{
+			Call:   models.ToolUse{ID: block.ID, Name: block.Name, Input: block.Input},
+			Result: result,
+			Err:    err,
+		})
+		f.mu.Unlock()
+
+		if err != nil {
+			results.Blocks = append(results.Blocks, models.ToolResultBlock(block.ID, err.Error(), true))
+			continue
+		}
+		results.Blocks = append(results.Blocks, models.ToolResultBlock(block.ID, result, false))
+	}
+
+	return results
+}
+
+// findTool looks up a registered tool by name
+func (f *FakeProvider) findTool(name string) (models.ToolExecutor, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, tool := range f.tools {
+		if tool.GetName() == name {
+			return tool, true
+		}
+	}
+	return nil, false
+}
+
+// RegisterTool records the tool and makes it available to scripted tool calls.
+// Registering a tool again replaces it.
+// This implements part of the models.Provider interface.
+func (f *FakeProvider) RegisterTool(tool models.ToolExecutor) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, existing := range f.tools {
+		if existing.GetName() == tool.GetName() {
+			f.tools[i] = tool
+			return
+		}
+	}
+	f.tools = append(f.tools, tool)
+}
+
+// SetSystemPrompt records the system prompt.
+// This implements part of the models.Provider interface.
+func (f *FakeProvider) SetSystemPrompt(prompt string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.systemPrompt = prompt
+}
+
+// SetModel records the model.
+// This implements part of the models.Provider interface.
+func (f *FakeProvider) SetModel(model string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.model = model
+}
+
+// SetMaxTokens records the maximum response length.
+// This implements part of the models.Provider interface.
+func (f *FakeProvider) SetMaxTokens(tokens int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.maxTokens = tokens
+}
+
+// SetTemperature records the temperature.
+// This implements part of the models.Provider interface.
+func (f *FakeProvider) SetTemperature(temperature float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.temperature = temperature
+}
+
+// RegisterMCP records the MCP server command without starting it.
+// This implements part of the models.Provider interface.
+func (f *FakeProvider) RegisterMCP(command string, args []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mcps = append(f.mcps, strings.TrimSpace(command+" "+strings.Join(args, " ")))
+	return nil
+}
+
+// CountTokens estimates the tokens with models.EstimateTokens and
+// models.EstimateToolTokens.
+// This implements part of the models.Provider interface.
+func (f *FakeProvider) CountTokens(ctx context.Context, messages []models.Message, tools []models.ToolExecutor) (int, error) {
+	return models.EstimateTokens(messages) + models.EstimateToolTokens(tools), nil
+}
+
+// Requests returns the requests received so far.
+func (f *FakeProvider) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.requests...)
+}
+
+// LastRequest returns the most recent request, and false if there was none.
+func (f *FakeProvider) LastRequest() (Request, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.requests) == 0 {
+		return Request{}, false
+	}
+	return f.requests[len(f.requests)-1], true
+}
+
+// ToolExecutions returns the tools run on behalf of scripted tool calls.
+func (f *FakeProvider) ToolExecutions() []ToolExecution {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ToolExecution(nil), f.executions...)
+}
+
+// Tools returns the names of the registered tools.
+func (f *FakeProvider) Tools() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names := make([]string, len(f.tools))
+	for i, tool := range f.tools {
+		names[i] = tool.GetName()
+	}
+	return names
+}
+
+// SystemPrompt returns the system prompt set on the provider.
+func (f *FakeProvider) SystemPrompt() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.systemPrompt
+}
+
+// MCPs returns the commands of the registered MCP servers.
+func (f *FakeProvider) MCPs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.mcps...)
+}
+
+// Remaining returns the number of scripted responses not yet used.
+func (f *FakeProvider) Remaining() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.responses)
+}
+
+// AssertRequests fails the test unless exactly n requests were received.
+func (f *FakeProvider) AssertRequests(t testing.TB, n int) {
+	t.Helper()
+	if got := len(f.Requests()); got != n {
+		t.Errorf("expected %d requests, got %d", n, got)
+	}
+}
+
+// AssertExhausted fails the test if scripted responses were left unused.
+func (f *FakeProvider) AssertExhausted(t testing.TB) {
+	t.Helper()
+	if remaining := f.Remaining(); remaining > 0 {
+		t.Errorf("expected all scripted responses to be used, %d left", remaining)
+	}
+}
+
+// AssertSystemPrompt fails the test unless the last request used the given system prompt.
+func (f *FakeProvider) AssertSystemPrompt(t testing.TB, prompt string) {
+	t.Helper()
+	request, ok := f.LastRequest()
+	if !ok {
+		t.Errorf("expected a request with system prompt %q, got none", prompt)
+		return
+	}
+	if request.SystemPrompt != prompt {
+		t.Errorf("expected system prompt %q, got %q", prompt, request.SystemPrompt)
+	}
+}
+
+// AssertToolRegistered fails the test unless a tool with the given name was registered.
+func (f *FakeProvider) AssertToolRegistered(t testing.TB, name string) {
+	t.Helper()
+	for _, registered := range f.Tools() {
+		if registered == name {
+			return
+		}
+	}
+	t.Errorf("expected tool %q to be registered, got %v", name, f.Tools())
+}
+
+// AssertToolExecuted fails the test unless the named tool was run for a scripted call.
+func (f *FakeProvider) AssertToolExecuted(t testing.TB, name string) {
+	t.Helper()
+	for _, execution := range f.ToolExecutions() {
+		if execution.Call.Name == name {
+			return
+		}
+	}
+	t.Errorf("expected tool %q to be executed", name)
+}
+
+// AssertReceived fails the test unless the text of a message in any request contains substr.
+func (f *FakeProvider) AssertReceived(t testing.TB, substr string) {
+	t.Helper()
+	for _, request := range f.Requests() {
+		for _, message := range request.Messages {
+			if strings.Contains(message.Text(), substr) {
+				return
+			}
+		}
+	}
+	t.Errorf("expected a message containing %q", substr)
+}
@@ -0,0 +1,151 @@
+package bondtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devOpifex/bond/models"
+	"github.com/devOpifex/bond/reasoning"
+	"github.com/devOpifex/bond/tools"
+)
+
+func weatherTool() models.ToolExecutor {
+	return tools.NewTool(
+		"get_weather",
+		"Get the weather for a city",
+		models.InputSchema{Type: "object"},
+		func(params map[string]any) (string, error) {
+			return "5°C in " + params["city"].(string), nil
+		},
+	)
+}
+
+// TestFakeProviderToolLoop tests that scripted tool calls run the registered tools
+func TestFakeProviderToolLoop(t *testing.T) {
+	fake := NewFakeProvider(
+		CallTool("get_weather", map[string]any{"city": "Paris"}),
+		Reply("It is 5°C in Paris."),
+	)
+	fake.RegisterTool(weatherTool())
+	fake.SetSystemPrompt("Be brief")
+
+	conversation := models.NewConversation("")
+	conversation.Append(models.Message{Role: models.RoleUser, Content: "Weather in Paris?"})
+
+	response, err := fake.SendConversation(context.Background(), conversation)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if response != "It is 5°C in Paris." {
+		t.Errorf("Unexpected response '%s'", response)
+	}
+
+	fake.AssertRequests(t, 2)
+	fake.AssertExhausted(t)
+	fake.AssertSystemPrompt(t, "Be brief")
+	fake.AssertToolRegistered(t, "get_weather")
+	fake.AssertToolExecuted(t, "get_weather")
+	fake.AssertReceived(t, "Weather in Paris?")
+
+	// The follow-up request carries the tool result
+	request, _ := fake.LastRequest()
+	last := request.LastMessage()
+	if len(last.Blocks) != 1 || last.Blocks[0].Type != models.BlockToolResult || last.Blocks[0].Content != "5°C in Paris" {
+		t.Errorf("Expected the tool result in the last message, got %+v", last)
+	}
+
+	if len(request.Tools) != 1 || request.Tools[0] != "get_weather" {
+		t.Errorf("Expected the tools to be recorded, got %v", request.Tools)
+	}
+
+	// User, tool call, tool result and answer
+	if conversation.Len() != 4 {
+		t.Errorf("Expected 4 messages in the conversation, got %d", conversation.Len())
+	}
+}
+
+// TestFakeProviderToolBudget tests that a round with more tool calls than the budget allows runs only those allowed
+func TestFakeProviderToolBudget(t *testing.T) {
+	fake := NewFakeProvider(
+		Response{ToolCalls: []models.ToolUse{
+			{Name: "get_weather", Input: map[string]any{"city": "Paris"}},
+			{Name: "get_weather", Input: map[string]any{"city": "Rome"}},
+			{Name: "get_weather", Input: map[string]any{"city": "Oslo"}},
+		}},
+		Reply("Done."),
+	)
+	fake.RegisterTool(weatherTool())
+
+	budget := &models.Budget{MaxToolCalls: 2}
+	ctx := models.WithBudget(context.Background(), budget)
+
+	_, err := fake.SendMessageWithTools(ctx, models.Message{Role: models.RoleUser, Content: "Weather?"})
+
+	var exceeded *models.BudgetExceededError
+	if !errors.As(err, &exceeded) || exceeded.Limit != models.BudgetToolCalls {
+		t.Fatalf("Expected the tool call budget to be exceeded, got %v", err)
+	}
+
+	if executions := fake.ToolExecutions(); len(executions) != 2 {
+		t.Errorf("Expected 2 tool executions, got %d", len(executions))
+	}
+	if budget.ToolCalls() != 2 {
+		t.Errorf("Expected 2 tool calls counted, got %d", budget.ToolCalls())
+	}
+	fake.AssertRequests(t, 1)
+}
+
+// TestFakeProviderErrors tests scripted errors, delays and running out of responses
+func TestFakeProviderErrors(t *testing.T) {
+	overloaded := errors.New("overloaded")
+	fake := NewFakeProvider(
+		Fail(overloaded),
+		Response{Text: "Too late", Delay: time.Second},
+	)
+
+	message := models.Message{Role: models.RoleUser, Content: "Hello"}
+
+	if _, err := fake.SendMessage(context.Background(), message); !errors.Is(err, overloaded) {
+		t.Errorf("Expected scripted error, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := fake.SendMessage(ctx, message); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the delay to respect the context, got %v", err)
+	}
+
+	if _, err := fake.SendMessage(context.Background(), message); !errors.Is(err, ErrNoResponse) {
+		t.Errorf("Expected ErrNoResponse, got %v", err)
+	}
+}
+
+// TestFakeProviderReactAgent tests a React agent offline
+func TestFakeProviderReactAgent(t *testing.T) {
+	fake := NewFakeProvider(
+		Reply("<thought>\nI need the weather.\n</thought>\n\n```json\n{\"name\": \"get_weather\", \"input\": {\"city\": \"Oslo\"}}\n```"),
+		Reply("It is 5°C in Oslo."),
+	)
+
+	agent := reasoning.NewReactAgent(fake)
+	agent.RegisterTool(weatherTool())
+	agent.SetSystemPrompt("Use tools when needed")
+
+	response, err := agent.Process(context.Background(), "Weather in Oslo?")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if response != "It is 5°C in Oslo." {
+		t.Errorf("Unexpected response '%s'", response)
+	}
+
+	fake.AssertRequests(t, 2)
+	fake.AssertExhausted(t)
+	fake.AssertSystemPrompt(t, "Use tools when needed")
+	fake.AssertToolRegistered(t, "get_weather")
+	fake.AssertReceived(t, "5°C in Oslo")
+}
@@ -0,0 +1,246 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BudgetLimit names the limit of a budget that was exceeded
+type BudgetLimit string
+
+const (
+	// BudgetTokens is the limit on input, cached and output tokens
+	BudgetTokens BudgetLimit = "tokens"
+
+	// BudgetCost is the limit on the cost of the tokens used
+	BudgetCost BudgetLimit = "cost"
+
+	// BudgetDuration is the limit on wall time
+	BudgetDuration BudgetLimit = "duration"
+
+	// BudgetToolCalls is the limit on the number of tools executed
+	BudgetToolCalls BudgetLimit = "tool_calls"
+)
+
+// Budget limits the resources a run may consume. Attach it to a context with
+// WithBudget; providers, agents and chains check it before every request to a
+// model and fail with a *BudgetExceededError once it is spent.
+// Zero limits are not enforced. A Budget is safe for concurrent use and may be
+// shared by several runs, which then draw from the same allowance.
+type Budget struct {
+	// MaxTokens limits the input, cached and output tokens used
+	MaxTokens int
+
+	// MaxCost limits the cost in US dollars, computed with Prices.
+	// Models missing from Prices do not count towards the cost.
+	MaxCost float64
+
+	// Prices is the price table used to enforce MaxCost
+	Prices PriceTable
+
+	// MaxDuration limits the wall time since the budget was attached to a context
+	MaxDuration time.Duration
+
+	// MaxToolCalls limits the number of tools executed. It is checked before
+	// every tool runs: calls beyond it are not executed and the run stops
+	// before the next request.
+	MaxToolCalls int
+
+	mu        sync.Mutex
+	usage     *UsageTracker
+	started   time.Time
+	toolCalls int
+}
+
+// tracker returns the usage tracker of the budget, creating it on first use
+func (b *Budget) tracker() *UsageTracker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.usage == nil {
+		b.usage = NewUsageTracker()
+	}
+	return b.usage
+}
+
+// Usage returns the tokens used so far.
+func (b *Budget) Usage() Usage {
+	return b.tracker().Total()
+}
+
+// ToolCalls returns the number of tools executed so far.
+func (b *Budget) ToolCalls() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.toolCalls
+}
+
+// Elapsed returns the wall time since the budget was first attached to a context.
+func (b *Budget) Elapsed() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.started.IsZero() {
+		return 0
+	}
+	return time.Since(b.started)
+}
+
+// StartToolCall counts a tool execution against the budget before the tool
+// runs. Once MaxToolCalls tools have been executed it returns a
+// *BudgetExceededError instead, and the tool must not run.
+func (b *Budget) StartToolCall() error {
+	b.mu.Lock()
+	spent := b.MaxToolCalls > 0 && b.toolCalls >= b.MaxToolCalls
+	if !spent {
+		b.toolCalls++
+	}
+	b.mu.Unlock()
+
+	if spent {
+		return b.exceeded(BudgetToolCalls)
+	}
+	return nil
+}
+
+// Check returns a *BudgetExceededError if any limit is spent: the tokens,
+// cost or tool calls have reached their maximum, or the wall time has run
+// out. It returns nil otherwise.
+func (b *Budget) Check() error {
+	tracker := b.tracker()
+	usage := tracker.Total()
+	cost, _ := tracker.Cost(b.Prices)
+
+	switch {
+	case b.MaxTokens > 0 && usage.TotalTokens() >= b.MaxTokens:
+		return b.exceeded(BudgetTokens)
+	case b.MaxCost > 0 && cost >= b.MaxCost:
+		return b.exceeded(BudgetCost)
+	case b.MaxDuration > 0 && b.Elapsed() >= b.MaxDuration:
+		return b.exceeded(BudgetDuration)
+	case b.MaxToolCalls > 0 && b.ToolCalls() >= b.MaxToolCalls:
+		return b.exceeded(BudgetToolCalls)
+	}
+
+	return nil
+}
+
+// exceeded reports the limit as exceeded along with what was consumed
+func (b *Budget) exceeded(limit BudgetLimit) *BudgetExceededError {
+	tracker := b.tracker()
+	cost, _ := tracker.Cost(b.Prices)
+
+	var maximum string
+	switch limit {
+	case BudgetTokens:
+		maximum = fmt.Sprint(b.MaxTokens)
+	case BudgetCost:
+		maximum = fmt.Sprintf("$%.4f", b.MaxCost)
+	case BudgetDuration:
+		maximum = b.MaxDuration.String()
+	case BudgetToolCalls:
+		maximum = fmt.Sprint(b.MaxToolCalls)
+	}
+
+	return &BudgetExceededError{
+		Limit:     limit,
+		Usage:     tracker.Total(),
+		Cost:      cost,
+		ToolCalls: b.ToolCalls(),
+		Elapsed:   b.Elapsed(),
+		maximum:   maximum,
+	}
+}
+
+// BudgetExceededError is returned when a run is aborted because its budget is
+// spent. Use errors.As to detect it.
+type BudgetExceededError struct {
+	// Limit is the limit that was exceeded
+	Limit BudgetLimit
+
+	// Usage, Cost, ToolCalls and Elapsed report what had been consumed
+	Usage     Usage
+	Cost      float64
+	ToolCalls int
+	Elapsed   time.Duration
+
+	// Transcript holds the messages exchanged before the run was aborted
+	Transcript []Message
+
+	// maximum is the exceeded limit as set when the error was raised, formatted
+	maximum string
+}
+
+// Error implements the error interface.
+func (e *BudgetExceededError) Error() string {
+	maximum := ""
+	if e.maximum != "" {
+		maximum = " of " + e.maximum
+	}
+
+	var detail string
+	switch e.Limit {
+	case BudgetTokens:
+		detail = fmt.Sprintf("used %d%s tokens", e.Usage.TotalTokens(), maximum)
+	case BudgetCost:
+		detail = fmt.Sprintf("spent $%.4f%s", e.Cost, maximum)
+	case BudgetDuration:
+		detail = fmt.Sprintf("ran for %v%s", e.Elapsed.Round(time.Millisecond), maximum)
+	case BudgetToolCalls:
+		detail = fmt.Sprintf("executed %d%s tool calls", e.ToolCalls, maximum)
+	default:
+		return "budget exceeded"
+	}
+	return fmt.Sprintf("budget exceeded (%s): %s", e.Limit, detail)
+}
+
+// budgetKey is the context key under which the budget is stored
+type budgetKey struct{}
+
+// WithBudget returns a context carrying the budget. The budget's clock starts
+// the first time it is attached, and the usage reported by providers using the
+// returned context is counted against it.
+func WithBudget(ctx context.Context, budget *Budget) context.Context {
+	budget.mu.Lock()
+	if budget.started.IsZero() {
+		budget.started = time.Now()
+	}
+	budget.mu.Unlock()
+
+	ctx = WithUsageTracker(ctx, budget.tracker())
+	return context.WithValue(ctx, budgetKey{}, budget)
+}
+
+// BudgetFrom returns the budget carried by the context, or nil.
+func BudgetFrom(ctx context.Context) *Budget {
+	budget, _ := ctx.Value(budgetKey{}).(*Budget)
+	return budget
+}
+
+// CheckBudget checks the context's budget, if any, before a request is sent.
+// When the budget is spent the returned *BudgetExceededError carries a copy of
+// the transcript so far.
+func CheckBudget(ctx context.Context, transcript []Message) error {
+	budget := BudgetFrom(ctx)
+	if budget == nil {
+		return nil
+	}
+
+	err := budget.Check()
+	if exceeded, ok := err.(*BudgetExceededError); ok {
+		exceeded.Transcript = append([]Message(nil), transcript...)
+	}
+	return err
+}
+
+// StartToolCall counts a tool execution against the context's budget, if any,
+// before the tool runs. When the budget allows no more tool calls it returns
+// a *BudgetExceededError and the tool must not run; see Budget.StartToolCall.
+func StartToolCall(ctx context.Context) error {
+	if budget := BudgetFrom(ctx); budget != nil {
+		return budget.StartToolCall()
+	}
+	return nil
+}
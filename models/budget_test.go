@@ -0,0 +1,144 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestBudget tests that each limit is enforced and reported with the transcript
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget *Budget
+		spend  func(ctx context.Context)
+		limit  BudgetLimit
+	}{
+		{
+			name:   "tokens",
+			budget: &Budget{MaxTokens: 1000},
+			spend:  func(ctx context.Context) { ReportUsage(ctx, "model", Usage{InputTokens: 900, OutputTokens: 100}) },
+			limit:  BudgetTokens,
+		},
+		{
+			name:   "cost",
+			budget: &Budget{MaxCost: 0.01, Prices: PriceTable{"model": {Input: 10, Output: 30}}},
+			spend:  func(ctx context.Context) { ReportUsage(ctx, "model", Usage{InputTokens: 1000}) },
+			limit:  BudgetCost,
+		},
+		{
+			name:   "duration",
+			budget: &Budget{MaxDuration: time.Millisecond},
+			spend:  func(ctx context.Context) { time.Sleep(2 * time.Millisecond) },
+			limit:  BudgetDuration,
+		},
+		{
+			name:   "tool calls",
+			budget: &Budget{MaxToolCalls: 2},
+			spend:  func(ctx context.Context) { StartToolCall(ctx); StartToolCall(ctx) },
+			limit:  BudgetToolCalls,
+		},
+	}
+
+	transcript := []Message{{Role: RoleUser, Content: "Hello"}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithBudget(context.Background(), tt.budget)
+
+			if err := CheckBudget(ctx, transcript); err != nil {
+				t.Fatalf("Expected fresh budget to pass, got %v", err)
+			}
+
+			tt.spend(ctx)
+
+			err := CheckBudget(ctx, transcript)
+
+			var exceeded *BudgetExceededError
+			if !errors.As(err, &exceeded) {
+				t.Fatalf("Expected BudgetExceededError, got %v", err)
+			}
+
+			if exceeded.Limit != tt.limit {
+				t.Errorf("Expected limit '%s', got '%s'", tt.limit, exceeded.Limit)
+			}
+
+			if len(exceeded.Transcript) != 1 || exceeded.Transcript[0].Content != "Hello" {
+				t.Errorf("Expected the transcript to be attached, got %+v", exceeded.Transcript)
+			}
+		})
+	}
+}
+
+// TestBudgetStartToolCall tests that tool calls are refused once the budget allows no more
+func TestBudgetStartToolCall(t *testing.T) {
+	budget := &Budget{MaxToolCalls: 2}
+	ctx := WithBudget(context.Background(), budget)
+
+	for i := 0; i < 2; i++ {
+		if err := StartToolCall(ctx); err != nil {
+			t.Fatalf("Expected tool call %d to be allowed, got %v", i+1, err)
+		}
+	}
+
+	var exceeded *BudgetExceededError
+	if err := StartToolCall(ctx); !errors.As(err, &exceeded) || exceeded.Limit != BudgetToolCalls {
+		t.Fatalf("Expected the third tool call to be refused, got %v", err)
+	}
+
+	if budget.ToolCalls() != 2 {
+		t.Errorf("Expected refused calls not to be counted, got %d", budget.ToolCalls())
+	}
+
+	if err := StartToolCall(context.Background()); err != nil {
+		t.Errorf("Expected no budget to allow every call, got %v", err)
+	}
+}
+
+// TestBudgetExceededErrorMessage tests that errors built outside a budget, including the zero value, can be printed
+func TestBudgetExceededErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  *BudgetExceededError
+		want string
+	}{
+		{&BudgetExceededError{}, "budget exceeded"},
+		{&BudgetExceededError{Limit: BudgetToolCalls, ToolCalls: 3}, "budget exceeded (tool_calls): executed 3 tool calls"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Expected '%s', got '%s'", tt.want, got)
+		}
+	}
+
+	// The limit is kept as it was when the budget was exceeded
+	budget := &Budget{MaxCost: 1}
+	err := budget.exceeded(BudgetCost)
+	budget.MaxCost = 2
+	if got := err.Error(); got != "budget exceeded (cost): spent $0.0000 of $1.0000" {
+		t.Errorf("Unexpected message '%s'", got)
+	}
+}
+
+// TestBudgetNestedTrackers tests that usage recorded by nested trackers counts against the budget
+func TestBudgetNestedTrackers(t *testing.T) {
+	budget := &Budget{MaxTokens: 100}
+	ctx := WithBudget(context.Background(), budget)
+
+	inner := NewUsageTracker()
+	ReportUsage(WithUsageTracker(ctx, inner), "model", Usage{InputTokens: 60, OutputTokens: 50})
+
+	if budget.Usage().TotalTokens() != 110 {
+		t.Errorf("Expected the budget to see 110 tokens, got %d", budget.Usage().TotalTokens())
+	}
+
+	err := CheckBudget(ctx, nil)
+	if err == nil || err.Error() != "budget exceeded (tokens): used 110 of 100 tokens" {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	if CheckBudget(context.Background(), nil) != nil {
+		t.Errorf("Expected no error without a budget")
+	}
+}
@@ -0,0 +1,125 @@
+package models
+
+import "context"
+
+// Conversation holds the state of a multi-turn exchange with an AI model.
+// Providers accept a Conversation directly and append the model's turns to it,
+// including tool calls and their results, so the same conversation can be
+// continued, forked or persisted with encoding/json regardless of the provider.
+type Conversation struct {
+	// SystemPrompt guides the model's behavior for this conversation.
+	// When empty, the provider's own system prompt is used.
+	SystemPrompt string `json:"system_prompt,omitempty"`
+
+	// Messages is the ordered list of turns in the conversation.
+	Messages []Message `json:"messages"`
+
+	// Metadata holds arbitrary information about the conversation, such as
+	// a session or user identifier. It is never sent to the model.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// NewConversation creates an empty conversation with the given system prompt.
+func NewConversation(systemPrompt string) *Conversation {
+	return &Conversation{
+		SystemPrompt: systemPrompt,
+		Messages:     []Message{},
+		Metadata:     make(map[string]string),
+	}
+}
+
+// Append adds messages to the end of the conversation and returns the
+// conversation for method chaining.
+func (c *Conversation) Append(messages ...Message) *Conversation {
+	c.Messages = append(c.Messages, messages...)
+	return c
+}
+
+// Len returns the number of messages in the conversation.
+func (c *Conversation) Len() int {
+	return len(c.Messages)
+}
+
+// Last returns the most recent message in the conversation.
+// It returns false if the conversation is empty.
+func (c *Conversation) Last() (Message, bool) {
+	if len(c.Messages) == 0 {
+		return Message{}, false
+	}
+	return c.Messages[len(c.Messages)-1], true
+}
+
+// Fork returns an independent copy of the conversation. Messages appended to
+// the fork do not affect the original, which makes it possible to explore
+// alternative continuations from the same point.
+func (c *Conversation) Fork() *Conversation {
+	fork := &Conversation{
+		SystemPrompt: c.SystemPrompt,
+		Messages:     make([]Message, len(c.Messages)),
+		Metadata:     make(map[string]string, len(c.Metadata)),
+	}
+
+	for i, message := range c.Messages {
+		fork.Messages[i] = message.clone()
+	}
+
+	for key, value := range c.Metadata {
+		fork.Metadata[key] = value
+	}
+
+	return fork
+}
+
+// Truncate shortens the conversation to its first n messages.
+// It is a no-op if the conversation has n messages or fewer.
+func (c *Conversation) Truncate(n int) {
+	if n < 0 {
+		n = 0
+	}
+	if n < len(c.Messages) {
+		c.Messages = c.Messages[:n]
+	}
+}
+
+// Fitter shortens a conversation in place so that it fits the model's
+// context window, or fails when it cannot.
+type Fitter func(ctx context.Context, conversation *Conversation) error
+
+// fitterKey is the context key under which the fitter is stored
+type fitterKey struct{}
+
+// WithFitter returns a context carrying the fitter, replacing any fitter
+// already attached; a nil fitter detaches it. Providers apply it before every
+// request they send, including each round of a tool loop.
+func WithFitter(ctx context.Context, fitter Fitter) context.Context {
+	return context.WithValue(ctx, fitterKey{}, fitter)
+}
+
+// FitConversation applies the context's fitter, if any, to the conversation.
+// Providers call it before every request, next to CheckBudget.
+func FitConversation(ctx context.Context, conversation *Conversation) error {
+	if fitter, _ := ctx.Value(fitterKey{}).(Fitter); fitter != nil {
+		return fitter(ctx, conversation)
+	}
+	return nil
+}
+
+// clone returns a copy of the message that shares no mutable state with the original.
+func (m Message) clone() Message {
+	if m.ToolUse != nil {
+		toolUse := *m.ToolUse
+		m.ToolUse = &toolUse
+	}
+
+	if m.ToolResult != nil {
+		toolResult := *m.ToolResult
+		toolResult.Content = append([]ContentItem(nil), m.ToolResult.Content...)
+		m.ToolResult = &toolResult
+	}
+
+	if m.Blocks != nil {
+		m.Blocks = append([]ContentBlock(nil), m.Blocks...)
+	}
+
+	return m
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestConversationFork tests that a fork does not share state with the original
+func TestConversationFork(t *testing.T) {
+	original := NewConversation("You are helpful.")
+	original.Metadata["session"] = "abc"
+	original.Append(Message{
+		Role:   RoleAssistant,
+		Blocks: []ContentBlock{TextBlock("Hello")},
+	})
+
+	fork := original.Fork()
+	fork.Append(Message{Role: RoleUser, Content: "Hi"})
+	fork.Messages[0].Blocks[0].Text = "Changed"
+	fork.Metadata["session"] = "def"
+
+	if original.Len() != 1 {
+		t.Errorf("Expected original to keep 1 message, got %d", original.Len())
+	}
+
+	if original.Messages[0].Blocks[0].Text != "Hello" {
+		t.Errorf("Expected original block text 'Hello', got '%s'", original.Messages[0].Blocks[0].Text)
+	}
+
+	if original.Metadata["session"] != "abc" {
+		t.Errorf("Expected original metadata 'abc', got '%s'", original.Metadata["session"])
+	}
+}
+
+// TestConversationTruncate tests truncating a conversation to its first messages
+func TestConversationTruncate(t *testing.T) {
+	conversation := NewConversation("")
+	conversation.Append(
+		Message{Role: RoleUser, Content: "one"},
+		Message{Role: RoleAssistant, Content: "two"},
+		Message{Role: RoleUser, Content: "three"},
+	)
+
+	conversation.Truncate(5)
+	if conversation.Len() != 3 {
+		t.Errorf("Expected 3 messages after no-op truncate, got %d", conversation.Len())
+	}
+
+	conversation.Truncate(1)
+	last, ok := conversation.Last()
+	if !ok || conversation.Len() != 1 || last.Content != "one" {
+		t.Errorf("Expected only the first message to remain, got %+v", conversation.Messages)
+	}
+}
+
+// TestConversationJSON tests that a conversation survives a JSON round trip
+func TestConversationJSON(t *testing.T) {
+	conversation := NewConversation("You are helpful.")
+	conversation.Metadata["user"] = "42"
+	conversation.Append(
+		Message{Role: RoleUser, Content: "What's the weather?"},
+		Message{Role: RoleAssistant, Blocks: []ContentBlock{
+			ToolUseBlock("toolu_1", "get_weather", json.RawMessage(`{"location":"Brussels"}`)),
+		}},
+		Message{Role: RoleUser, Blocks: []ContentBlock{ToolResultBlock("toolu_1", "5°C", false)}},
+	)
+
+	data, err := json.Marshal(conversation)
+	if err != nil {
+		t.Fatalf("Failed to marshal conversation: %v", err)
+	}
+
+	var restored Conversation
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("Failed to unmarshal conversation: %v", err)
+	}
+
+	if restored.SystemPrompt != "You are helpful." || restored.Metadata["user"] != "42" {
+		t.Errorf("Expected system prompt and metadata to be restored, got %+v", restored)
+	}
+
+	if restored.Len() != 3 || restored.Messages[2].Blocks[0].ToolUseID != "toolu_1" {
+		t.Errorf("Expected tool blocks to be restored, got %+v", restored.Messages)
+	}
+}
@@ -43,6 +43,11 @@ type Provider interface {
 	// It returns the model's response as a string.
 	SendMessageWithTools(ctx context.Context, message Message) (string, error)
 
+	// SendConversation sends the whole conversation to the AI provider along with the
+	// registered tools. The model's turns, including any tool calls and their results,
+	// are appended to the conversation and the final response text is returned.
+	SendConversation(ctx context.Context, conversation *Conversation) (string, error)
+
 	// RegisterTool adds a tool that the AI provider can call during its reasoning process.
 	// Tools are registered with the provider so they can be included in API requests.
 	RegisterTool(tool ToolExecutor)
@@ -66,6 +71,48 @@ type Provider interface {
 
 	// register an MCP with the provider
 	RegisterMCP(command string, args []string) error
+
+	// CountTokens counts the input tokens of a request made of the messages and
+	// the definitions of the given tools, without sending it for a response.
+	// System messages in the list are counted as the system prompt; the
+	// provider's own system prompt and registered tools are not counted.
+	CountTokens(ctx context.Context, messages []Message, tools []ToolExecutor) (int, error)
+}
+
+// Streamer is implemented by providers that can stream a response as it is
+// generated instead of returning it once complete. Every provider emits the same
+// StreamEvent values, so consumers do not need to know which backend is in use.
+type Streamer interface {
+	// SendMessageStream sends a message to the AI provider along with the registered
+	// tools and returns a channel of incremental events. The channel is closed after
+	// the final StreamStop or StreamError event.
+	SendMessageStream(ctx context.Context, message Message) (<-chan StreamEvent, error)
+}
+
+// StructuredResponder is implemented by providers with a native mechanism for
+// constraining a response to a JSON Schema, such as OpenAI's json_schema response
+// format or a forced tool call on Claude. Use the structured package rather than
+// calling it directly.
+type StructuredResponder interface {
+	// SendStructured sends the conversation and returns the model's response as
+	// JSON conforming to format's schema. The model's turn is appended to the
+	// conversation so that it can be corrected in a follow-up message.
+	SendStructured(ctx context.Context, conversation *Conversation, format ResponseFormat) (string, error)
+}
+
+// Embedder is implemented by providers that turn text into embedding vectors,
+// for semantic search and retrieval augmented generation. Texts are embedded
+// with a dedicated embedding model, configured separately from the chat model.
+type Embedder interface {
+	// Embed returns one vector per text, in the order of the texts. Large
+	// batches are split into several requests.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+
+	// Dimensions returns the length of the vectors, or 0 while it is unknown
+	Dimensions() int
+
+	// EmbeddingModel returns the name of the embedding model
+	EmbeddingModel() string
 }
 
 // Agent defines the interface that all AI agents must implement.
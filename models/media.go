@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ImageFile reads an image from disk and returns it as an image content block.
+// The media type is derived from the file extension, or sniffed from the
+// content when the extension is unknown, and must be an image type.
+func ImageFile(path string) (ContentBlock, error) {
+	data, mimeType, err := readMedia(path)
+	if err != nil {
+		return ContentBlock{}, err
+	}
+
+	if !strings.HasPrefix(mimeType, "image/") {
+		return ContentBlock{}, fmt.Errorf("%s is not an image (%s)", path, mimeType)
+	}
+
+	return ImageBlock(mimeType, data), nil
+}
+
+// DocumentFile reads a document, such as a PDF, from disk and returns it as a
+// document content block named after the file.
+func DocumentFile(path string) (ContentBlock, error) {
+	data, mimeType, err := readMedia(path)
+	if err != nil {
+		return ContentBlock{}, err
+	}
+
+	return DocumentBlock(mimeType, data, filepath.Base(path)), nil
+}
+
+// DocumentText returns the decoded content of a text document, such as a
+// text/plain or text/markdown document block, and reports whether the block
+// is one. Binary documents such as PDFs are not text documents.
+func (b ContentBlock) DocumentText() (string, bool) {
+	if b.Type != BlockDocument || !strings.HasPrefix(b.MimeType, "text/") {
+		return "", false
+	}
+
+	content, err := base64.StdEncoding.DecodeString(b.Data)
+	if err != nil {
+		return "", false
+	}
+	return string(content), true
+}
+
+// readMedia reads a file and returns its base64 encoded content and media type
+func readMedia(path string) (string, string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(content)
+	}
+
+	// Drop parameters such as charset, which providers reject
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+
+	return base64.StdEncoding.EncodeToString(content), strings.TrimSpace(mimeType), nil
+}
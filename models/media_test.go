@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMediaFiles tests reading images and documents from disk
+func TestMediaFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	// A PNG signature is enough for content sniffing
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	pngPath := filepath.Join(dir, "screenshot")
+	pdfPath := filepath.Join(dir, "report.pdf")
+	os.WriteFile(pngPath, png, 0o644)
+	os.WriteFile(pdfPath, []byte("%PDF-1.7"), 0o644)
+
+	image, err := ImageFile(pngPath)
+	if err != nil {
+		t.Fatalf("Failed to read image: %v", err)
+	}
+
+	if image.Type != BlockImage || image.MimeType != "image/png" || image.Data != base64.StdEncoding.EncodeToString(png) {
+		t.Errorf("Unexpected image block %+v", image)
+	}
+
+	document, err := DocumentFile(pdfPath)
+	if err != nil {
+		t.Fatalf("Failed to read document: %v", err)
+	}
+
+	if document.Type != BlockDocument || document.MimeType != "application/pdf" || document.Name != "report.pdf" {
+		t.Errorf("Unexpected document block %+v", document)
+	}
+
+	if _, err := ImageFile(pdfPath); err == nil {
+		t.Errorf("Expected an error when reading a PDF as an image")
+	}
+
+	if _, ok := document.DocumentText(); ok {
+		t.Errorf("Expected a PDF not to be a text document")
+	}
+
+	text, ok := DocumentBlock("text/markdown", base64.StdEncoding.EncodeToString([]byte("# Notes")), "").DocumentText()
+	if !ok || text != "# Notes" {
+		t.Errorf("Expected the text document to be decoded, got '%s'", text)
+	}
+}
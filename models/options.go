@@ -0,0 +1,159 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Tool choice modes, see ToolChoice
+const (
+	// ToolChoiceAuto lets the model decide whether to call tools
+	ToolChoiceAuto = "auto"
+
+	// ToolChoiceNone prevents the model from calling tools
+	ToolChoiceNone = "none"
+
+	// ToolChoiceAny makes the model call at least one tool of its choice
+	ToolChoiceAny = "any"
+
+	// ToolChoiceTool makes the model call the tool named in ToolChoice.Name
+	ToolChoiceTool = "tool"
+)
+
+// ToolChoice controls whether and which tools the model calls.
+// The zero value leaves the choice to the provider's default.
+type ToolChoice struct {
+	// Mode is one of ToolChoiceAuto, ToolChoiceNone, ToolChoiceAny or ToolChoiceTool
+	Mode string
+
+	// Name is the tool to call when Mode is ToolChoiceTool
+	Name string
+}
+
+// UseTool returns a ToolChoice forcing a call to the named tool.
+func UseTool(name string) ToolChoice {
+	return ToolChoice{Mode: ToolChoiceTool, Name: name}
+}
+
+// GenerationOptions holds per-request settings. Attach them to a context with
+// WithOptions; they override the provider's own settings for the requests made
+// with that context only, so a provider can be shared by goroutines using
+// different options. Zero values and nil pointers leave the provider's settings
+// in place. Providers reject options they do not support rather than ignoring them.
+type GenerationOptions struct {
+	// Model overrides the provider's model
+	Model string
+
+	// MaxTokens overrides the maximum number of tokens generated
+	MaxTokens int
+
+	// Temperature overrides the provider's temperature
+	Temperature *float64
+
+	// TopP restricts sampling to the most likely tokens whose probabilities add up to TopP
+	TopP *float64
+
+	// TopK restricts sampling to the K most likely tokens
+	TopK *int
+
+	// StopSequences end the generation when the model produces one of them
+	StopSequences []string
+
+	// Seed makes sampling reproducible, as far as the provider allows
+	Seed *int
+
+	// UserID identifies the end user on whose behalf the request is made, to
+	// help providers detect abuse
+	UserID string
+
+	// Metadata is attached to the request by providers that support it
+	Metadata map[string]string
+
+	// ToolChoice controls whether and which tools the model calls
+	ToolChoice ToolChoice
+}
+
+// Float returns a pointer to v, for the optional fields of GenerationOptions.
+func Float(v float64) *float64 { return &v }
+
+// Int returns a pointer to v, for the optional fields of GenerationOptions.
+func Int(v int) *int { return &v }
+
+// Validate checks the options for values no provider accepts.
+func (o GenerationOptions) Validate() error {
+	var problems []error
+
+	if o.MaxTokens < 0 {
+		problems = append(problems, fmt.Errorf("max tokens must be positive, got %d", o.MaxTokens))
+	}
+	if o.Temperature != nil && (*o.Temperature < 0 || *o.Temperature > 2) {
+		problems = append(problems, fmt.Errorf("temperature must be between 0 and 2, got %v", *o.Temperature))
+	}
+	if o.TopP != nil && (*o.TopP <= 0 || *o.TopP > 1) {
+		problems = append(problems, fmt.Errorf("top_p must be in (0, 1], got %v", *o.TopP))
+	}
+	if o.TopK != nil && *o.TopK < 1 {
+		problems = append(problems, fmt.Errorf("top_k must be at least 1, got %d", *o.TopK))
+	}
+
+	switch o.ToolChoice.Mode {
+	case "", ToolChoiceAuto, ToolChoiceNone, ToolChoiceAny:
+		if o.ToolChoice.Name != "" {
+			problems = append(problems, fmt.Errorf("tool choice %q cannot name a tool", o.ToolChoice.Mode))
+		}
+	case ToolChoiceTool:
+		if o.ToolChoice.Name == "" {
+			problems = append(problems, errors.New("tool choice \"tool\" requires a tool name"))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("unknown tool choice %q", o.ToolChoice.Mode))
+	}
+
+	return errors.Join(problems...)
+}
+
+// ValidateToolChoice checks that the tool choice can be honoured by a request
+// offering the given tools.
+func (o GenerationOptions) ValidateToolChoice(tools []string) error {
+	switch o.ToolChoice.Mode {
+	case ToolChoiceAny:
+		if len(tools) == 0 {
+			return errors.New("tool choice \"any\" requires tools to be offered")
+		}
+	case ToolChoiceTool:
+		for _, tool := range tools {
+			if tool == o.ToolChoice.Name {
+				return nil
+			}
+		}
+		return fmt.Errorf("tool choice names tool %q, which is not offered", o.ToolChoice.Name)
+	}
+	return nil
+}
+
+// optionsKey is the context key under which generation options are stored
+type optionsKey struct{}
+
+// WithOptions returns a context carrying the generation options, replacing any
+// options already attached.
+func WithOptions(ctx context.Context, options GenerationOptions) context.Context {
+	return context.WithValue(ctx, optionsKey{}, options)
+}
+
+// OptionsFrom returns the generation options carried by the context, or the
+// zero value if there are none.
+func OptionsFrom(ctx context.Context) GenerationOptions {
+	options, _ := ctx.Value(optionsKey{}).(GenerationOptions)
+	return options
+}
+
+// FollowUp returns the options for the follow-up requests of a tool loop.
+// A tool choice forcing the model to call tools only applies to the first
+// request, so that the model can answer once it has the tools' results.
+func (o GenerationOptions) FollowUp() GenerationOptions {
+	if o.ToolChoice.Mode == ToolChoiceAny || o.ToolChoice.Mode == ToolChoiceTool {
+		o.ToolChoice = ToolChoice{Mode: ToolChoiceAuto}
+	}
+	return o
+}
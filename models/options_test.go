@@ -0,0 +1,79 @@
+package models
+
+import (
+	"context"
+	"testing"
+)
+
+// TestGenerationOptionsValidate tests that out of range values are rejected
+func TestGenerationOptionsValidate(t *testing.T) {
+	valid := GenerationOptions{
+		Temperature: Float(0.5),
+		TopP:        Float(1),
+		TopK:        Int(40),
+		ToolChoice:  UseTool("get_weather"),
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid options, got %v", err)
+	}
+
+	invalid := []GenerationOptions{
+		{MaxTokens: -1},
+		{Temperature: Float(2.5)},
+		{TopP: Float(0)},
+		{TopK: Int(0)},
+		{ToolChoice: ToolChoice{Mode: ToolChoiceTool}},
+		{ToolChoice: ToolChoice{Mode: ToolChoiceAuto, Name: "get_weather"}},
+		{ToolChoice: ToolChoice{Mode: "required"}},
+	}
+	for _, opts := range invalid {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", opts)
+		}
+	}
+}
+
+// TestValidateToolChoice tests that forced tool choices require offered tools
+func TestValidateToolChoice(t *testing.T) {
+	tools := []string{"get_weather"}
+
+	if err := (GenerationOptions{ToolChoice: UseTool("get_weather")}).ValidateToolChoice(tools); err != nil {
+		t.Errorf("Expected offered tool to be accepted, got %v", err)
+	}
+	if err := (GenerationOptions{ToolChoice: UseTool("get_time")}).ValidateToolChoice(tools); err == nil {
+		t.Errorf("Expected an error for a tool that is not offered")
+	}
+	if err := (GenerationOptions{ToolChoice: ToolChoice{Mode: ToolChoiceAny}}).ValidateToolChoice(nil); err == nil {
+		t.Errorf("Expected an error for tool choice any without tools")
+	}
+	if err := (GenerationOptions{ToolChoice: ToolChoice{Mode: ToolChoiceNone}}).ValidateToolChoice(nil); err != nil {
+		t.Errorf("Expected tool choice none without tools to be accepted, got %v", err)
+	}
+}
+
+// TestOptionsContext tests carrying options in a context and relaxing them for follow-ups
+func TestOptionsContext(t *testing.T) {
+	if opts := OptionsFrom(context.Background()); opts.Model != "" || opts.ToolChoice.Mode != "" {
+		t.Errorf("Expected zero options without WithOptions, got %+v", opts)
+	}
+
+	ctx := WithOptions(context.Background(), GenerationOptions{
+		Model:      "small",
+		ToolChoice: UseTool("get_weather"),
+	})
+
+	opts := OptionsFrom(ctx)
+	if opts.Model != "small" || opts.ToolChoice.Name != "get_weather" {
+		t.Errorf("Unexpected options %+v", opts)
+	}
+
+	followUp := opts.FollowUp()
+	if followUp.ToolChoice != (ToolChoice{Mode: ToolChoiceAuto}) || followUp.Model != "small" {
+		t.Errorf("Expected forced tool choice to be relaxed to auto, got %+v", followUp)
+	}
+
+	none := GenerationOptions{ToolChoice: ToolChoice{Mode: ToolChoiceNone}}
+	if none.FollowUp().ToolChoice.Mode != ToolChoiceNone {
+		t.Errorf("Expected tool choice none to apply to follow-ups")
+	}
+}
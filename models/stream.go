@@ -0,0 +1,47 @@
+package models
+
+// Stream event types emitted by providers that support streaming responses.
+const (
+	// StreamText carries a fragment of the model's text output.
+	StreamText = "text"
+
+	// StreamThinking carries a fragment of the model's reasoning in Text.
+	StreamThinking = "thinking"
+
+	// StreamToolUse signals that the model started a tool call.
+	StreamToolUse = "tool_use"
+
+	// StreamToolInput carries a fragment of a tool call's JSON input.
+	StreamToolInput = "tool_input"
+
+	// StreamStop signals the end of the response and carries the stop reason.
+	StreamStop = "stop"
+
+	// StreamError carries an error that terminated the stream.
+	StreamError = "error"
+)
+
+// StreamEvent represents a single incremental update from a streaming provider.
+// Only the fields relevant to the event type are populated.
+type StreamEvent struct {
+	// Type is the kind of event (text, thinking, tool_use, tool_input, stop, error).
+	Type string
+
+	// Text is the text fragment for StreamText and StreamThinking events.
+	Text string
+
+	// ToolID identifies the tool call for StreamToolUse and StreamToolInput events.
+	ToolID string
+
+	// ToolName is the name of the tool being called for StreamToolUse events.
+	ToolName string
+
+	// PartialInput is a fragment of the tool call's JSON input for StreamToolInput events.
+	PartialInput string
+
+	// StopReason explains why the model stopped for StreamStop events.
+	StopReason string
+
+	// Err is the error that terminated the stream for StreamError events.
+	Err error
+}
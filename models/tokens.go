@@ -0,0 +1,77 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// Constants of the token estimator, calibrated on English text and code
+const (
+	// bytesPerToken is the average number of bytes of text per token
+	bytesPerToken = 4
+
+	// messageOverhead covers the role and delimiters of every message
+	messageOverhead = 4
+
+	// imageTokens is charged per image, about what a 1 megapixel image costs
+	imageTokens = 1600
+
+	// toolOverhead covers the framing of every tool definition
+	toolOverhead = 8
+)
+
+// EstimateTokens estimates the number of tokens of the messages without a
+// tokenizer, at about four bytes of text per token. Images count as a fixed
+// number of tokens and documents by their size. Estimates are meant to keep
+// a safe distance from limits, not for billing.
+func EstimateTokens(messages []Message) int {
+	total := 0
+	for _, message := range messages {
+		total += messageOverhead + estimateMessage(message)
+	}
+	return total
+}
+
+// EstimateToolTokens estimates the number of tokens of the tools' definitions,
+// made of their names, descriptions and input schemas.
+func EstimateToolTokens(tools []ToolExecutor) int {
+	total := 0
+	for _, tool := range tools {
+		schema, _ := json.Marshal(tool.GetSchema())
+		total += toolOverhead + EstimateTextTokens(tool.GetName()) +
+			EstimateTextTokens(tool.GetDescription()) + EstimateTextTokens(string(schema))
+	}
+	return total
+}
+
+// EstimateTextTokens estimates the number of tokens of a piece of text.
+func EstimateTextTokens(text string) int {
+	return (len(text) + bytesPerToken - 1) / bytesPerToken
+}
+
+// estimateMessage estimates the tokens of a message's content
+func estimateMessage(message Message) int {
+	if len(message.Blocks) == 0 {
+		tokens := EstimateTextTokens(message.Content)
+		if message.ToolUse != nil {
+			tokens += EstimateTextTokens(message.ToolUse.Name) + EstimateTextTokens(message.ToolUse.ID)
+		}
+		return tokens
+	}
+
+	tokens := 0
+	for _, block := range message.Blocks {
+		switch block.Type {
+		case BlockImage:
+			tokens += imageTokens
+		case BlockDocument:
+			tokens += EstimateTextTokens(block.Name) + base64.StdEncoding.DecodedLen(len(block.Data))/bytesPerToken
+		case BlockRedactedThinking:
+			tokens += EstimateTextTokens(block.Data)
+		default:
+			tokens += EstimateTextTokens(block.Text) + EstimateTextTokens(block.Name) +
+				EstimateTextTokens(string(block.Input)) + EstimateTextTokens(block.Content)
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestEstimateTokens tests the heuristic token estimator
+func TestEstimateTokens(t *testing.T) {
+	if tokens := EstimateTextTokens(strings.Repeat("a", 10)); tokens != 3 {
+		t.Errorf("Expected 10 bytes to round up to 3 tokens, got %d", tokens)
+	}
+
+	text := EstimateTokens([]Message{{Role: RoleUser, Content: strings.Repeat("word ", 80)}})
+	if text != messageOverhead+100 {
+		t.Errorf("Expected %d tokens for 400 bytes, got %d", messageOverhead+100, text)
+	}
+
+	media := EstimateTokens([]Message{{Role: RoleUser, Blocks: []ContentBlock{
+		TextBlock("Compare"),
+		ImageBlock("image/png", "iVBORw0KGgo="),
+		DocumentBlock("application/pdf", strings.Repeat("QUJD", 1000), ""),
+	}}})
+	if media != messageOverhead+2+imageTokens+750 {
+		t.Errorf("Unexpected estimate for media %d", media)
+	}
+}
+
+// stubTool is a tool definition that is never executed
+type stubTool struct {
+	name        string
+	description string
+	schema      InputSchema
+}
+
+func (t stubTool) GetName() string                               { return t.name }
+func (t stubTool) GetDescription() string                        { return t.description }
+func (t stubTool) GetSchema() InputSchema                        { return t.schema }
+func (t stubTool) Execute(input json.RawMessage) (string, error) { return "", nil }
+func (t stubTool) IsNamespaced() bool                            { return false }
+func (t stubTool) Namespace(namespace string)                    {}
+
+// TestEstimateToolTokens tests that tool definitions are estimated from their parts
+func TestEstimateToolTokens(t *testing.T) {
+	tool := stubTool{name: "get_weather", description: "Get the weather", schema: InputSchema{Type: "object"}}
+	schema, _ := json.Marshal(tool.schema)
+
+	want := toolOverhead + 3 + 4 + EstimateTextTokens(string(schema))
+	if tokens := EstimateToolTokens([]ToolExecutor{tool}); tokens != want {
+		t.Errorf("Expected %d tokens for a tool, got %d", want, tokens)
+	}
+}
@@ -1,5 +1,7 @@
 package models
 
+import "encoding/json"
+
 // Role constants define the standard roles used in LLM conversations.
 // These roles indicate who is speaking in each message of a conversation.
 const (
@@ -19,6 +21,9 @@ const (
 // ToolUse represents an AI model's request to use a tool.
 // It contains the tool name and input parameters formatted as a JSON string.
 type ToolUse struct {
+	// ID uniquely identifies this tool call so its result can be matched to it.
+	ID string `json:"id,omitempty"`
+
 	// Name is the identifier of the tool to be called.
 	Name string `json:"name"`
 
@@ -30,6 +35,9 @@ type ToolUse struct {
 // ToolResult represents the result of a tool execution.
 // It's used to track and communicate the outcome of tool calls back to the AI.
 type ToolResult struct {
+	// ToolUseID is the ID of the tool call this result answers, if known.
+	ToolUseID string `json:"tool_use_id,omitempty"`
+
 	// Name is the name of the tool that was executed.
 	Name string `json:"name"`
 
@@ -89,6 +97,146 @@ type Message struct {
 
 	// ToolResult is present when including the result of a tool execution.
 	ToolResult *ToolResult `json:"tool_result,omitempty"`
+
+	// Blocks holds structured content such as tool calls and their results.
+	// When set, providers send the blocks instead of Content.
+	Blocks []ContentBlock `json:"blocks,omitempty"`
+
+	// CacheBreakpoint asks providers with explicit prompt caching, such as
+	// Claude, to cache the conversation up to and including this message.
+	CacheBreakpoint bool `json:"cache_breakpoint,omitempty"`
+}
+
+// Content block types used in Message.Blocks.
+const (
+	// BlockText is a block of plain text.
+	BlockText = "text"
+
+	// BlockToolUse is a request from the model to call a tool.
+	BlockToolUse = "tool_use"
+
+	// BlockToolResult is the result of a tool call, sent back to the model.
+	BlockToolResult = "tool_result"
+
+	// BlockImage is an image sent to the model.
+	BlockImage = "image"
+
+	// BlockDocument is a document, such as a PDF, sent to the model.
+	BlockDocument = "document"
+
+	// BlockThinking is the model's reasoning before its answer, signed by the
+	// provider so it can be sent back unaltered.
+	BlockThinking = "thinking"
+
+	// BlockRedactedThinking is reasoning the provider encrypted, sent back as is.
+	BlockRedactedThinking = "redacted_thinking"
+)
+
+// ContentBlock represents a single structured piece of a message.
+// Only the fields relevant to the block type are populated.
+type ContentBlock struct {
+	// Type is the kind of block (text, tool_use, tool_result, image, document,
+	// thinking, redacted_thinking).
+	Type string `json:"type"`
+
+	// Text is the content of a text or thinking block.
+	Text string `json:"text,omitempty"`
+
+	// ID uniquely identifies a tool_use block.
+	ID string `json:"id,omitempty"`
+
+	// Name is the name of the tool called by a tool_use block,
+	// or the file name of a document block.
+	Name string `json:"name,omitempty"`
+
+	// Input holds the JSON arguments of a tool_use block.
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// ToolUseID links a tool_result block to the tool_use block it answers.
+	ToolUseID string `json:"tool_use_id,omitempty"`
+
+	// Content is the output of the tool for a tool_result block.
+	Content string `json:"content,omitempty"`
+
+	// IsError marks a tool_result block whose tool execution failed.
+	IsError bool `json:"is_error,omitempty"`
+
+	// MimeType is the media type of an image or document block.
+	MimeType string `json:"mime_type,omitempty"`
+
+	// Data is the base64 encoded content of an image or document block,
+	// or the encrypted reasoning of a redacted_thinking block.
+	Data string `json:"data,omitempty"`
+
+	// Signature verifies a thinking block when it is sent back to the model.
+	Signature string `json:"signature,omitempty"`
+}
+
+// TextBlock creates a text content block.
+func TextBlock(text string) ContentBlock {
+	return ContentBlock{Type: BlockText, Text: text}
+}
+
+// ToolUseBlock creates a tool_use content block for a call to the named tool.
+func ToolUseBlock(id string, name string, input json.RawMessage) ContentBlock {
+	return ContentBlock{Type: BlockToolUse, ID: id, Name: name, Input: input}
+}
+
+// ToolResultBlock creates a tool_result content block answering the tool call with the given ID.
+func ToolResultBlock(toolUseID string, content string, isError bool) ContentBlock {
+	return ContentBlock{Type: BlockToolResult, ToolUseID: toolUseID, Content: content, IsError: isError}
+}
+
+// ImageBlock creates an image content block from base64 encoded data,
+// e.g. ImageBlock("image/png", data). See ImageFile to read an image from disk.
+func ImageBlock(mimeType string, data string) ContentBlock {
+	return ContentBlock{Type: BlockImage, MimeType: mimeType, Data: data}
+}
+
+// DocumentBlock creates a document content block from base64 encoded data,
+// e.g. DocumentBlock("application/pdf", data, "report.pdf"). The name is
+// optional. See DocumentFile to read a document from disk.
+func DocumentBlock(mimeType string, data string, name string) ContentBlock {
+	return ContentBlock{Type: BlockDocument, MimeType: mimeType, Data: data, Name: name}
+}
+
+// ThinkingBlock creates a thinking content block with the provider's signature.
+func ThinkingBlock(thinking string, signature string) ContentBlock {
+	return ContentBlock{Type: BlockThinking, Text: thinking, Signature: signature}
+}
+
+// RedactedThinkingBlock creates a redacted_thinking content block from the
+// encrypted reasoning returned by the provider.
+func RedactedThinkingBlock(data string) ContentBlock {
+	return ContentBlock{Type: BlockRedactedThinking, Data: data}
+}
+
+// Text returns the textual content of the message.
+// For messages made of blocks, the text blocks are concatenated.
+func (m Message) Text() string {
+	if len(m.Blocks) == 0 {
+		return m.Content
+	}
+
+	var text string
+	for _, block := range m.Blocks {
+		if block.Type == BlockText {
+			text += block.Text
+		}
+	}
+	return text
+}
+
+// Thinking returns the reasoning the model produced before its answer, with
+// the thinking blocks concatenated. Redacted reasoning is not included.
+func (m Message) Thinking() string {
+	var thinking string
+	for _, block := range m.Blocks {
+		if block.Type == BlockThinking {
+			thinking += block.Text
+		}
+	}
+	return thinking
 }
 
 // InputSchema defines the structure of tool inputs following a simplified JSON Schema format.
@@ -212,3 +360,20 @@ type Property struct {
 	// Not specifies a schema which must not match.
 	Not *Property `json:"not,omitempty"`
 }
+
+// ResponseFormat describes the JSON a model is asked to respond with.
+type ResponseFormat struct {
+	// Name identifies the format; it must match ^[a-zA-Z0-9_-]+$.
+	Name string `json:"name"`
+
+	// Description tells the model what the response is for.
+	Description string `json:"description,omitempty"`
+
+	// Schema is the JSON Schema of the response, an object.
+	Schema InputSchema `json:"schema"`
+
+	// Strict requests exact schema adherence from providers that support it. It
+	// requires every property to be required and additional properties to be
+	// disallowed throughout the schema.
+	Strict bool `json:"strict,omitempty"`
+}
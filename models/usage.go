@@ -0,0 +1,211 @@
+package models
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Usage reports the tokens consumed by one or more calls to a model.
+// Input tokens are split by how they are billed: InputTokens excludes tokens
+// read from or written to the provider's prompt cache, which are counted in
+// CacheReadTokens and CacheCreationTokens.
+type Usage struct {
+	// InputTokens is the number of uncached input tokens
+	InputTokens int `json:"input_tokens"`
+
+	// OutputTokens is the number of generated tokens
+	OutputTokens int `json:"output_tokens"`
+
+	// CacheReadTokens is the number of input tokens read from the prompt cache
+	CacheReadTokens int `json:"cache_read_tokens,omitempty"`
+
+	// CacheCreationTokens is the number of input tokens written to the prompt cache
+	CacheCreationTokens int `json:"cache_creation_tokens,omitempty"`
+}
+
+// Add accumulates other into u.
+func (u *Usage) Add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheReadTokens += other.CacheReadTokens
+	u.CacheCreationTokens += other.CacheCreationTokens
+}
+
+// TotalTokens returns the number of input, cached and output tokens.
+func (u Usage) TotalTokens() int {
+	return u.InputTokens + u.OutputTokens + u.CacheReadTokens + u.CacheCreationTokens
+}
+
+// Price holds the cost of a model in US dollars per million tokens.
+type Price struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheRead  float64 `json:"cache_read,omitempty"`
+	CacheWrite float64 `json:"cache_write,omitempty"`
+}
+
+// PriceTable maps model names to their prices. Bond does not ship prices, as
+// they change often; callers provide the prices of the models they use.
+// Entries may be model name prefixes, e.g. "claude-3-5-sonnet" matches
+// "claude-3-5-sonnet-20241022".
+type PriceTable map[string]Price
+
+// Cost computes the cost of the usage for the given model. It returns false if
+// the table has no price for the model.
+func (t PriceTable) Cost(model string, usage Usage) (float64, bool) {
+	price, ok := t.lookup(model)
+	if !ok {
+		return 0, false
+	}
+
+	cost := float64(usage.InputTokens)*price.Input +
+		float64(usage.OutputTokens)*price.Output +
+		float64(usage.CacheReadTokens)*price.CacheRead +
+		float64(usage.CacheCreationTokens)*price.CacheWrite
+
+	return cost / 1_000_000, true
+}
+
+// lookup finds the price for a model by exact name or by longest matching prefix.
+func (t PriceTable) lookup(model string) (Price, bool) {
+	if price, ok := t[model]; ok {
+		return price, true
+	}
+
+	best := ""
+	for name := range t {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return t[best], true
+}
+
+// UsageTracker aggregates the usage reported by providers. Attach one to a
+// context with WithUsageTracker, and every provider call made with that context
+// reports its usage to it, including each round of a tool loop.
+// A UsageTracker is safe for concurrent use.
+type UsageTracker struct {
+	mu      sync.Mutex
+	total   Usage
+	byModel map[string]Usage
+	calls   int
+
+	// OnUsage, if set, is called after every reported call with the model
+	// and the usage of that call.
+	OnUsage func(model string, usage Usage)
+}
+
+// NewUsageTracker creates an empty usage tracker.
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{byModel: make(map[string]Usage)}
+}
+
+// Record adds the usage of a single call to the tracker.
+func (t *UsageTracker) Record(model string, usage Usage) {
+	t.mu.Lock()
+	t.total.Add(usage)
+	perModel := t.byModel[model]
+	perModel.Add(usage)
+	t.byModel[model] = perModel
+	t.calls++
+	onUsage := t.OnUsage
+	t.mu.Unlock()
+
+	if onUsage != nil {
+		onUsage(model, usage)
+	}
+}
+
+// Total returns the usage aggregated over all recorded calls.
+func (t *UsageTracker) Total() Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
+
+// Calls returns the number of recorded calls.
+func (t *UsageTracker) Calls() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.calls
+}
+
+// ByModel returns the usage aggregated per model.
+func (t *UsageTracker) ByModel() map[string]Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	byModel := make(map[string]Usage, len(t.byModel))
+	for model, usage := range t.byModel {
+		byModel[model] = usage
+	}
+	return byModel
+}
+
+// Cost computes the cost of the recorded usage with the given prices. Models
+// missing from the table are not counted and are returned, sorted, so callers
+// can tell whether the cost is complete.
+func (t *UsageTracker) Cost(prices PriceTable) (float64, []string) {
+	var total float64
+	var missing []string
+
+	for model, usage := range t.ByModel() {
+		cost, ok := prices.Cost(model, usage)
+		if !ok {
+			missing = append(missing, model)
+			continue
+		}
+		total += cost
+	}
+
+	sort.Strings(missing)
+	return total, missing
+}
+
+// usageTrackerKey is the context key under which the usage trackers are stored
+type usageTrackerKey struct{}
+
+// usageTrackers links a tracker to the trackers of the enclosing contexts
+type usageTrackers struct {
+	tracker *UsageTracker
+	parent  *usageTrackers
+}
+
+// WithUsageTracker returns a context carrying the tracker. If the context
+// already carries trackers, usage reported with the new context is recorded
+// by them as well, so nested trackers see their own share while outer ones
+// see the total. The nesting belongs to the context: the tracker itself is
+// left untouched and can be attached to other contexts.
+func WithUsageTracker(ctx context.Context, tracker *UsageTracker) context.Context {
+	parent, _ := ctx.Value(usageTrackerKey{}).(*usageTrackers)
+	return context.WithValue(ctx, usageTrackerKey{}, &usageTrackers{tracker: tracker, parent: parent})
+}
+
+// UsageTrackerFrom returns the innermost tracker carried by the context, or nil.
+func UsageTrackerFrom(ctx context.Context) *UsageTracker {
+	if trackers, _ := ctx.Value(usageTrackerKey{}).(*usageTrackers); trackers != nil {
+		return trackers.tracker
+	}
+	return nil
+}
+
+// ReportUsage records the usage of a call in every tracker carried by the
+// context, once each. Providers call it once per request sent to the model.
+func ReportUsage(ctx context.Context, model string, usage Usage) {
+	trackers, _ := ctx.Value(usageTrackerKey{}).(*usageTrackers)
+
+	seen := make(map[*UsageTracker]bool)
+	for ; trackers != nil; trackers = trackers.parent {
+		if trackers.tracker == nil || seen[trackers.tracker] {
+			continue
+		}
+		seen[trackers.tracker] = true
+		trackers.tracker.Record(model, usage)
+	}
+}
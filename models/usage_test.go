@@ -0,0 +1,90 @@
+package models
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+// TestPriceTableCost tests cost computation with exact and prefix matches
+func TestPriceTableCost(t *testing.T) {
+	prices := PriceTable{
+		"claude-3-5-sonnet": {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+		"gpt-4o":            {Input: 2.5, Output: 10},
+		"gpt-4o-mini":       {Input: 0.15, Output: 0.6},
+	}
+
+	usage := Usage{InputTokens: 1_000_000, OutputTokens: 100_000, CacheReadTokens: 1_000_000, CacheCreationTokens: 0}
+
+	cost, ok := prices.Cost("claude-3-5-sonnet-20241022", usage)
+	if !ok || math.Abs(cost-4.8) > 1e-9 {
+		t.Errorf("Expected cost 4.8, got %v (found %v)", cost, ok)
+	}
+
+	// The longest prefix wins
+	cost, ok = prices.Cost("gpt-4o-mini-2024-07-18", Usage{InputTokens: 1_000_000})
+	if !ok || math.Abs(cost-0.15) > 1e-9 {
+		t.Errorf("Expected cost 0.15, got %v (found %v)", cost, ok)
+	}
+
+	if _, ok := prices.Cost("llama3.1", usage); ok {
+		t.Errorf("Expected no price for unknown model")
+	}
+}
+
+// TestUsageTracker tests aggregation through the context, including nested trackers
+func TestUsageTracker(t *testing.T) {
+	outer := NewUsageTracker()
+	inner := NewUsageTracker()
+
+	var reported int
+	outer.OnUsage = func(model string, usage Usage) {
+		reported++
+	}
+
+	ctx := WithUsageTracker(context.Background(), outer)
+	ReportUsage(ctx, "gpt-4o", Usage{InputTokens: 10, OutputTokens: 5})
+
+	innerCtx := WithUsageTracker(ctx, inner)
+	ReportUsage(innerCtx, "claude-3-5-sonnet-20241022", Usage{InputTokens: 20, OutputTokens: 7, CacheReadTokens: 100})
+
+	if inner.Total() != (Usage{InputTokens: 20, OutputTokens: 7, CacheReadTokens: 100}) {
+		t.Errorf("Unexpected inner total %+v", inner.Total())
+	}
+
+	if outer.Total().TotalTokens() != 142 || outer.Calls() != 2 || reported != 2 {
+		t.Errorf("Expected outer tracker to see both calls, got %+v over %d calls", outer.Total(), outer.Calls())
+	}
+
+	cost, missing := outer.Cost(PriceTable{"gpt-4o": {Input: 1_000_000, Output: 1_000_000}})
+	if cost != 15 || len(missing) != 1 || missing[0] != "claude-3-5-sonnet-20241022" {
+		t.Errorf("Expected cost 15 with claude missing, got %v, %v", cost, missing)
+	}
+
+	// Reporting without a tracker is a no-op
+	ReportUsage(context.Background(), "gpt-4o", Usage{InputTokens: 1})
+}
+
+// TestUsageTrackerNesting tests that nesting belongs to the context, so trackers nested in both orders do not loop
+func TestUsageTrackerNesting(t *testing.T) {
+	a := NewUsageTracker()
+	b := NewUsageTracker()
+
+	ab := WithUsageTracker(WithUsageTracker(context.Background(), a), b)
+	ba := WithUsageTracker(WithUsageTracker(context.Background(), b), a)
+	aba := WithUsageTracker(ab, a)
+
+	ReportUsage(ab, "gpt-4o", Usage{InputTokens: 1})
+	ReportUsage(ba, "gpt-4o", Usage{InputTokens: 1})
+	ReportUsage(aba, "gpt-4o", Usage{InputTokens: 1})
+
+	if a.Calls() != 3 || b.Calls() != 3 {
+		t.Errorf("Expected every call recorded once per tracker, got %d and %d", a.Calls(), b.Calls())
+	}
+
+	// A tracker used alone only sees its own calls
+	ReportUsage(WithUsageTracker(context.Background(), b), "gpt-4o", Usage{InputTokens: 1})
+	if a.Calls() != 3 || b.Calls() != 4 {
+		t.Errorf("Expected the earlier nesting not to stick to the tracker, got %d and %d", a.Calls(), b.Calls())
+	}
+}
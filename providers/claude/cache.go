@@ -0,0 +1,93 @@
+package claude
+
+// maxCacheBreakpoints is the number of cache_control breakpoints Claude accepts per request
+const maxCacheBreakpoints = 4
+
+// Cache lifetimes accepted by CacheConfig.TTL
+const (
+	// CacheTTL5m keeps cache entries for five minutes, refreshed on every hit
+	CacheTTL5m = "5m"
+
+	// CacheTTL1h keeps cache entries for an hour, at a higher write cost
+	CacheTTL1h = "1h"
+)
+
+// CacheConfig selects the parts of each request marked with ephemeral
+// cache_control breakpoints. Claude caches the request up to each breakpoint,
+// so that later requests sharing the prefix read it from the cache instead of
+// processing it again. Cache reads and writes are reported as
+// models.Usage.CacheReadTokens and CacheCreationTokens.
+type CacheConfig struct {
+	// System caches the system prompt, along with the tool definitions that
+	// precede it in the prompt
+	System bool
+
+	// Tools caches the tool definitions
+	Tools bool
+
+	// Conversation places a breakpoint on the last message of every request, so
+	// that each round of a tool loop or ReAct run reads the conversation cached
+	// by the previous one
+	Conversation bool
+
+	// TTL is the lifetime of cache entries, CacheTTL5m when empty
+	TTL string
+}
+
+// cacheControl marks the end of a cached prefix of the request
+type cacheControl struct {
+	Type string `json:"type"`
+	TTL  string `json:"ttl,omitempty"`
+}
+
+// control returns the cache_control marker for the configured lifetime
+func (c CacheConfig) control() *cacheControl {
+	return &cacheControl{Type: "ephemeral", TTL: c.TTL}
+}
+
+// SetCache configures the parts of requests that Claude caches.
+func (p *Provider) SetCache(config CacheConfig) {
+	p.Cache = config
+}
+
+// applyCache adds cache_control breakpoints to the payload according to the
+// provider's cache configuration and the messages marked with
+// models.Message.CacheBreakpoint. When there are more breakpoints than Claude
+// accepts, the earliest message breakpoints are dropped.
+func (p *Provider) applyCache(payload map[string]any, messages []message) {
+	config := p.Cache
+	available := maxCacheBreakpoints
+
+	if system, ok := payload["system"].(string); ok && config.System {
+		payload["system"] = []contentBlock{{Type: "text", Text: system, CacheControl: config.control()}}
+		available--
+	}
+
+	if tools, ok := payload["tools"].([]map[string]any); ok && len(tools) > 0 && config.Tools {
+		tools[len(tools)-1]["cache_control"] = config.control()
+		available--
+	}
+
+	// The last message is cached first as it covers every earlier breakpoint
+	if config.Conversation && len(messages) > 0 {
+		last := messages[len(messages)-1].Content
+		if len(last) > 0 && last[len(last)-1].CacheControl == nil {
+			last[len(last)-1].CacheControl = &cacheControl{Type: "ephemeral"}
+		}
+	}
+
+	for i := len(messages) - 1; i >= 0; i-- {
+		content := messages[i].Content
+		if len(content) == 0 || content[len(content)-1].CacheControl == nil {
+			continue
+		}
+
+		block := &content[len(content)-1]
+		if available == 0 {
+			block.CacheControl = nil
+			continue
+		}
+		block.CacheControl = config.control()
+		available--
+	}
+}
@@ -4,15 +4,14 @@
 package claude
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 	"time"
 
-	"github.com/devOpifex/bond/mcp"
 	"github.com/devOpifex/bond/models"
 	"github.com/devOpifex/bond/providers/common"
 )
@@ -33,6 +32,10 @@ type Provider struct {
 	// HTTPClient is used for making requests to Claude's API
 	HTTPClient *http.Client
 
+	// StreamHTTPClient is used for streamed responses. When nil, HTTPClient is
+	// used without its total timeout, see common.StreamingClient.
+	StreamHTTPClient *http.Client
+
 	// System prompt provides context and instructions for the model
 	SystemPrompt string
 
@@ -45,22 +48,45 @@ type Provider struct {
 	// Tools that have been registered for use with this provider
 	Tools []models.ToolExecutor
 
-	MCPs map[string]*mcp.MCP
+	// MaxToolRounds limits how many rounds of tool calls a single request may
+	// run. Calling tools once more fails with common.ErrMaxToolRounds before they run.
+	// Zero or less means common.DefaultMaxToolRounds.
+	MaxToolRounds int
+
+	// MCPs holds the MCP clients serving namespaced tools
+	MCPs common.MCPClients
+
+	// Retry controls how requests are retried after rate limits, overloaded
+	// errors and other transient failures
+	Retry common.RetryPolicy
+
+	// Cache selects the parts of requests cached by Claude, nothing by default
+	Cache CacheConfig
+
+	// ThinkingBudget enables extended thinking when positive: the number of
+	// tokens Claude may spend reasoning before it answers. It must be at least
+	// 1024 and less than MaxTokens.
+	ThinkingBudget int
 }
 
+// Provider must satisfy the models.Provider interface
+var _ models.Provider = (*Provider)(nil)
+
 // New creates a new Claude provider with the given API key.
 // It sets up default values that can be customized through
 // the provider's configuration methods.
 func New(apiKey string) *Provider {
 	return &Provider{
-		APIKey:      apiKey,
-		BaseURL:     "https://api.anthropic.com/v1/messages",
-		Model:       "claude-3-opus-20240229",
-		HTTPClient:  &http.Client{Timeout: 60 * time.Second},
-		MaxTokens:   1024,
-		Temperature: 0.7,
-		Tools:       []models.ToolExecutor{},
-		MCPs:        make(map[string]*mcp.MCP),
+		APIKey:        apiKey,
+		BaseURL:       "https://api.anthropic.com/v1/messages",
+		Model:         "claude-3-opus-20240229",
+		HTTPClient:    &http.Client{Timeout: 60 * time.Second},
+		MaxTokens:     1024,
+		Temperature:   0.7,
+		Tools:         []models.ToolExecutor{},
+		MaxToolRounds: common.DefaultMaxToolRounds,
+		MCPs:          make(common.MCPClients),
+		Retry:         common.DefaultRetryPolicy(),
 	}
 }
 
@@ -95,6 +121,20 @@ func (p *Provider) SetTemperature(temperature float64) {
 	p.Temperature = temperature
 }
 
+// SetThinkingBudget enables extended thinking with the given token budget, or
+// disables it when tokens is 0. Claude's reasoning is returned as thinking
+// blocks on the assistant's turns, see models.Message.Thinking. The temperature
+// and top_k cannot be set while thinking is enabled.
+func (p *Provider) SetThinkingBudget(tokens int) {
+	p.ThinkingBudget = tokens
+}
+
+// SetRetryPolicy configures how requests are retried after rate limits,
+// overloaded errors and other transient failures.
+func (p *Provider) SetRetryPolicy(policy common.RetryPolicy) {
+	p.Retry = policy
+}
+
 // RegisterTool adds a tool to the provider's available tools.
 // These tools will be included in the API request to Claude,
 // allowing the model to use them during its reasoning process.
@@ -114,57 +154,31 @@ func (p *Provider) RegisterTool(tool models.ToolExecutor) {
 // SendMessage sends a message to Claude and returns the model's response.
 // This method does not include tool information in the request.
 func (p *Provider) SendMessage(ctx context.Context, message models.Message) (string, error) {
-	return p.sendRequest(ctx, message, false)
+	conversation := models.NewConversation("").Append(message)
+	return p.sendRequest(ctx, conversation, false)
 }
 
 // SendMessageWithTools sends a message to Claude with available tools.
 // This method includes information about registered tools in the request,
 // allowing Claude to call these tools during its reasoning process.
 func (p *Provider) SendMessageWithTools(ctx context.Context, message models.Message) (string, error) {
-	return p.sendRequest(ctx, message, true)
+	conversation := models.NewConversation("").Append(message)
+	return p.sendRequest(ctx, conversation, true)
 }
 
-// prepareChatContext constructs the conversation history to send to Claude.
-// It handles extracting message history from the context if available,
-// and applying Claude-specific formatting for tool results.
-func (p *Provider) prepareChatContext(ctx context.Context, message models.Message) []models.Message {
-	// Extract message history from context if available
-	var messageHistory []models.Message
-	if history, ok := ctx.Value("message_history").([]models.Message); ok {
-		messageHistory = make([]models.Message, len(history))
-		copy(messageHistory, history)
-	} else {
-		messageHistory = []models.Message{}
-	}
-
-	// Special handling for messages to Claude API
-	// Tool results need to be formatted specially - for Claude we need to preserve
-	// the original message and include the tool result as a user message
-	if message.Role == models.RoleFunction && message.ToolResult != nil {
-		// Format the tool result message
-		userMsg := models.Message{
-			Role: models.RoleUser,
-			Content: fmt.Sprintf("Tool '%s' returned: %s",
-				message.ToolResult.Name, message.Content),
-		}
-
-		// Add the tool result as a user message to the history
-		messageHistory = append(messageHistory, userMsg)
-	} else {
-		// For regular messages, add to history
-		messageHistory = append(messageHistory, message)
-	}
-
-	// Create a clean message list for Claude with only supported roles (user/assistant)
-	return convertMessagesToClaudeFormat(messageHistory)
+// SendConversation sends the whole conversation to Claude with available tools.
+// Claude's turns, including tool_use blocks and the matching tool results,
+// are appended to the conversation so it can be continued later.
+func (p *Provider) SendConversation(ctx context.Context, conversation *models.Conversation) (string, error) {
+	return p.sendRequest(ctx, conversation, true)
 }
 
-// prepareToolsForRequest converts the registered tools to Claude's API format.
+// convertTools converts tools to Claude's API format.
 // It builds a list of tool definitions that Claude can understand and use.
-func (p *Provider) prepareToolsForRequest() []map[string]any {
-	toolDefinitions := make([]map[string]any, 0, len(p.Tools))
+func convertTools(tools []models.ToolExecutor) []map[string]any {
+	toolDefinitions := make([]map[string]any, 0, len(tools))
 
-	for _, tool := range p.Tools {
+	for _, tool := range tools {
 		// Convert each tool to Claude's expected format
 		schema := tool.GetSchema()
 
@@ -189,13 +203,12 @@ func (p *Provider) prepareToolsForRequest() []map[string]any {
 	return toolDefinitions
 }
 
-// sendRequest handles the common logic for sending requests to Claude's API.
-// It prepares the request payload, sends it to the API, and processes the response.
-func (p *Provider) sendRequest(ctx context.Context, message models.Message, withTools bool) (string, error) {
-	// Build the Claude-formatted messages
-	messages := p.prepareChatContext(ctx, message)
-
-	// Create the request payload
+// buildPayload creates the request payload for Claude's Messages API.
+// The conversation's system prompt takes precedence over the provider's.
+// Tool definitions are only included when withTools is set and tools are registered.
+// Cache breakpoints are added last, see CacheConfig.
+func (p *Provider) buildPayload(conversation *models.Conversation, withTools bool) map[string]any {
+	messages := convertMessagesToClaudeFormat(conversation.Messages)
 	payload := map[string]any{
 		"model":       p.Model,
 		"messages":    messages,
@@ -204,162 +217,206 @@ func (p *Provider) sendRequest(ctx context.Context, message models.Message, with
 	}
 
 	// Add system prompt if provided
-	if p.SystemPrompt != "" {
-		payload["system"] = p.SystemPrompt
+	systemPrompt := p.SystemPrompt
+	if conversation.SystemPrompt != "" {
+		systemPrompt = conversation.SystemPrompt
+	}
+	if systemPrompt != "" {
+		payload["system"] = systemPrompt
+	}
+
+	// Thinking is incompatible with a temperature other than the default
+	if p.ThinkingBudget > 0 {
+		payload["thinking"] = map[string]any{"type": "enabled", "budget_tokens": p.ThinkingBudget}
+		delete(payload, "temperature")
 	}
 
 	// Add tools if requested and available
 	if withTools && len(p.Tools) > 0 {
-		toolDefinitions := p.prepareToolsForRequest()
+		toolDefinitions := convertTools(p.Tools)
 		payload["tools"] = toolDefinitions
 	}
 
+	p.applyCache(payload, messages)
+
+	return payload
+}
+
+// doRequest sends the payload to the given endpoint of Claude's API with the
+// given HTTP client and returns the raw HTTP response. Non-200 responses are turned into typed errors (see common.NewAPIError), so the
+// caller only has to deal with successful responses and is responsible for closing the body.
+func (p *Provider) doRequest(ctx context.Context, client *http.Client, url string, payload map[string]any) (*http.Response, error) {
 	// Convert the payload to JSON
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal Claude request: %w", err)
+		return nil, fmt.Errorf("failed to marshal Claude request: %w", err)
 	}
 
-	// Create the HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", p.BaseURL, strings.NewReader(string(payloadBytes)))
+	// Send the request, retrying transient failures with a fresh request each time
+	resp, err := p.Retry.Do(ctx, func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payloadBytes))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Claude request: %w", err)
+		}
+
+		// Set the required headers
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-api-key", p.APIKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+
+		return client.Do(req)
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to create Claude request: %w", err)
+		return nil, fmt.Errorf("Claude API request failed: %w", err)
 	}
 
-	// Set the required headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", p.APIKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
+	// Check for API error
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Claude API response: %w", err)
+		}
+
+		return nil, common.NewAPIError("Claude", resp.StatusCode, resp.Header, body)
+	}
+
+	return resp, nil
+}
+
+// sendRequest handles the common logic for sending requests to Claude's API.
+// It prepares the request payload, sends it to the API, and processes the response.
+// When Claude stops to use tools, the tools are executed and their results are sent
+// back as tool_result blocks until Claude produces a final answer. Every turn is
+// appended to the conversation. Generation options in the context apply to every
+// request, see models.GenerationOptions.FollowUp.
+func (p *Provider) sendRequest(ctx context.Context, conversation *models.Conversation, withTools bool) (string, error) {
+	opts := models.OptionsFrom(ctx)
+
+	for round := 0; ; round++ {
+		if err := models.CheckBudget(ctx, conversation.Messages); err != nil {
+			return "", err
+		}
+
+		if err := models.FitConversation(ctx, conversation); err != nil {
+			return "", err
+		}
 
-	// Send the request
-	resp, err := p.HTTPClient.Do(req)
+		payload := p.buildPayload(conversation, withTools)
+		if err := p.applyOptions(payload, opts); err != nil {
+			return "", err
+		}
+
+		claudeResp, err := p.createMessage(ctx, payload)
+		if err != nil {
+			return "", err
+		}
+		opts = opts.FollowUp()
+		p.reportUsage(ctx, claudeResp.Model, claudeResp.Usage)
+
+		assistantMessage := models.Message{
+			Role:   models.RoleAssistant,
+			Blocks: claudeResp.blocks(),
+		}
+
+		// Anything but a tool call ends the exchange
+		if claudeResp.StopReason != "tool_use" {
+			conversation.Append(assistantMessage)
+			return assistantMessage.Text(), nil
+		}
+
+		if common.ToolRoundsExceeded(round, p.MaxToolRounds) {
+			return "", common.ErrMaxToolRounds
+		}
+
+		// Keep the assistant's tool_use turn and answer every call it made
+		conversation.Append(assistantMessage, p.runTools(ctx, assistantMessage.Blocks))
+	}
+}
+
+// reportUsage reports the usage of a request to the usage tracker in the context.
+// The model reported by the API is preferred as it names the exact version used.
+func (p *Provider) reportUsage(ctx context.Context, model string, u usage) {
+	if model == "" {
+		model = p.Model
+	}
+	models.ReportUsage(ctx, model, u.convert())
+}
+
+// createMessage sends the payload to Claude's API and parses the response.
+func (p *Provider) createMessage(ctx context.Context, payload map[string]any) (*messageResponse, error) {
+	resp, err := p.doRequest(ctx, p.HTTPClient, p.BaseURL, payload)
 	if err != nil {
-		return "", fmt.Errorf("Claude API request failed: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	// Read the response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read Claude API response: %w", err)
+		return nil, fmt.Errorf("failed to read Claude API response: %w", err)
 	}
 
-	// Check for API error
-	if resp.StatusCode != http.StatusOK {
-		// Try to parse the error message
-		var errorResp common.ErrorResponse
-		if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error != nil {
-			return "", fmt.Errorf("Claude API error (%d): %s", resp.StatusCode, errorResp.Error.Message)
-		}
-		return "", fmt.Errorf("Claude API error (%d): %s", resp.StatusCode, string(body))
+	// Parse the successful response
+	var claudeResp messageResponse
+	if err := json.Unmarshal(body, &claudeResp); err != nil {
+		return nil, fmt.Errorf("failed to parse Claude API response: %w", err)
 	}
 
-	// Parse the successful response
-	var claudeResp struct {
-		Content []struct {
-			Type  string          `json:"type"`
-			Text  string          `json:"text,omitempty"`
-			Name  string          `json:"name,omitempty"`
-			Input json.RawMessage `json:"input,omitempty"`
-		} `json:"content"`
-		StopReason string `json:"stop_reason"`
+	return &claudeResp, nil
+}
+
+// runTools executes every tool_use block and returns the user message carrying
+// a tool_result block for each of them. Failed tools are reported to Claude as
+// error results rather than aborting the request. Every call counts against
+// the budget in the context, if any, and calls beyond it are not executed.
+func (p *Provider) runTools(ctx context.Context, blocks []models.ContentBlock) models.Message {
+	results := models.Message{Role: models.RoleUser}
+
+	for _, block := range blocks {
+		if block.Type != models.BlockToolUse {
+			continue
+		}
+
+		if err := models.StartToolCall(ctx); err != nil {
+			results.Blocks = append(results.Blocks, models.ToolResultBlock(block.ID, err.Error(), true))
+			continue
+		}
+
+		result, err := p.executeTool(block.Name, block.Input)
+		if err != nil {
+			results.Blocks = append(results.Blocks, models.ToolResultBlock(block.ID, err.Error(), true))
+			continue
+		}
+		results.Blocks = append(results.Blocks, models.ToolResultBlock(block.ID, result, false))
 	}
 
-	if err := json.Unmarshal(body, &claudeResp); err != nil {
-		return "", fmt.Errorf("failed to parse Claude API response: %w", err)
+	return results
+}
+
+// executeTool runs the named tool with the given input, dispatching namespaced
+// tools to the MCP client registered for their namespace.
+// The returned error is worded so that it can be sent to the model as a tool result.
+func (p *Provider) executeTool(name string, input json.RawMessage) (string, error) {
+	// Find the tool
+	tool, exists := p.findTool(name)
+	if !exists {
+		return "", fmt.Errorf("tool '%s' not found", name)
 	}
 
-	// Check if Claude wants to use a tool
-	if claudeResp.StopReason == "tool_use" {
-		// Find the tool use request
-		for _, content := range claudeResp.Content {
-			if content.Type == "tool_use" {
-				// Find the tool
-				tool, exists := p.findTool(content.Name)
-				if !exists {
-					return fmt.Sprintf("Error: Tool '%s' not found", content.Name), nil
-				}
-
-				var result string
-				var err error
-
-				// Check if this is a namespaced MCP tool
-				if tool.IsNamespaced() {
-					// Extract namespace from tool name
-					parts := strings.Split(tool.GetName(), "__")
-					if len(parts) > 1 {
-						namespace := parts[0]
-						toolName := parts[1]
-
-						// Find the MCP client for this namespace
-						mcpClient, exists := p.MCPs[namespace]
-						if !exists {
-							return fmt.Sprintf("Error: MCP for namespace '%s' not found", namespace), nil
-						}
-
-						// Parse the input JSON
-						var args map[string]any
-						if err := json.Unmarshal(content.Input, &args); err != nil {
-							return fmt.Sprintf("Error parsing tool arguments: %v", err), nil
-						}
-
-						// Call the tool via MCP
-						toolResult, err := mcpClient.CallTool(toolName, args)
-						if err != nil {
-							return fmt.Sprintf("Error executing MCP tool '%s': %v", content.Name, err), nil
-						}
-
-						// Format the result based on content items
-						var formattedResult string
-						if len(toolResult.Content) > 0 {
-							for _, item := range toolResult.Content {
-								switch item.Type {
-								case "text":
-									formattedResult += item.Text
-								case "image":
-									formattedResult += fmt.Sprintf("[Image: %s]", item.MimeType)
-								default:
-									formattedResult += fmt.Sprintf("[Content type: %s]", item.Type)
-								}
-								formattedResult += "\n"
-							}
-							result = strings.TrimSpace(formattedResult)
-						} else {
-							// Fallback to the simple result
-							result = toolResult.Result
-						}
-					} else {
-						return fmt.Sprintf("Error: Invalid namespaced tool format '%s'", content.Name), nil
-					}
-				} else {
-					// Regular tool execution
-					result, err = tool.Execute(content.Input)
-					if err != nil {
-						return fmt.Sprintf("Error executing tool '%s': %v", content.Name, err), nil
-					}
-				}
-
-				// Now we need to send the tool result back to Claude in a new request
-				toolResultMessage := models.Message{
-					Role:    models.RoleUser,
-					Content: fmt.Sprintf("Tool '%s' returned: %s", content.Name, result),
-				}
-
-				// Recursive call to send the tool result back to Claude
-				return p.sendRequest(ctx, toolResultMessage, true)
-			}
-		}
+	// Namespaced tools are served by an MCP client
+	if tool.IsNamespaced() {
+		return p.MCPs.CallTool(tool.GetName(), input)
 	}
 
-	// Extract the text from the response
-	var responseText string
-	for _, content := range claudeResp.Content {
-		if content.Type == "text" {
-			responseText += content.Text
-		}
+	// Regular tool execution
+	result, err := tool.Execute(input)
+	if err != nil {
+		return "", fmt.Errorf("error executing tool '%s': %v", name, err)
 	}
 
-	return responseText, nil
+	return result, nil
 }
 
 // findTool looks up a tool by name in the provider's tools
@@ -374,58 +431,152 @@ func (p *Provider) findTool(name string) (models.ToolExecutor, bool) {
 
 // convertMessagesToClaudeFormat transforms our internal message format to Claude's API format.
 // Claude only supports user and assistant roles, so this function handles the conversion
-// of system and function messages appropriately.
-func convertMessagesToClaudeFormat(messages []models.Message) []models.Message {
+// of system and function messages appropriately. Message content is always sent as a
+// list of content blocks so that tool calls and their results keep their IDs.
+func convertMessagesToClaudeFormat(messages []models.Message) []message {
 	// Claude only supports user and assistant roles
-	claudeMessages := []models.Message{}
+	claudeMessages := []message{}
 
 	for _, msg := range messages {
+		converted := len(claudeMessages)
+
 		switch msg.Role {
-		case models.RoleUser:
-			// User messages pass through directly
-			claudeMessages = append(claudeMessages, msg)
-		case models.RoleAssistant:
-			// Assistant messages pass through directly
-			claudeMessages = append(claudeMessages, msg)
+		case models.RoleUser, models.RoleAssistant:
+			// User and assistant messages pass through with their content converted
+			claudeMessages = append(claudeMessages, message{
+				Role:    msg.Role,
+				Content: convertContent(msg),
+			})
 		case models.RoleSystem:
 			// System messages in Claude are handled separately, not as a message
 			// We don't include them in the messages array
 			continue
 		case models.RoleFunction:
-			// Function messages are transformed to user messages in the prepare method
-			// This should already be handled, but just in case
-			userMsg := models.Message{
-				Role:    models.RoleUser,
-				Content: fmt.Sprintf("Function returned: %s", msg.Content),
+			// Function results linked to a tool call become tool_result blocks
+			if msg.ToolResult != nil && msg.ToolResult.ToolUseID != "" {
+				claudeMessages = append(claudeMessages, message{
+					Role: models.RoleUser,
+					Content: []contentBlock{{
+						Type:      models.BlockToolResult,
+						ToolUseID: msg.ToolResult.ToolUseID,
+						Content:   msg.Content,
+						IsError:   msg.ToolResult.IsError,
+					}},
+				})
+				break
 			}
-			claudeMessages = append(claudeMessages, userMsg)
+
+			// Other function messages are described in a user message
+			text := fmt.Sprintf("Function returned: %s", msg.Content)
+			if msg.ToolResult != nil {
+				text = fmt.Sprintf("Tool '%s' returned: %s", msg.ToolResult.Name, msg.Content)
+			}
+			claudeMessages = append(claudeMessages, message{
+				Role:    models.RoleUser,
+				Content: []contentBlock{{Type: models.BlockText, Text: text}},
+			})
 		default:
 			// Unknown roles are sent as user messages
-			userMsg := models.Message{
+			claudeMessages = append(claudeMessages, message{
 				Role:    models.RoleUser,
-				Content: msg.Content,
+				Content: []contentBlock{{Type: models.BlockText, Text: msg.Content}},
+			})
+		}
+
+		// Marked messages end with a cache breakpoint, see Provider.applyCache
+		if msg.CacheBreakpoint && len(claudeMessages) > converted {
+			content := claudeMessages[len(claudeMessages)-1].Content
+			if len(content) > 0 {
+				content[len(content)-1].CacheControl = &cacheControl{Type: "ephemeral"}
 			}
-			claudeMessages = append(claudeMessages, userMsg)
 		}
 	}
 
 	return claudeMessages
 }
 
-func (p *Provider) RegisterMCP(command string, args []string) error {
-	client := mcp.New(command, args)
+// convertContent converts the content of a user or assistant message to Claude content blocks.
+func convertContent(msg models.Message) []contentBlock {
+	var blocks []contentBlock
 
-	_, err := client.Initialise()
-	if err != nil {
-		return err
+	if len(msg.Blocks) == 0 {
+		if msg.Content != "" {
+			blocks = append(blocks, contentBlock{Type: models.BlockText, Text: msg.Content})
+		}
+
+		// Tool calls recorded on the message itself become tool_use blocks
+		if msg.ToolUse != nil && msg.ToolUse.ID != "" {
+			input, err := json.Marshal(msg.ToolUse.Input)
+			if err != nil || msg.ToolUse.Input == nil {
+				input = json.RawMessage("{}")
+			}
+			blocks = append(blocks, contentBlock{
+				Type:  models.BlockToolUse,
+				ID:    msg.ToolUse.ID,
+				Name:  msg.ToolUse.Name,
+				Input: input,
+			})
+		}
+
+		return blocks
 	}
 
-	for _, tool := range client.GetRegistry() {
-		tool.Namespace(command)
-		p.RegisterTool(tool)
+	for _, block := range msg.Blocks {
+		switch block.Type {
+		case models.BlockText:
+			// Claude rejects empty text blocks
+			if block.Text == "" {
+				continue
+			}
+			blocks = append(blocks, contentBlock{Type: models.BlockText, Text: block.Text})
+		case models.BlockToolUse:
+			input := block.Input
+			if len(input) == 0 {
+				input = json.RawMessage("{}")
+			}
+			blocks = append(blocks, contentBlock{
+				Type:  models.BlockToolUse,
+				ID:    block.ID,
+				Name:  block.Name,
+				Input: input,
+			})
+		case models.BlockToolResult:
+			blocks = append(blocks, contentBlock{
+				Type:      models.BlockToolResult,
+				ToolUseID: block.ToolUseID,
+				Content:   block.Content,
+				IsError:   block.IsError,
+			})
+		case models.BlockThinking:
+			// Thinking blocks are sent back unaltered, as Claude verifies their signature
+			blocks = append(blocks, contentBlock{
+				Type:      models.BlockThinking,
+				Thinking:  block.Text,
+				Signature: block.Signature,
+			})
+		case models.BlockRedactedThinking:
+			blocks = append(blocks, contentBlock{Type: models.BlockRedactedThinking, Data: block.Data})
+		case models.BlockImage:
+			blocks = append(blocks, contentBlock{
+				Type:   block.Type,
+				Source: &mediaSource{Type: "base64", MediaType: block.MimeType, Data: block.Data},
+			})
+		case models.BlockDocument:
+			// Claude takes PDFs as base64 and text documents as plain text
+			source := &mediaSource{Type: "base64", MediaType: block.MimeType, Data: block.Data}
+			if text, ok := block.DocumentText(); ok {
+				source = &mediaSource{Type: "text", MediaType: "text/plain", Data: text}
+			}
+			blocks = append(blocks, contentBlock{Type: block.Type, Source: source, Title: block.Name})
+		}
 	}
 
-	p.MCPs[command] = client
+	return blocks
+}
 
-	return nil
+// RegisterMCP starts an MCP server and registers its tools with the provider.
+// The tools are namespaced with the command so that calls can be routed back
+// to the right server.
+func (p *Provider) RegisterMCP(command string, args []string) error {
+	return p.MCPs.Register(command, args, p.RegisterTool)
 }
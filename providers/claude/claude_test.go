@@ -0,0 +1,995 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devOpifex/bond/models"
+	"github.com/devOpifex/bond/providers/common"
+	"github.com/devOpifex/bond/tools"
+)
+
+// Recorded SSE frames for a plain text response
+const textStream = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"stop_reason":null}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: ping
+data: {"type":"ping"}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"It is 5°C"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" in Brussels."}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":9}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+
+// Recorded SSE frames for a response that requests a tool
+const toolUseStream = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_0","type":"message","role":"assistant","content":[],"stop_reason":null}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me check."}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"location\":"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":" \"Brussels\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":30}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+
+// collect drains a stream into a slice of events
+func collect(events <-chan models.StreamEvent) []models.StreamEvent {
+	var all []models.StreamEvent
+	for event := range events {
+		all = append(all, event)
+	}
+	return all
+}
+
+// TestSendMessageStream tests that text deltas and the stop reason are streamed
+func TestSendMessageStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+
+		if payload["stream"] != true {
+			t.Errorf("Expected stream to be true, got %v", payload["stream"])
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(textStream))
+	}))
+	defer server.Close()
+
+	provider := New("test-api-key")
+	provider.BaseURL = server.URL
+
+	events, err := provider.SendMessageStream(context.Background(), models.Message{
+		Role:    models.RoleUser,
+		Content: "What's the weather in Brussels?",
+	})
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+
+	var text strings.Builder
+	var last models.StreamEvent
+	for _, event := range collect(events) {
+		if event.Type == models.StreamText {
+			text.WriteString(event.Text)
+		}
+		last = event
+	}
+
+	if text.String() != "It is 5°C in Brussels." {
+		t.Errorf("Expected streamed text 'It is 5°C in Brussels.', got '%s'", text.String())
+	}
+
+	if last.Type != models.StreamStop || last.StopReason != "end_turn" {
+		t.Errorf("Expected final stop event with reason 'end_turn', got %+v", last)
+	}
+}
+
+// TestSendMessageStreamOutlivesTimeout tests that the HTTP client's total
+// timeout does not cut off a response streaming for longer
+func TestSendMessageStreamOutlivesTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		start, rest, _ := strings.Cut(textStream, "event: content_block_delta")
+		w.Write([]byte(start))
+		w.(http.Flusher).Flush()
+
+		time.Sleep(150 * time.Millisecond)
+		w.Write([]byte("event: content_block_delta" + rest))
+	}))
+	defer server.Close()
+
+	provider := New("test-api-key")
+	provider.BaseURL = server.URL
+	provider.HTTPClient.Timeout = 50 * time.Millisecond
+
+	events, err := provider.SendMessageStream(context.Background(), models.Message{Role: models.RoleUser, Content: "Hi"})
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+
+	var text strings.Builder
+	for _, event := range collect(events) {
+		switch event.Type {
+		case models.StreamText:
+			text.WriteString(event.Text)
+		case models.StreamError:
+			t.Fatalf("Unexpected stream error: %v", event.Err)
+		}
+	}
+
+	if text.String() != "It is 5°C in Brussels." {
+		t.Errorf("Expected the whole answer, got '%s'", text.String())
+	}
+}
+
+// TestSendMessageStreamTruncated tests that a stream ending before message_stop is reported as an error
+func TestSendMessageStreamTruncated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		truncated, _, _ := strings.Cut(textStream, "event: content_block_stop")
+		w.Write([]byte(truncated))
+	}))
+	defer server.Close()
+
+	provider := New("test-api-key")
+	provider.BaseURL = server.URL
+
+	events, err := provider.SendMessageStream(context.Background(), models.Message{Role: models.RoleUser, Content: "Hi"})
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+
+	received := collect(events)
+	last := received[len(received)-1]
+	if last.Type != models.StreamError || !errors.Is(last.Err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected an unexpected EOF error, got %+v", last)
+	}
+}
+
+// TestSendMessageStreamToolUse tests that a streamed tool_use block runs the tool loop
+func TestSendMessageStreamToolUse(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "text/event-stream")
+		if requests == 1 {
+			w.Write([]byte(toolUseStream))
+			return
+		}
+		w.Write([]byte(textStream))
+	}))
+	defer server.Close()
+
+	var received map[string]any
+	provider := New("test-api-key")
+	provider.BaseURL = server.URL
+	provider.RegisterTool(tools.NewTool(
+		"get_weather",
+		"Get the weather",
+		models.InputSchema{Type: "object"},
+		func(params map[string]any) (string, error) {
+			received = params
+			return "5°C", nil
+		},
+	))
+
+	events, err := provider.SendMessageStream(context.Background(), models.Message{
+		Role:    models.RoleUser,
+		Content: "What's the weather in Brussels?",
+	})
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+
+	var input strings.Builder
+	var toolName string
+	for _, event := range collect(events) {
+		switch event.Type {
+		case models.StreamToolUse:
+			toolName = event.ToolName
+		case models.StreamToolInput:
+			input.WriteString(event.PartialInput)
+		case models.StreamError:
+			t.Fatalf("Unexpected stream error: %v", event.Err)
+		}
+	}
+
+	if toolName != "get_weather" {
+		t.Errorf("Expected tool_use event for 'get_weather', got '%s'", toolName)
+	}
+
+	if input.String() != `{"location": "Brussels"}` {
+		t.Errorf("Expected reassembled input, got '%s'", input.String())
+	}
+
+	if received["location"] != "Brussels" {
+		t.Errorf("Expected tool to receive location 'Brussels', got %v", received["location"])
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+}
+
+// TestSendMessageWithToolsToolResult tests that tool results are sent back as tool_result blocks
+func TestSendMessageWithToolsToolResult(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		var payload struct {
+			Messages []message `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			w.Write([]byte(`{
+				"id": "msg_0",
+				"content": [
+					{"type": "text", "text": "Let me check."},
+					{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"location": "Brussels"}}
+				],
+				"stop_reason": "tool_use"
+			}`))
+			return
+		}
+
+		if len(payload.Messages) != 3 {
+			t.Errorf("Expected 3 messages in follow-up request, got %d", len(payload.Messages))
+			return
+		}
+
+		assistant := payload.Messages[1]
+		if assistant.Role != models.RoleAssistant || len(assistant.Content) != 2 || assistant.Content[1].ID != "toolu_1" {
+			t.Errorf("Expected assistant tool_use turn to be preserved, got %+v", assistant)
+		}
+
+		result := payload.Messages[2]
+		if result.Role != models.RoleUser || len(result.Content) != 1 {
+			t.Errorf("Expected a single tool_result block, got %+v", result)
+			return
+		}
+
+		block := result.Content[0]
+		if block.Type != models.BlockToolResult || block.ToolUseID != "toolu_1" || block.Content != "5°C" || block.IsError {
+			t.Errorf("Unexpected tool_result block: %+v", block)
+		}
+
+		w.Write([]byte(`{
+			"id": "msg_1",
+			"content": [{"type": "text", "text": "It is 5°C in Brussels."}],
+			"stop_reason": "end_turn"
+		}`))
+	}))
+	defer server.Close()
+
+	provider := New("test-api-key")
+	provider.BaseURL = server.URL
+	provider.RegisterTool(tools.NewTool(
+		"get_weather",
+		"Get the weather",
+		models.InputSchema{Type: "object"},
+		func(params map[string]any) (string, error) {
+			return "5°C", nil
+		},
+	))
+
+	response, err := provider.SendMessageWithTools(context.Background(), models.Message{
+		Role:    models.RoleUser,
+		Content: "What's the weather in Brussels?",
+	})
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	if response != "It is 5°C in Brussels." {
+		t.Errorf("Expected final answer, got '%s'", response)
+	}
+}
+
+// TestMaxToolRounds tests that both the request and streaming paths run MaxToolRounds rounds of tools and no more
+func TestMaxToolRounds(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if stream {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte(toolUseStream))
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{
+				"id": "msg_0",
+				"content": [{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"location": "Brussels"}}],
+				"stop_reason": "tool_use"
+			}`))
+		}))
+
+		executions := 0
+		provider := New("test-api-key")
+		provider.BaseURL = server.URL
+		provider.MaxToolRounds = 2
+		provider.RegisterTool(tools.NewTool(
+			"get_weather",
+			"Get the weather",
+			models.InputSchema{Type: "object"},
+			func(params map[string]any) (string, error) {
+				executions++
+				return "5°C", nil
+			},
+		))
+
+		message := models.Message{Role: models.RoleUser, Content: "Weather?"}
+		var err error
+		if stream {
+			var events <-chan models.StreamEvent
+			events, err = provider.SendMessageStream(context.Background(), message)
+			if err != nil {
+				t.Fatalf("Failed to open stream: %v", err)
+			}
+			received := collect(events)
+			err = received[len(received)-1].Err
+		} else {
+			_, err = provider.SendMessageWithTools(context.Background(), message)
+		}
+		server.Close()
+
+		if !errors.Is(err, common.ErrMaxToolRounds) {
+			t.Errorf("Expected ErrMaxToolRounds when streaming is %v, got %v", stream, err)
+		}
+		if executions != 2 || requests != 3 {
+			t.Errorf("Expected 2 tool rounds over 3 requests when streaming is %v, got %d over %d", stream, executions, requests)
+		}
+	}
+}
+
+// TestStructLiteralToolRounds tests that a provider built without New still runs tools
+func TestStructLiteralToolRounds(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			w.Write([]byte(`{
+				"id": "msg_0",
+				"content": [{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"location": "Brussels"}}],
+				"stop_reason": "tool_use"
+			}`))
+			return
+		}
+		w.Write([]byte(`{"id": "msg_1", "content": [{"type": "text", "text": "It is 5°C."}], "stop_reason": "end_turn"}`))
+	}))
+	defer server.Close()
+
+	provider := &Provider{
+		APIKey:     "test-api-key",
+		BaseURL:    server.URL,
+		Model:      "claude-3-5-sonnet-20241022",
+		HTTPClient: server.Client(),
+		MaxTokens:  1024,
+		Tools: []models.ToolExecutor{tools.NewTool("get_weather", "Get the weather", models.InputSchema{Type: "object"}, func(map[string]any) (string, error) {
+			return "5°C", nil
+		})},
+	}
+
+	response, err := provider.SendMessageWithTools(context.Background(), models.Message{Role: models.RoleUser, Content: "Weather?"})
+	if err != nil {
+		t.Fatalf("Expected an unset MaxToolRounds to allow tool calls, got %v", err)
+	}
+	if response != "It is 5°C." {
+		t.Errorf("Expected final answer, got '%s'", response)
+	}
+}
+
+// TestSendMessageRetriesOverloaded tests that 529 overloaded responses are retried
+func TestSendMessageRetriesOverloaded(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			w.Header().Set("retry-after-ms", "5")
+			w.WriteHeader(529)
+			w.Write([]byte(`{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`))
+			return
+		}
+		w.Write([]byte(`{
+			"id": "msg_1",
+			"content": [{"type": "text", "text": "Hello!"}],
+			"stop_reason": "end_turn"
+		}`))
+	}))
+	defer server.Close()
+
+	provider := New("test-api-key")
+	provider.BaseURL = server.URL
+
+	response, err := provider.SendMessage(context.Background(), models.Message{
+		Role:    models.RoleUser,
+		Content: "Hi",
+	})
+	if err != nil {
+		t.Fatalf("Expected request to succeed after retry, got %v", err)
+	}
+
+	if response != "Hello!" || requests != 2 {
+		t.Errorf("Expected 'Hello!' after 2 requests, got '%s' after %d", response, requests)
+	}
+}
+
+// TestSendMessageWithToolsUsage tests that usage is reported for every round of the tool loop
+func TestSendMessageWithToolsUsage(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			w.Write([]byte(`{
+				"id": "msg_0",
+				"model": "claude-3-5-sonnet-20241022",
+				"content": [{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {}}],
+				"stop_reason": "tool_use",
+				"usage": {"input_tokens": 100, "output_tokens": 20, "cache_read_input_tokens": 1000}
+			}`))
+			return
+		}
+		w.Write([]byte(`{
+			"id": "msg_1",
+			"model": "claude-3-5-sonnet-20241022",
+			"content": [{"type": "text", "text": "It is 5°C."}],
+			"stop_reason": "end_turn",
+			"usage": {"input_tokens": 130, "output_tokens": 8, "cache_creation_input_tokens": 50}
+		}`))
+	}))
+	defer server.Close()
+
+	provider := New("test-api-key")
+	provider.BaseURL = server.URL
+	provider.RegisterTool(tools.NewTool(
+		"get_weather",
+		"Get the weather",
+		models.InputSchema{Type: "object"},
+		func(params map[string]any) (string, error) {
+			return "5°C", nil
+		},
+	))
+
+	tracker := models.NewUsageTracker()
+	ctx := models.WithUsageTracker(context.Background(), tracker)
+
+	if _, err := provider.SendMessageWithTools(ctx, models.Message{Role: models.RoleUser, Content: "Weather?"}); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	expected := models.Usage{InputTokens: 230, OutputTokens: 28, CacheReadTokens: 1000, CacheCreationTokens: 50}
+	if tracker.Total() != expected {
+		t.Errorf("Expected usage %+v, got %+v", expected, tracker.Total())
+	}
+
+	if tracker.Calls() != 2 {
+		t.Errorf("Expected 2 reported calls, got %d", tracker.Calls())
+	}
+
+	if _, ok := tracker.ByModel()["claude-3-5-sonnet-20241022"]; !ok {
+		t.Errorf("Expected usage to be attributed to the model reported by the API, got %v", tracker.ByModel())
+	}
+}
+
+// TestSendMessageWithToolsBudget tests that the tool loop stops once the budget is spent
+func TestSendMessageWithToolsBudget(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "msg_0",
+			"model": "claude-3-5-sonnet-20241022",
+			"content": [{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {}}],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 400, "output_tokens": 20}
+		}`))
+	}))
+	defer server.Close()
+
+	provider := New("test-api-key")
+	provider.BaseURL = server.URL
+	provider.RegisterTool(tools.NewTool(
+		"get_weather",
+		"Get the weather",
+		models.InputSchema{Type: "object"},
+		func(params map[string]any) (string, error) {
+			return "5°C", nil
+		},
+	))
+
+	budget := &models.Budget{MaxTokens: 1000}
+	ctx := models.WithBudget(context.Background(), budget)
+
+	_, err := provider.SendMessageWithTools(ctx, models.Message{Role: models.RoleUser, Content: "Weather?"})
+
+	var exceeded *models.BudgetExceededError
+	if !errors.As(err, &exceeded) {
+		t.Fatalf("Expected BudgetExceededError, got %v", err)
+	}
+
+	if requests != 3 {
+		t.Errorf("Expected 3 requests before the budget was spent, got %d", requests)
+	}
+
+	// The user message, then a tool_use turn and its results for each request
+	if len(exceeded.Transcript) != 7 {
+		t.Errorf("Expected 7 messages in the transcript, got %d", len(exceeded.Transcript))
+	}
+
+	if budget.ToolCalls() != 3 {
+		t.Errorf("Expected 3 tool calls, got %d", budget.ToolCalls())
+	}
+}
+
+// TestConvertMediaBlocks tests that images and documents become base64 sources
+func TestConvertMediaBlocks(t *testing.T) {
+	msg := models.Message{
+		Role: models.RoleUser,
+		Blocks: []models.ContentBlock{
+			models.TextBlock("What is in these?"),
+			models.ImageBlock("image/png", "iVBORw0KGgo="),
+			models.DocumentBlock("application/pdf", "JVBERi0xLjc=", "report.pdf"),
+			models.DocumentBlock("text/plain", "SGVsbG8=", "notes.txt"),
+		},
+	}
+
+	data, err := json.Marshal(convertContent(msg))
+	if err != nil {
+		t.Fatalf("Failed to marshal content: %v", err)
+	}
+
+	expected := `[{"type":"text","text":"What is in these?"},` +
+		`{"type":"image","source":{"type":"base64","media_type":"image/png","data":"iVBORw0KGgo="}},` +
+		`{"type":"document","source":{"type":"base64","media_type":"application/pdf","data":"JVBERi0xLjc="},"title":"report.pdf"},` +
+		`{"type":"document","source":{"type":"text","media_type":"text/plain","data":"Hello"},"title":"notes.txt"}]`
+	if string(data) != expected {
+		t.Errorf("Unexpected content:\n%s", data)
+	}
+}
+
+// TestSendMessageWithToolsOptions tests that generation options are mapped onto
+// the payload and that a forced tool choice only applies to the first request
+func TestSendMessageWithToolsOptions(t *testing.T) {
+	var payloads []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		payloads = append(payloads, payload)
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(payloads) == 1 {
+			w.Write([]byte(`{
+				"id": "msg_0",
+				"content": [{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {}}],
+				"stop_reason": "tool_use"
+			}`))
+			return
+		}
+		w.Write([]byte(`{"id": "msg_1", "content": [{"type": "text", "text": "5°C"}], "stop_reason": "end_turn"}`))
+	}))
+	defer server.Close()
+
+	provider := New("test-api-key")
+	provider.BaseURL = server.URL
+	provider.RegisterTool(tools.NewTool(
+		"get_weather",
+		"Get the weather",
+		models.InputSchema{Type: "object"},
+		func(params map[string]any) (string, error) {
+			return "5°C", nil
+		},
+	))
+
+	ctx := models.WithOptions(context.Background(), models.GenerationOptions{
+		Model:         "claude-haiku",
+		TopP:          models.Float(0.9),
+		TopK:          models.Int(20),
+		StopSequences: []string{"END"},
+		UserID:        "user-1",
+		ToolChoice:    models.UseTool("get_weather"),
+	})
+
+	if _, err := provider.SendMessageWithTools(ctx, models.Message{Role: models.RoleUser, Content: "Weather?"}); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	if len(payloads) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(payloads))
+	}
+
+	first := payloads[0]
+	if first["model"] != "claude-haiku" || first["top_p"] != 0.9 || first["top_k"] != float64(20) {
+		t.Errorf("Expected model and sampling options in payload, got %+v", first)
+	}
+	if _, ok := first["temperature"]; ok {
+		t.Errorf("Expected default temperature to be dropped with top_p")
+	}
+	if stop, _ := first["stop_sequences"].([]any); len(stop) != 1 || stop[0] != "END" {
+		t.Errorf("Expected stop sequences in payload, got %v", first["stop_sequences"])
+	}
+	if metadata, _ := first["metadata"].(map[string]any); metadata["user_id"] != "user-1" {
+		t.Errorf("Expected user ID in metadata, got %v", first["metadata"])
+	}
+	if choice, _ := first["tool_choice"].(map[string]any); choice["type"] != "tool" || choice["name"] != "get_weather" {
+		t.Errorf("Expected forced tool choice, got %v", first["tool_choice"])
+	}
+
+	if choice, _ := payloads[1]["tool_choice"].(map[string]any); choice["type"] != "auto" {
+		t.Errorf("Expected tool choice to be relaxed in follow-up, got %v", payloads[1]["tool_choice"])
+	}
+}
+
+// TestSendMessageUnsupportedOptions tests that options Claude does not support
+// are rejected before any request is made
+func TestSendMessageUnsupportedOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request")
+	}))
+	defer server.Close()
+
+	provider := New("test-api-key")
+	provider.BaseURL = server.URL
+
+	for _, opts := range []models.GenerationOptions{
+		{Seed: models.Int(1)},
+		{Metadata: map[string]string{"team": "search"}},
+		{Temperature: models.Float(1.5)},
+		{Temperature: models.Float(0.2), TopP: models.Float(0.9)},
+		{ToolChoice: models.UseTool("get_weather")},
+	} {
+		ctx := models.WithOptions(context.Background(), opts)
+		if _, err := provider.SendMessage(ctx, models.Message{Role: models.RoleUser, Content: "Hi"}); err == nil {
+			t.Errorf("Expected %+v to be rejected", opts)
+		}
+	}
+}
+
+// TestBuildPayloadCache tests that cache breakpoints are placed on the system
+// prompt, the last tool, the last message and marked messages, within Claude's limit
+func TestBuildPayloadCache(t *testing.T) {
+	provider := New("test-api-key")
+	provider.SetSystemPrompt("You are a weather assistant.")
+	provider.SetCache(CacheConfig{System: true, Tools: true, Conversation: true, TTL: CacheTTL1h})
+	for _, name := range []string{"get_weather", "get_time"} {
+		provider.RegisterTool(tools.NewTool(name, "A tool", models.InputSchema{Type: "object"},
+			func(params map[string]any) (string, error) { return "", nil }))
+	}
+
+	conversation := models.NewConversation("")
+	for i, text := range []string{"One", "Two", "Three", "Four"} {
+		role := models.RoleUser
+		if i%2 == 1 {
+			role = models.RoleAssistant
+		}
+		conversation.Append(models.Message{Role: role, Content: text, CacheBreakpoint: i < 2})
+	}
+
+	data, err := json.Marshal(provider.buildPayload(conversation, true))
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+
+	var payload struct {
+		System   []contentBlock    `json:"system"`
+		Tools    []json.RawMessage `json:"tools"`
+		Messages []message         `json:"messages"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+
+	if len(payload.System) != 1 || payload.System[0].CacheControl == nil || payload.System[0].CacheControl.TTL != CacheTTL1h {
+		t.Errorf("Expected a cached system prompt, got %+v", payload.System)
+	}
+
+	if strings.Contains(string(payload.Tools[0]), "cache_control") || !strings.Contains(string(payload.Tools[1]), `"cache_control":{"type":"ephemeral","ttl":"1h"}`) {
+		t.Errorf("Expected only the last tool to be cached, got %s", data)
+	}
+
+	// Two breakpoints are left for messages: the last one and the latest marked one
+	var cached []string
+	for _, msg := range payload.Messages {
+		if msg.Content[0].CacheControl != nil {
+			cached = append(cached, msg.Content[0].Text)
+		}
+	}
+	if strings.Join(cached, ",") != "Two,Four" {
+		t.Errorf("Expected messages Two and Four to be cached, got %v", cached)
+	}
+
+	// Without a cache configuration the system prompt stays a string
+	provider.SetCache(CacheConfig{})
+	if _, ok := provider.buildPayload(conversation, true)["system"].(string); !ok {
+		t.Errorf("Expected an uncached system prompt to be sent as a string")
+	}
+}
+
+// Recorded SSE frames for a response preceded by extended thinking
+const thinkingStream = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"stop_reason":null}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Brussels is cold"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":" in winter."}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig_1"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"It is 5°C."}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":40}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+
+// TestSendMessageWithToolsThinking tests that thinking blocks are kept apart from
+// the answer and sent back with their signatures after a tool call
+func TestSendMessageWithToolsThinking(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		var payload struct {
+			Thinking    map[string]any `json:"thinking"`
+			Temperature *float64       `json:"temperature"`
+			Messages    []message      `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+
+		if payload.Thinking["type"] != "enabled" || payload.Thinking["budget_tokens"] != float64(2048) {
+			t.Errorf("Expected thinking to be enabled with a budget, got %v", payload.Thinking)
+		}
+		if payload.Temperature != nil {
+			t.Errorf("Expected no temperature with thinking, got %v", *payload.Temperature)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			w.Write([]byte(`{
+				"id": "msg_0",
+				"content": [
+					{"type": "thinking", "thinking": "I need the weather.", "signature": "sig_0"},
+					{"type": "redacted_thinking", "data": "encrypted"},
+					{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {}}
+				],
+				"stop_reason": "tool_use"
+			}`))
+			return
+		}
+
+		assistant := payload.Messages[1].Content
+		if len(assistant) != 3 || assistant[0].Type != models.BlockThinking || assistant[0].Thinking != "I need the weather." ||
+			assistant[0].Signature != "sig_0" || assistant[1].Type != models.BlockRedactedThinking || assistant[1].Data != "encrypted" {
+			t.Errorf("Expected thinking blocks to be sent back unaltered, got %+v", assistant)
+		}
+
+		w.Write([]byte(`{
+			"id": "msg_1",
+			"content": [
+				{"type": "thinking", "thinking": "It is 5°C.", "signature": "sig_1"},
+				{"type": "text", "text": "It is 5°C in Brussels."}
+			],
+			"stop_reason": "end_turn"
+		}`))
+	}))
+	defer server.Close()
+
+	provider := New("test-api-key")
+	provider.BaseURL = server.URL
+	provider.SetMaxTokens(4096)
+	provider.SetThinkingBudget(2048)
+	provider.RegisterTool(tools.NewTool(
+		"get_weather",
+		"Get the weather",
+		models.InputSchema{Type: "object"},
+		func(params map[string]any) (string, error) {
+			return "5°C", nil
+		},
+	))
+
+	conversation := models.NewConversation("").Append(models.Message{Role: models.RoleUser, Content: "Weather?"})
+	response, err := provider.SendConversation(context.Background(), conversation)
+	if err != nil {
+		t.Fatalf("Failed to send conversation: %v", err)
+	}
+
+	if response != "It is 5°C in Brussels." {
+		t.Errorf("Expected the answer without thinking, got '%s'", response)
+	}
+
+	last, _ := conversation.Last()
+	if last.Thinking() != "It is 5°C." {
+		t.Errorf("Expected thinking on the assistant turn, got '%s'", last.Thinking())
+	}
+}
+
+// TestSendMessageStreamThinking tests that thinking deltas are streamed apart from text
+func TestSendMessageStreamThinking(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(thinkingStream))
+	}))
+	defer server.Close()
+
+	provider := New("test-api-key")
+	provider.BaseURL = server.URL
+	provider.SetMaxTokens(4096)
+	provider.SetThinkingBudget(2048)
+
+	events, err := provider.SendMessageStream(context.Background(), models.Message{Role: models.RoleUser, Content: "Weather?"})
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+
+	var text, thinking strings.Builder
+	for _, event := range collect(events) {
+		switch event.Type {
+		case models.StreamText:
+			text.WriteString(event.Text)
+		case models.StreamThinking:
+			thinking.WriteString(event.Text)
+		}
+	}
+
+	if text.String() != "It is 5°C." || thinking.String() != "Brussels is cold in winter." {
+		t.Errorf("Unexpected text '%s' and thinking '%s'", text.String(), thinking.String())
+	}
+
+	streamed := &streamedBlock{Type: models.BlockThinking, Signature: "sig_1"}
+	streamed.Text.WriteString("Brussels is cold")
+	if block := streamed.block(); block.Type != models.BlockThinking || block.Signature != "sig_1" || block.Text != "Brussels is cold" {
+		t.Errorf("Unexpected thinking block %+v", block)
+	}
+}
+
+// TestThinkingValidation tests that invalid thinking configurations are rejected
+func TestThinkingValidation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request")
+	}))
+	defer server.Close()
+
+	provider := New("test-api-key")
+	provider.BaseURL = server.URL
+	provider.SetMaxTokens(2048)
+
+	cases := []struct {
+		budget int
+		opts   models.GenerationOptions
+	}{
+		{budget: 512},
+		{budget: 2048},
+		{budget: 1024, opts: models.GenerationOptions{Temperature: models.Float(0.5)}},
+		{budget: 1024, opts: models.GenerationOptions{TopP: models.Float(0.5)}},
+	}
+	for _, c := range cases {
+		provider.SetThinkingBudget(c.budget)
+		ctx := models.WithOptions(context.Background(), c.opts)
+		if _, err := provider.SendMessage(ctx, models.Message{Role: models.RoleUser, Content: "Hi"}); err == nil {
+			t.Errorf("Expected budget %d with %+v to be rejected", c.budget, c.opts)
+		}
+	}
+}
+
+// TestCountTokens tests that tokens are counted with the count_tokens endpoint
+func TestCountTokens(t *testing.T) {
+	var payload map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/count_tokens" {
+			t.Errorf("Expected request to /count_tokens, got %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"input_tokens": 42}`))
+	}))
+	defer server.Close()
+
+	provider := New("test-api-key")
+	provider.BaseURL = server.URL
+	provider.SetSystemPrompt("Not counted")
+
+	weather := tools.NewTool("get_weather", "Get the weather", models.InputSchema{Type: "object"}, func(params map[string]any) (string, error) {
+		return "", nil
+	})
+	tokens, err := provider.CountTokens(context.Background(), []models.Message{
+		{Role: models.RoleSystem, Content: "Be brief"},
+		{Role: models.RoleUser, Content: "Weather in Brussels?"},
+	}, []models.ToolExecutor{weather})
+	if err != nil {
+		t.Fatalf("CountTokens failed: %v", err)
+	}
+
+	if tokens != 42 {
+		t.Errorf("Expected 42 tokens, got %d", tokens)
+	}
+	if payload["system"] != "Be brief" || payload["model"] != provider.Model {
+		t.Errorf("Expected the system message as system prompt, got %v", payload["system"])
+	}
+	if messages, _ := payload["messages"].([]any); len(messages) != 1 {
+		t.Errorf("Expected the user message only, got %v", payload["messages"])
+	}
+	if tools, _ := payload["tools"].([]any); len(tools) != 1 {
+		t.Errorf("Expected the tool definition, got %v", payload["tools"])
+	}
+	if _, ok := payload["max_tokens"]; ok {
+		t.Errorf("Expected no max_tokens in a count request")
+	}
+}
@@ -0,0 +1,87 @@
+package claude
+
+import (
+	"encoding/json"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// message is a single turn of the conversation in the format expected by Claude's Messages API.
+type message struct {
+	Role    string         `json:"role"`
+	Content []contentBlock `json:"content"`
+}
+
+// contentBlock is a piece of message content in Claude's format.
+// Only the fields relevant to the block type are sent.
+type contentBlock struct {
+	Type         string          `json:"type"`
+	Text         string          `json:"text,omitempty"`
+	Thinking     string          `json:"thinking,omitempty"`
+	Signature    string          `json:"signature,omitempty"`
+	Data         string          `json:"data,omitempty"`
+	ID           string          `json:"id,omitempty"`
+	Name         string          `json:"name,omitempty"`
+	Input        json.RawMessage `json:"input,omitempty"`
+	ToolUseID    string          `json:"tool_use_id,omitempty"`
+	Content      string          `json:"content,omitempty"`
+	IsError      bool            `json:"is_error,omitempty"`
+	Source       *mediaSource    `json:"source,omitempty"`
+	Title        string          `json:"title,omitempty"`
+	CacheControl *cacheControl   `json:"cache_control,omitempty"`
+}
+
+// mediaSource holds the content of an image or document block.
+type mediaSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// messageResponse is the response returned by Claude's Messages API.
+type messageResponse struct {
+	ID         string         `json:"id"`
+	Model      string         `json:"model"`
+	Content    []contentBlock `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      usage          `json:"usage"`
+}
+
+// usage reports the tokens billed for a request to Claude's Messages API.
+type usage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// convert converts the reported usage to Bond's format. Claude already excludes
+// cached tokens from input_tokens.
+func (u usage) convert() models.Usage {
+	return models.Usage{
+		InputTokens:         u.InputTokens,
+		OutputTokens:        u.OutputTokens,
+		CacheReadTokens:     u.CacheReadInputTokens,
+		CacheCreationTokens: u.CacheCreationInputTokens,
+	}
+}
+
+// blocks converts the response content to Bond content blocks.
+func (r *messageResponse) blocks() []models.ContentBlock {
+	blocks := make([]models.ContentBlock, 0, len(r.Content))
+
+	for _, content := range r.Content {
+		switch content.Type {
+		case models.BlockText:
+			blocks = append(blocks, models.TextBlock(content.Text))
+		case models.BlockToolUse:
+			blocks = append(blocks, models.ToolUseBlock(content.ID, content.Name, content.Input))
+		case models.BlockThinking:
+			blocks = append(blocks, models.ThinkingBlock(content.Thinking, content.Signature))
+		case models.BlockRedactedThinking:
+			blocks = append(blocks, models.RedactedThinkingBlock(content.Data))
+		}
+	}
+
+	return blocks
+}
@@ -0,0 +1,118 @@
+package claude
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// Constraints of extended thinking
+const (
+	minThinkingBudget = 1024
+	minThinkingTopP   = 0.95
+)
+
+// maxTemperature is the highest temperature Claude accepts, below the 2 allowed by other providers
+const maxTemperature = 1.0
+
+// applyOptions applies the per-request generation options to the payload,
+// rejecting those Claude does not support.
+func (p *Provider) applyOptions(payload map[string]any, opts models.GenerationOptions) error {
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("invalid generation options: %w", err)
+	}
+
+	switch {
+	case opts.Seed != nil:
+		return errors.New("Claude does not support a seed")
+	case len(opts.Metadata) > 0:
+		return errors.New("Claude does not support request metadata other than the user ID")
+	case opts.Temperature != nil && *opts.Temperature > maxTemperature:
+		return fmt.Errorf("Claude accepts a temperature between 0 and %v, got %v", maxTemperature, *opts.Temperature)
+	case opts.Temperature != nil && opts.TopP != nil:
+		return errors.New("Claude accepts either temperature or top_p, not both")
+	}
+
+	if opts.Model != "" {
+		payload["model"] = opts.Model
+	}
+	if opts.MaxTokens > 0 {
+		payload["max_tokens"] = opts.MaxTokens
+	}
+	if opts.Temperature != nil {
+		payload["temperature"] = *opts.Temperature
+	}
+	if opts.TopP != nil {
+		// The provider's temperature is dropped as it cannot be combined with top_p
+		delete(payload, "temperature")
+		payload["top_p"] = *opts.TopP
+	}
+	if opts.TopK != nil {
+		payload["top_k"] = *opts.TopK
+	}
+	if len(opts.StopSequences) > 0 {
+		payload["stop_sequences"] = opts.StopSequences
+	}
+	if opts.UserID != "" {
+		payload["metadata"] = map[string]string{"user_id": opts.UserID}
+	}
+
+	if err := applyToolChoice(payload, opts); err != nil {
+		return err
+	}
+
+	if _, thinking := payload["thinking"]; thinking {
+		return p.validateThinking(payload, opts)
+	}
+
+	return nil
+}
+
+// validateThinking checks the payload against the constraints of extended thinking
+func (p *Provider) validateThinking(payload map[string]any, opts models.GenerationOptions) error {
+	maxTokens, _ := payload["max_tokens"].(int)
+
+	switch {
+	case p.ThinkingBudget < minThinkingBudget:
+		return fmt.Errorf("the thinking budget must be at least %d tokens, got %d", minThinkingBudget, p.ThinkingBudget)
+	case p.ThinkingBudget >= maxTokens:
+		return fmt.Errorf("the thinking budget of %d tokens must be less than max tokens (%d)", p.ThinkingBudget, maxTokens)
+	case opts.Temperature != nil || opts.TopK != nil:
+		return errors.New("temperature and top_k cannot be set with extended thinking")
+	case opts.TopP != nil && *opts.TopP < minThinkingTopP:
+		return fmt.Errorf("top_p must be at least %v with extended thinking", minThinkingTopP)
+	case opts.ToolChoice.Mode == models.ToolChoiceAny || opts.ToolChoice.Mode == models.ToolChoiceTool:
+		return errors.New("extended thinking only supports the auto and none tool choices")
+	}
+
+	return nil
+}
+
+// applyToolChoice sets tool_choice for the tools offered in the payload
+func applyToolChoice(payload map[string]any, opts models.GenerationOptions) error {
+	var offered []string
+	tools, _ := payload["tools"].([]map[string]any)
+	for _, tool := range tools {
+		if name, ok := tool["name"].(string); ok {
+			offered = append(offered, name)
+		}
+	}
+
+	if err := opts.ValidateToolChoice(offered); err != nil {
+		return err
+	}
+
+	// Without tools there is nothing to choose from
+	if opts.ToolChoice.Mode == "" || len(offered) == 0 {
+		return nil
+	}
+
+	choice := map[string]any{"type": opts.ToolChoice.Mode}
+	if opts.ToolChoice.Mode == models.ToolChoiceTool {
+		choice["name"] = opts.ToolChoice.Name
+	}
+	payload["tool_choice"] = choice
+
+	return nil
+}
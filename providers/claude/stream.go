@@ -0,0 +1,277 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/devOpifex/bond/models"
+	"github.com/devOpifex/bond/providers/common"
+)
+
+// streamEvent mirrors the payload of the server-sent events emitted by Claude's
+// Messages API when streaming is enabled. Only the fields Bond uses are decoded.
+type streamEvent struct {
+	Type  string `json:"type"`
+	Index int    `json:"index"`
+
+	ContentBlock *struct {
+		Type string `json:"type"`
+		ID   string `json:"id,omitempty"`
+		Name string `json:"name,omitempty"`
+		Text string `json:"text,omitempty"`
+		Data string `json:"data,omitempty"`
+	} `json:"content_block,omitempty"`
+
+	Delta *struct {
+		Type        string `json:"type,omitempty"`
+		Text        string `json:"text,omitempty"`
+		Thinking    string `json:"thinking,omitempty"`
+		Signature   string `json:"signature,omitempty"`
+		PartialJSON string `json:"partial_json,omitempty"`
+		StopReason  string `json:"stop_reason,omitempty"`
+	} `json:"delta,omitempty"`
+
+	Message *struct {
+		Model string `json:"model"`
+		Usage usage  `json:"usage"`
+	} `json:"message,omitempty"`
+
+	Usage *usage `json:"usage,omitempty"`
+
+	Error *common.ErrorDetail `json:"error,omitempty"`
+}
+
+// streamedBlock collects a content block as it arrives in fragments.
+type streamedBlock struct {
+	Type      string
+	ID        string
+	Name      string
+	Data      string
+	Signature string
+	Text      strings.Builder
+	Input     strings.Builder
+}
+
+// block converts the collected fragments to a Bond content block.
+func (b *streamedBlock) block() models.ContentBlock {
+	switch b.Type {
+	case models.BlockThinking:
+		return models.ThinkingBlock(b.Text.String(), b.Signature)
+	case models.BlockRedactedThinking:
+		return models.RedactedThinkingBlock(b.Data)
+	case models.BlockToolUse:
+		// Tools without parameters stream no input at all
+		input := b.Input.String()
+		if input == "" {
+			input = "{}"
+		}
+		return models.ToolUseBlock(b.ID, b.Name, json.RawMessage(input))
+	}
+
+	return models.TextBlock(b.Text.String())
+}
+
+// SendMessageStream sends a message to Claude with available tools and streams the response.
+// Text, thinking and tool input fragments are delivered on the returned channel as they arrive.
+// When Claude requests a tool, the tool is executed and the conversation continues
+// on the same channel. The channel is closed after the final stop or error event.
+func (p *Provider) SendMessageStream(ctx context.Context, message models.Message) (<-chan models.StreamEvent, error) {
+	conversation := models.NewConversation("").Append(message)
+
+	resp, err := p.openStream(ctx, conversation, models.OptionsFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan models.StreamEvent)
+	go func() {
+		defer close(events)
+		p.streamResponses(ctx, conversation, resp, events)
+	}()
+
+	return events, nil
+}
+
+// openStream sends a streaming request to Claude's API for the given conversation
+// and generation options, unless the budget in the context is spent. The
+// conversation is fitted first, see models.FitConversation.
+func (p *Provider) openStream(ctx context.Context, conversation *models.Conversation, opts models.GenerationOptions) (*http.Response, error) {
+	if err := models.CheckBudget(ctx, conversation.Messages); err != nil {
+		return nil, err
+	}
+
+	if err := models.FitConversation(ctx, conversation); err != nil {
+		return nil, err
+	}
+
+	payload := p.buildPayload(conversation, true)
+	if err := p.applyOptions(payload, opts); err != nil {
+		return nil, err
+	}
+	payload["stream"] = true
+
+	client := p.StreamHTTPClient
+	if client == nil {
+		client = common.StreamingClient(p.HTTPClient)
+	}
+	return p.doRequest(ctx, client, p.BaseURL, payload)
+}
+
+// streamResponses forwards events from resp to the events channel, running the
+// tool loop whenever Claude stops to use a tool.
+func (p *Provider) streamResponses(ctx context.Context, conversation *models.Conversation, resp *http.Response, events chan<- models.StreamEvent) {
+	for round := 0; ; round++ {
+		blocks, stopReason, err := p.readStream(ctx, resp.Body, events)
+		resp.Body.Close()
+		if err != nil {
+			common.SendStreamEvent(ctx, events, models.StreamEvent{Type: models.StreamError, Err: err})
+			return
+		}
+
+		if stopReason != "tool_use" {
+			common.SendStreamEvent(ctx, events, models.StreamEvent{Type: models.StreamStop, StopReason: stopReason})
+			return
+		}
+
+		if common.ToolRoundsExceeded(round, p.MaxToolRounds) {
+			common.SendStreamEvent(ctx, events, models.StreamEvent{Type: models.StreamError, Err: common.ErrMaxToolRounds})
+			return
+		}
+
+		// Keep the assistant's tool_use turn and answer every call it made
+		assistantMessage := models.Message{Role: models.RoleAssistant, Blocks: blocks}
+		conversation.Append(assistantMessage, p.runTools(ctx, blocks))
+
+		// Send the tool results back to Claude in a new streaming request
+		resp, err = p.openStream(ctx, conversation, models.OptionsFrom(ctx).FollowUp())
+		if err != nil {
+			common.SendStreamEvent(ctx, events, models.StreamEvent{Type: models.StreamError, Err: err})
+			return
+		}
+	}
+}
+
+// readStream parses a single streamed Claude response, forwarding text and tool
+// input fragments as they arrive. It returns the completed content blocks and
+// the stop reason reported by the API, and reports the usage of the response.
+// A stream ending before message_stop fails with an error wrapping
+// io.ErrUnexpectedEOF.
+func (p *Provider) readStream(ctx context.Context, body io.Reader, events chan<- models.StreamEvent) ([]models.ContentBlock, string, error) {
+	reader := common.NewSSEReader(body)
+	pending := make(map[int]*streamedBlock)
+
+	var blocks []models.ContentBlock
+	var stopReason string
+
+	// Input usage arrives with message_start, output usage with message_delta
+	var model string
+	var streamUsage usage
+
+	for {
+		sse, err := reader.Next()
+		if err == io.EOF {
+			p.reportUsage(ctx, model, streamUsage)
+			return nil, "", common.NewTruncatedStreamError("Claude")
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read Claude stream: %w", err)
+		}
+
+		var event streamEvent
+		if err := json.Unmarshal([]byte(sse.Data), &event); err != nil {
+			return nil, "", fmt.Errorf("failed to parse Claude stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				model = event.Message.Model
+				streamUsage = event.Message.Usage
+			}
+
+		case "content_block_start":
+			if event.ContentBlock == nil {
+				continue
+			}
+			pending[event.Index] = &streamedBlock{
+				Type: event.ContentBlock.Type,
+				ID:   event.ContentBlock.ID,
+				Name: event.ContentBlock.Name,
+				Data: event.ContentBlock.Data,
+			}
+			if event.ContentBlock.Type != models.BlockToolUse {
+				continue
+			}
+			if !common.SendStreamEvent(ctx, events, models.StreamEvent{
+				Type:     models.StreamToolUse,
+				ToolID:   event.ContentBlock.ID,
+				ToolName: event.ContentBlock.Name,
+			}) {
+				return nil, "", ctx.Err()
+			}
+
+		case "content_block_delta":
+			if event.Delta == nil {
+				continue
+			}
+			block, ok := pending[event.Index]
+			if !ok {
+				continue
+			}
+			var out models.StreamEvent
+			switch event.Delta.Type {
+			case "text_delta":
+				block.Text.WriteString(event.Delta.Text)
+				out = models.StreamEvent{Type: models.StreamText, Text: event.Delta.Text}
+			case "thinking_delta":
+				block.Text.WriteString(event.Delta.Thinking)
+				out = models.StreamEvent{Type: models.StreamThinking, Text: event.Delta.Thinking}
+			case "signature_delta":
+				// The signature arrives whole, just before the thinking block stops
+				block.Signature = event.Delta.Signature
+				continue
+			case "input_json_delta":
+				block.Input.WriteString(event.Delta.PartialJSON)
+				out = models.StreamEvent{
+					Type:         models.StreamToolInput,
+					ToolID:       block.ID,
+					PartialInput: event.Delta.PartialJSON,
+				}
+			default:
+				continue
+			}
+			if !common.SendStreamEvent(ctx, events, out) {
+				return nil, "", ctx.Err()
+			}
+
+		case "content_block_stop":
+			if block, ok := pending[event.Index]; ok {
+				blocks = append(blocks, block.block())
+				delete(pending, event.Index)
+			}
+
+		case "message_delta":
+			if event.Delta != nil && event.Delta.StopReason != "" {
+				stopReason = event.Delta.StopReason
+			}
+			if event.Usage != nil {
+				// Counts in message_delta are cumulative
+				streamUsage.OutputTokens = event.Usage.OutputTokens
+			}
+
+		case "message_stop":
+			p.reportUsage(ctx, model, streamUsage)
+			return blocks, stopReason, nil
+
+		case "error":
+			if event.Error != nil {
+				return nil, "", common.NewStreamError("Claude", event.Error.Type, event.Error.Message)
+			}
+			return nil, "", fmt.Errorf("Claude API stream error: %s", sse.Data)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package claude
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// Provider must satisfy the models.StructuredResponder interface
+var _ models.StructuredResponder = (*Provider)(nil)
+
+// SendStructured makes Claude answer with JSON conforming to the format's schema
+// by forcing a call to a tool whose input schema is that schema. The tool is
+// never executed: its input is the response. Registered tools are not offered
+// and extended thinking is disabled.
+// The tool_use turn is appended to the conversation, so corrections must be sent
+// back as a tool_result for that call.
+// This implements the models.StructuredResponder interface.
+func (p *Provider) SendStructured(ctx context.Context, conversation *models.Conversation, format models.ResponseFormat) (string, error) {
+	if err := models.CheckBudget(ctx, conversation.Messages); err != nil {
+		return "", err
+	}
+
+	if err := models.FitConversation(ctx, conversation); err != nil {
+		return "", err
+	}
+
+	// The tool choice is forced below, other options apply as usual
+	opts := models.OptionsFrom(ctx)
+	opts.ToolChoice = models.ToolChoice{}
+
+	// Forcing a tool call is incompatible with extended thinking
+	payload := p.buildPayload(conversation, false)
+	delete(payload, "thinking")
+	if err := p.applyOptions(payload, opts); err != nil {
+		return "", err
+	}
+	payload["tools"] = []map[string]any{{
+		"name":         format.Name,
+		"description":  format.Description,
+		"input_schema": format.Schema,
+	}}
+	payload["tool_choice"] = map[string]any{"type": "tool", "name": format.Name}
+
+	claudeResp, err := p.createMessage(ctx, payload)
+	if err != nil {
+		return "", err
+	}
+	p.reportUsage(ctx, claudeResp.Model, claudeResp.Usage)
+
+	assistantMessage := models.Message{
+		Role:   models.RoleAssistant,
+		Blocks: claudeResp.blocks(),
+	}
+	conversation.Append(assistantMessage)
+
+	for _, block := range assistantMessage.Blocks {
+		if block.Type == models.BlockToolUse && block.Name == format.Name {
+			return string(block.Input), nil
+		}
+	}
+
+	return "", fmt.Errorf("Claude did not call the %s tool (stop reason %s)", format.Name, claudeResp.StopReason)
+}
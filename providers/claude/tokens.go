@@ -0,0 +1,63 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// countTokensPath is appended to BaseURL to reach the token counting endpoint
+const countTokensPath = "/count_tokens"
+
+// countTokensResponse is the body returned by the token counting endpoint
+type countTokensResponse struct {
+	InputTokens int `json:"input_tokens"`
+}
+
+// CountTokens counts the input tokens of the messages and tool definitions
+// with Claude's token counting endpoint, which is free of charge but rate
+// limited. The count is exact for the provider's model.
+// This implements part of the models.Provider interface.
+func (p *Provider) CountTokens(ctx context.Context, messages []models.Message, tools []models.ToolExecutor) (int, error) {
+	payload := map[string]any{
+		"model":    p.Model,
+		"messages": convertMessagesToClaudeFormat(messages),
+	}
+
+	// System messages are skipped by the conversion, they make the system prompt
+	var system []string
+	for _, msg := range messages {
+		if msg.Role == models.RoleSystem {
+			system = append(system, msg.Text())
+		}
+	}
+	if len(system) > 0 {
+		payload["system"] = strings.Join(system, "\n\n")
+	}
+
+	if len(tools) > 0 {
+		payload["tools"] = convertTools(tools)
+	}
+
+	resp, err := p.doRequest(ctx, p.HTTPClient, p.BaseURL+countTokensPath, payload)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read Claude API response: %w", err)
+	}
+
+	var count countTokensResponse
+	if err := json.Unmarshal(body, &count); err != nil {
+		return 0, fmt.Errorf("failed to parse Claude API response: %w", err)
+	}
+
+	return count.InputTokens, nil
+}
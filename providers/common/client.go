@@ -8,9 +8,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/devOpifex/bond/models"
@@ -36,6 +38,9 @@ type HTTPRequest struct {
 // It implements parts of the models.Provider interface and provides utility
 // methods that specific provider implementations can use.
 type BaseClient struct {
+	// Provider names the API in the errors returned by the client, e.g. "Ollama"
+	Provider string
+
 	// ApiKey is the authentication key for the provider API
 	ApiKey string
 	
@@ -44,6 +49,10 @@ type BaseClient struct {
 	
 	// HttpClient is used for making HTTP requests to the provider API
 	HttpClient *http.Client
+
+	// StreamClient is used for streamed responses. When nil, HttpClient is
+	// used without its total timeout, see StreamingClient.
+	StreamClient *http.Client
 	
 	// Tools is a registry of tools that can be called by the model
 	Tools map[string]models.ToolExecutor
@@ -61,6 +70,20 @@ type BaseClient struct {
 	
 	// SystemPrompt contains instructions included in all requests
 	SystemPrompt string
+
+	// MaxToolRounds limits how many rounds of tool calls a single request may
+	// run. Calling tools once more fails with ErrMaxToolRounds before they run.
+	// Zero or less means DefaultMaxToolRounds.
+	MaxToolRounds int
+
+	// ParallelToolCalls executes the tool calls of a single round concurrently
+	ParallelToolCalls bool
+
+	// MCPs holds the MCP clients serving namespaced tools
+	MCPs MCPClients
+
+	// Retry controls how requests are retried after transient failures
+	Retry RetryPolicy
 }
 
 // NewBaseClient creates a new base client with common configuration.
@@ -73,10 +96,13 @@ func NewBaseClient(apiKey string, baseURL string, defaultModel string) BaseClien
 		HttpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		Tools:       make(map[string]models.ToolExecutor),
-		Model:       defaultModel,
-		MaxTokens:   1000,
-		Temperature: 0.7, // Default temperature
+		Tools:         make(map[string]models.ToolExecutor),
+		Model:         defaultModel,
+		MaxTokens:     1000,
+		Temperature:   0.7, // Default temperature
+		MaxToolRounds: DefaultMaxToolRounds,
+		MCPs:          make(MCPClients),
+		Retry:         DefaultRetryPolicy(),
 	}
 }
 
@@ -86,6 +112,21 @@ func (c *BaseClient) RegisterTool(tool models.ToolExecutor) {
 	c.Tools[tool.GetName()] = tool
 }
 
+// RegisterMCP starts an MCP server and registers its tools with the provider.
+// The tools are namespaced with the command so that calls can be routed back
+// to the right server. This implements part of the models.Provider interface.
+func (c *BaseClient) RegisterMCP(command string, args []string) error {
+	return c.MCPs.Register(command, args, c.RegisterTool)
+}
+
+// CountTokens estimates the input tokens of the messages and tool definitions
+// with models.EstimateTokens and models.EstimateToolTokens, for providers
+// without a tokenizer or token counting endpoint.
+// This implements part of the models.Provider interface.
+func (c *BaseClient) CountTokens(ctx context.Context, messages []models.Message, tools []models.ToolExecutor) (int, error) {
+	return models.EstimateTokens(messages) + models.EstimateToolTokens(tools), nil
+}
+
 // SetModel configures which specific model version to use for this provider.
 // This implements part of the models.Provider interface.
 func (c *BaseClient) SetModel(model string) {
@@ -112,10 +153,67 @@ func (c *BaseClient) SetTemperature(temperature float64) {
 	c.Temperature = temperature
 }
 
+// SetMaxToolRounds configures how many rounds of tool calls a single request may trigger
+// before it fails with ErrMaxToolRounds. Zero or less means DefaultMaxToolRounds.
+func (c *BaseClient) SetMaxToolRounds(rounds int) {
+	c.MaxToolRounds = rounds
+}
+
+// SetRetryPolicy configures how requests are retried after transient failures
+// such as rate limits or overloaded servers.
+func (c *BaseClient) SetRetryPolicy(policy RetryPolicy) {
+	c.Retry = policy
+}
+
+// SetParallelToolCalls configures whether the tool calls requested in a single
+// round are executed concurrently.
+func (c *BaseClient) SetParallelToolCalls(parallel bool) {
+	c.ParallelToolCalls = parallel
+}
+
 // DoHTTPRequest performs an HTTP request and returns the response body.
 // It handles the details of creating the request, setting headers, sending it,
 // and processing the response, including error handling.
 func (c *BaseClient) DoHTTPRequest(ctx context.Context, req HTTPRequest) ([]byte, error) {
+	resp, err := c.send(ctx, c.HttpClient, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Read response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// DoStreamRequest performs an HTTP request and returns the response without
+// reading its body, so that streamed responses can be consumed as they arrive.
+// Non-2xx responses are turned into typed errors, see NewAPIError.
+// The caller must close the body.
+func (c *BaseClient) DoStreamRequest(ctx context.Context, req HTTPRequest) (*http.Response, error) {
+	client := c.StreamClient
+	if client == nil {
+		client = StreamingClient(c.HttpClient)
+	}
+	return c.send(ctx, client, req)
+}
+
+// StreamingClient returns a copy of client without its total timeout, which
+// also covers reading the body and would cut off responses streaming for
+// longer. The request's context bounds the stream instead.
+func StreamingClient(client *http.Client) *http.Client {
+	streaming := *client
+	streaming.Timeout = 0
+	return &streaming
+}
+
+// send performs an HTTP request with the given client, retrying transient
+// failures, and turns non-2xx responses into typed errors.
+func (c *BaseClient) send(ctx context.Context, client *http.Client, req HTTPRequest) (*http.Response, error) {
 	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, bytes.NewBuffer(req.Body))
 	if err != nil {
 		return nil, err
@@ -126,37 +224,58 @@ func (c *BaseClient) DoHTTPRequest(ctx context.Context, req HTTPRequest) ([]byte
 		httpReq.Header.Set(key, value)
 	}
 
-	// Execute request
-	resp, err := c.HttpClient.Do(httpReq)
+	// Execute request, retrying transient failures
+	resp, err := c.Retry.Do(ctx, func() (*http.Response, error) {
+		attempt := httpReq.Clone(ctx)
+		attempt.Body = io.NopCloser(bytes.NewReader(req.Body))
+		return client.Do(attempt)
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
 	// Check for non-200 status code
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("HTTP error %d: %s", resp.StatusCode, string(body))
+		return nil, NewAPIError(c.Provider, resp.StatusCode, resp.Header, body)
 	}
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
+	return resp, nil
+}
 
-	return body, nil
+// ErrMaxToolRounds is returned when a model keeps calling tools beyond the
+// number of rounds a provider allows for a single request.
+var ErrMaxToolRounds = errors.New("maximum number of tool rounds exceeded")
+
+// DefaultMaxToolRounds is the number of tool rounds allowed when a provider's
+// limit is not set, as in a client built as a struct literal
+const DefaultMaxToolRounds = 10
+
+// ToolRoundsExceeded reports whether the tool calls requested in the given
+// round, counting from 0, exceed the limit; zero or less means
+// DefaultMaxToolRounds.
+func ToolRoundsExceeded(round, limit int) bool {
+	if limit <= 0 {
+		limit = DefaultMaxToolRounds
+	}
+	return round >= limit
 }
 
 // HandleToolCall executes the requested tool with the provided input.
 // It looks up the tool in the registry, executes it with the given input,
 // and returns the result or an error if the tool is not found or execution fails.
+// Namespaced tools are dispatched to the MCP client registered for their namespace.
 func (c *BaseClient) HandleToolCall(ctx context.Context, toolName string, input json.RawMessage) (string, error) {
 	tool, exists := c.Tools[toolName]
 	if !exists {
 		return "", fmt.Errorf("tool %s not found", toolName)
 	}
 
+	if tool.IsNamespaced() {
+		return c.MCPs.CallTool(tool.GetName(), input)
+	}
+
 	result, err := tool.Execute(input)
 	if err != nil {
 		return "", fmt.Errorf("tool execution failed: %w", err)
@@ -165,6 +284,67 @@ func (c *BaseClient) HandleToolCall(ctx context.Context, toolName string, input
 	return result, nil
 }
 
+// ToolCall describes a single tool invocation requested by a model.
+type ToolCall struct {
+	// ID identifies the call so its result can be matched to it
+	ID string
+
+	// Name is the name of the tool to execute
+	Name string
+
+	// Input contains the JSON arguments for the tool
+	Input json.RawMessage
+}
+
+// ToolCallResult holds the outcome of executing a ToolCall.
+type ToolCallResult struct {
+	// ID is the ID of the call that produced this result
+	ID string
+
+	// Output is the tool's output, empty if the call failed
+	Output string
+
+	// Err is set when the tool could not be executed
+	Err error
+}
+
+// HandleToolCalls executes every requested tool call and returns the results in
+// the same order as the calls. Calls run concurrently when ParallelToolCalls is set.
+// The calls count against the budget in the context, if any, and calls beyond
+// it fail without being executed.
+func (c *BaseClient) HandleToolCalls(ctx context.Context, calls []ToolCall) []ToolCallResult {
+	results := make([]ToolCallResult, len(calls))
+
+	execute := func(i int) {
+		if err := models.StartToolCall(ctx); err != nil {
+			results[i] = ToolCallResult{ID: calls[i].ID, Err: err}
+			return
+		}
+
+		output, err := c.HandleToolCall(ctx, calls[i].Name, calls[i].Input)
+		results[i] = ToolCallResult{ID: calls[i].ID, Output: output, Err: err}
+	}
+
+	if !c.ParallelToolCalls {
+		for i := range calls {
+			execute(i)
+		}
+		return results
+	}
+
+	var wg sync.WaitGroup
+	for i := range calls {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			execute(i)
+		}(i)
+	}
+	wg.Wait()
+
+	return results
+}
+
 // ErrorResponse is a standard error structure returned by API providers.
 type ErrorResponse struct {
 	Error *ErrorDetail `json:"error,omitempty"`
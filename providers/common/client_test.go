@@ -0,0 +1,24 @@
+package common
+
+import "testing"
+
+// TestToolRoundsExceeded tests the tool round limit, with unset limits meaning the default
+func TestToolRoundsExceeded(t *testing.T) {
+	tests := []struct {
+		round, limit int
+		want         bool
+	}{
+		{round: 0, limit: 1, want: false},
+		{round: 1, limit: 1, want: true},
+		{round: 0, limit: 0, want: false},
+		{round: DefaultMaxToolRounds - 1, limit: 0, want: false},
+		{round: DefaultMaxToolRounds, limit: 0, want: true},
+		{round: 3, limit: -1, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := ToolRoundsExceeded(tt.round, tt.limit); got != tt.want {
+			t.Errorf("Expected round %d with limit %d to be exceeded: %v, got %v", tt.round, tt.limit, tt.want, got)
+		}
+	}
+}
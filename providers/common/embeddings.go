@@ -0,0 +1,54 @@
+package common
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// EmbeddingConfig configures the embeddings computed by providers implementing
+// models.Embedder
+type EmbeddingConfig struct {
+	// Model is the embedding model, separate from the chat model
+	Model string
+
+	// Dimensions shortens the vectors to this length, for models that support
+	// it; 0 keeps the model's native length
+	Dimensions int
+
+	// BatchSize is the maximum number of texts embedded per request; 0 sends
+	// all the texts in one request
+	BatchSize int
+}
+
+// EmbedBatches splits the texts into batches of at most size texts and embeds
+// them in turn with embed, which sends one request. It checks that every batch
+// returns one vector per text, and checks the context's budget before every
+// request.
+func EmbedBatches(ctx context.Context, texts []string, size int, embed func(ctx context.Context, batch []string) ([][]float32, error)) ([][]float32, error) {
+	if size <= 0 {
+		size = len(texts)
+	}
+
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += size {
+		batch := texts[start:min(start+size, len(texts))]
+
+		if err := models.CheckBudget(ctx, nil); err != nil {
+			return nil, err
+		}
+
+		embedded, err := embed(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(embedded) != len(batch) {
+			return nil, fmt.Errorf("expected %d embeddings, got %d", len(batch), len(embedded))
+		}
+
+		vectors = append(vectors, embedded...)
+	}
+
+	return vectors, nil
+}
@@ -0,0 +1,208 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// APIError describes an error response from a provider's API. Every typed
+// error below wraps an APIError, so errors.As with *APIError matches any of
+// them while errors.As with a specific type tells them apart:
+//
+//	var rateLimit *common.RateLimitError
+//	if errors.As(err, &rateLimit) {
+//		time.Sleep(rateLimit.RetryAfter)
+//	}
+type APIError struct {
+	// Provider names the API that returned the error, e.g. "Claude".
+	// It is empty for errors raised by a BaseClient without a Provider.
+	Provider string
+
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+
+	// Type is the provider's error type or code, e.g. "rate_limit_error"
+	Type string
+
+	// Message is the human readable message returned by the API
+	Message string
+
+	// RequestID identifies the failed request in the provider's logs
+	RequestID string
+
+	// RetryAfter is how long the provider asked to wait before retrying,
+	// or zero if it did not say
+	RetryAfter time.Duration
+
+	// Body is the raw response body, for provider-specific details
+	Body []byte
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	if e.Provider == "" {
+		return fmt.Sprintf("HTTP error %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s API error (%d): %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// RateLimitError is returned when the provider rejects a request because a rate limit was hit.
+type RateLimitError struct{ *APIError }
+
+// Unwrap returns the underlying APIError.
+func (e *RateLimitError) Unwrap() error { return e.APIError }
+
+// AuthError is returned when the API key is missing, invalid or lacks permission.
+type AuthError struct{ *APIError }
+
+// Unwrap returns the underlying APIError.
+func (e *AuthError) Unwrap() error { return e.APIError }
+
+// OverloadedError is returned when the provider is temporarily overloaded.
+type OverloadedError struct{ *APIError }
+
+// Unwrap returns the underlying APIError.
+func (e *OverloadedError) Unwrap() error { return e.APIError }
+
+// ContextLengthError is returned when the conversation does not fit in the model's context window.
+type ContextLengthError struct{ *APIError }
+
+// Unwrap returns the underlying APIError.
+func (e *ContextLengthError) Unwrap() error { return e.APIError }
+
+// InvalidRequestError is returned when the provider rejects a malformed or unsupported request.
+type InvalidRequestError struct{ *APIError }
+
+// Unwrap returns the underlying APIError.
+func (e *InvalidRequestError) Unwrap() error { return e.APIError }
+
+// contextLengthMarkers are fragments of the error types and messages providers
+// use when a request exceeds the model's context window.
+var contextLengthMarkers = []string{
+	"context_length_exceeded",
+	"prompt is too long",
+	"maximum context length",
+	"context window",
+	"exceeds the maximum number of tokens",
+}
+
+// NewAPIError builds a typed error from an error response. The body is parsed
+// for the error type and message in any of the shapes used by the supported
+// providers, and the request ID and retry delay are read from the headers.
+func NewAPIError(provider string, statusCode int, header http.Header, body []byte) error {
+	apiErr := &APIError{
+		Provider:   provider,
+		StatusCode: statusCode,
+		Body:       body,
+	}
+	apiErr.Type, apiErr.Message = parseErrorBody(body)
+
+	if header != nil {
+		apiErr.RequestID = firstHeader(header, "request-id", "x-request-id", "apim-request-id")
+		if delay, ok := serverDelay(header, time.Now()); ok {
+			apiErr.RetryAfter = delay
+		}
+	}
+
+	return classify(apiErr)
+}
+
+// classify wraps an APIError in the typed error matching its status and type.
+func classify(apiErr *APIError) error {
+	lowered := strings.ToLower(apiErr.Type + " " + apiErr.Message)
+	for _, marker := range contextLengthMarkers {
+		if strings.Contains(lowered, marker) {
+			return &ContextLengthError{apiErr}
+		}
+	}
+
+	switch {
+	case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+		return &AuthError{apiErr}
+	case apiErr.StatusCode == http.StatusTooManyRequests:
+		return &RateLimitError{apiErr}
+	case apiErr.StatusCode == 529 || apiErr.StatusCode == http.StatusServiceUnavailable || apiErr.Type == "overloaded_error":
+		return &OverloadedError{apiErr}
+	case apiErr.StatusCode == http.StatusBadRequest ||
+		apiErr.StatusCode == http.StatusNotFound ||
+		apiErr.StatusCode == http.StatusRequestEntityTooLarge ||
+		apiErr.StatusCode == http.StatusUnprocessableEntity:
+		return &InvalidRequestError{apiErr}
+	}
+
+	return apiErr
+}
+
+// NewStreamError builds a typed error from an error event received in the
+// middle of a streamed response, where no HTTP status is available.
+func NewStreamError(provider string, errorType string, message string) error {
+	apiErr := &APIError{Provider: provider, Type: errorType, Message: message}
+
+	switch errorType {
+	case "overloaded_error":
+		return &OverloadedError{apiErr}
+	case "rate_limit_error":
+		return &RateLimitError{apiErr}
+	case "authentication_error", "permission_error":
+		return &AuthError{apiErr}
+	case "invalid_request_error":
+		return classify(&APIError{Provider: provider, StatusCode: http.StatusBadRequest, Type: errorType, Message: message})
+	}
+
+	return apiErr
+}
+
+// parseErrorBody extracts the error type and message from a response body.
+// It understands Anthropic and OpenAI style {"error": {"type", "message"}},
+// Google style {"error": {"status", "message"}} and Ollama style {"error": "..."}.
+// Bodies in none of these shapes are returned as the message.
+func parseErrorBody(body []byte) (string, string) {
+	var envelope struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || len(envelope.Error) == 0 {
+		return "", strings.TrimSpace(string(body))
+	}
+
+	var message string
+	if err := json.Unmarshal(envelope.Error, &message); err == nil {
+		return "", message
+	}
+
+	var detail struct {
+		Type    string          `json:"type"`
+		Code    json.RawMessage `json:"code"`
+		Status  json.RawMessage `json:"status"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(envelope.Error, &detail); err != nil {
+		return "", strings.TrimSpace(string(body))
+	}
+
+	// OpenAI sends a string code such as "context_length_exceeded" that is more
+	// specific than its type, Google a numeric code alongside a status name.
+	// Codes and statuses that are numbers carry no extra information.
+	var code, status string
+	if json.Unmarshal(detail.Code, &code) == nil && code != "" {
+		return code, detail.Message
+	}
+	if detail.Type != "" {
+		return detail.Type, detail.Message
+	}
+	json.Unmarshal(detail.Status, &status)
+
+	return status, detail.Message
+}
+
+// firstHeader returns the value of the first of the named headers that is set.
+func firstHeader(header http.Header, names ...string) string {
+	for _, name := range names {
+		if value := header.Get(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
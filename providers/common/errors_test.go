@@ -0,0 +1,129 @@
+package common
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestNewAPIError tests that error responses from each provider map to the right type
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		check   func(error) bool
+		errType string
+		message string
+	}{
+		{
+			name:    "anthropic rate limit",
+			status:  429,
+			body:    `{"type": "error", "error": {"type": "rate_limit_error", "message": "Number of requests has exceeded your rate limit"}}`,
+			check:   func(err error) bool { var e *RateLimitError; return errors.As(err, &e) },
+			errType: "rate_limit_error",
+			message: "Number of requests has exceeded your rate limit",
+		},
+		{
+			name:    "anthropic overloaded",
+			status:  529,
+			body:    `{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`,
+			check:   func(err error) bool { var e *OverloadedError; return errors.As(err, &e) },
+			errType: "overloaded_error",
+			message: "Overloaded",
+		},
+		{
+			name:    "anthropic prompt too long",
+			status:  400,
+			body:    `{"type": "error", "error": {"type": "invalid_request_error", "message": "prompt is too long: 210000 tokens > 200000 maximum"}}`,
+			check:   func(err error) bool { var e *ContextLengthError; return errors.As(err, &e) },
+			errType: "invalid_request_error",
+			message: "prompt is too long: 210000 tokens > 200000 maximum",
+		},
+		{
+			name:    "openai invalid key",
+			status:  401,
+			body:    `{"error": {"message": "Incorrect API key provided", "type": "invalid_request_error", "param": null, "code": "invalid_api_key"}}`,
+			check:   func(err error) bool { var e *AuthError; return errors.As(err, &e) },
+			errType: "invalid_api_key",
+			message: "Incorrect API key provided",
+		},
+		{
+			name:    "openai context length",
+			status:  400,
+			body:    `{"error": {"message": "This model's maximum context length is 128000 tokens.", "type": "invalid_request_error", "param": "messages", "code": "context_length_exceeded"}}`,
+			check:   func(err error) bool { var e *ContextLengthError; return errors.As(err, &e) },
+			errType: "context_length_exceeded",
+			message: "This model's maximum context length is 128000 tokens.",
+		},
+		{
+			name:    "gemini invalid argument",
+			status:  400,
+			body:    `{"error": {"code": 400, "message": "Invalid JSON payload received.", "status": "INVALID_ARGUMENT"}}`,
+			check:   func(err error) bool { var e *InvalidRequestError; return errors.As(err, &e) },
+			errType: "INVALID_ARGUMENT",
+			message: "Invalid JSON payload received.",
+		},
+		{
+			name:    "ollama model not found",
+			status:  404,
+			body:    `{"error": "model 'llama9' not found"}`,
+			check:   func(err error) bool { var e *InvalidRequestError; return errors.As(err, &e) },
+			message: "model 'llama9' not found",
+		},
+		{
+			name:    "plain text server error",
+			status:  502,
+			body:    "Bad Gateway",
+			check:   func(err error) bool { _, ok := err.(*APIError); return ok },
+			message: "Bad Gateway",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewAPIError("Test", tt.status, nil, []byte(tt.body))
+
+			if !tt.check(err) {
+				t.Errorf("Unexpected error type %T", err)
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Expected error to unwrap to *APIError")
+			}
+
+			if apiErr.StatusCode != tt.status || apiErr.Type != tt.errType || apiErr.Message != tt.message {
+				t.Errorf("Unexpected fields: status %d, type '%s', message '%s'", apiErr.StatusCode, apiErr.Type, apiErr.Message)
+			}
+		})
+	}
+}
+
+// TestNewAPIErrorHeaders tests that the request ID and retry delay are read from headers
+func TestNewAPIErrorHeaders(t *testing.T) {
+	header := http.Header{
+		"Request-Id":  {"req_011CKZ"},
+		"Retry-After": {"12"},
+	}
+
+	err := NewAPIError("Claude", 429, header, []byte(`{"type": "error", "error": {"type": "rate_limit_error", "message": "Slow down"}}`))
+
+	var rateLimit *RateLimitError
+	if !errors.As(err, &rateLimit) {
+		t.Fatalf("Expected RateLimitError, got %T", err)
+	}
+
+	if rateLimit.RequestID != "req_011CKZ" {
+		t.Errorf("Expected request ID 'req_011CKZ', got '%s'", rateLimit.RequestID)
+	}
+
+	if rateLimit.RetryAfter != 12*time.Second {
+		t.Errorf("Expected retry after 12s, got %v", rateLimit.RetryAfter)
+	}
+
+	if err.Error() != "Claude API error (429): Slow down" {
+		t.Errorf("Unexpected message '%s'", err.Error())
+	}
+}
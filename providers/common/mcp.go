@@ -0,0 +1,85 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/devOpifex/bond/mcp"
+	"github.com/devOpifex/bond/models"
+)
+
+// MCPClients maps namespaces to the MCP clients that serve their tools.
+// Tools provided by an MCP server are registered as "namespace__tool", and calls
+// to them are routed back to the client registered for that namespace.
+type MCPClients map[string]*mcp.MCP
+
+// Register starts the MCP server for command and initialises its tool list.
+// Each tool is namespaced with the command and passed to register so the
+// provider can offer it to the model.
+func (m MCPClients) Register(command string, args []string, register func(models.ToolExecutor)) error {
+	client := mcp.New(command, args)
+
+	_, err := client.Initialise()
+	if err != nil {
+		return err
+	}
+
+	for _, tool := range client.GetRegistry() {
+		tool.Namespace(command)
+		register(tool)
+	}
+
+	m[command] = client
+
+	return nil
+}
+
+// CallTool executes a namespaced tool through the MCP client registered for its namespace.
+// The tool's content items are flattened into a single string result.
+func (m MCPClients) CallTool(name string, input json.RawMessage) (string, error) {
+	// Extract namespace from tool name
+	namespace, toolName, found := strings.Cut(name, "__")
+	if !found {
+		return "", fmt.Errorf("invalid namespaced tool format '%s'", name)
+	}
+
+	// Find the MCP client for this namespace
+	mcpClient, exists := m[namespace]
+	if !exists {
+		return "", fmt.Errorf("MCP for namespace '%s' not found", namespace)
+	}
+
+	// Parse the input JSON
+	var args map[string]any
+	if err := json.Unmarshal(input, &args); err != nil {
+		return "", fmt.Errorf("error parsing tool arguments: %v", err)
+	}
+
+	// Call the tool via MCP
+	toolResult, err := mcpClient.CallTool(toolName, args)
+	if err != nil {
+		return "", fmt.Errorf("error executing MCP tool '%s': %v", name, err)
+	}
+
+	// Fallback to the simple result
+	if len(toolResult.Content) == 0 {
+		return toolResult.Result, nil
+	}
+
+	// Format the result based on content items
+	var formattedResult string
+	for _, item := range toolResult.Content {
+		switch item.Type {
+		case "text":
+			formattedResult += item.Text
+		case "image":
+			formattedResult += fmt.Sprintf("[Image: %s]", item.MimeType)
+		default:
+			formattedResult += fmt.Sprintf("[Content type: %s]", item.Type)
+		}
+		formattedResult += "\n"
+	}
+
+	return strings.TrimSpace(formattedResult), nil
+}
@@ -0,0 +1,252 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how requests to a provider are retried after transient
+// failures such as rate limits, overloaded servers or refused connections.
+// The zero value sends every request exactly once.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values of 1 or less disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts computed from the backoff.
+	MaxBackoff time.Duration
+
+	// Multiplier grows the backoff after every attempt.
+	Multiplier float64
+
+	// Jitter is the fraction (0.0-1.0) of each backoff that is randomised, so
+	// that clients failing together do not retry in lockstep.
+	Jitter float64
+
+	// MaxRetryAfter is the longest delay the policy will wait when the server
+	// asks for one through Retry-After or rate limit headers. Longer requested
+	// delays end the retries and the failure is returned to the caller.
+	MaxRetryAfter time.Duration
+}
+
+// DefaultRetryPolicy returns the policy providers use unless configured otherwise:
+// up to 4 attempts with exponential backoff starting at 500ms.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		MaxRetryAfter:  60 * time.Second,
+	}
+}
+
+// NoRetry returns a policy that sends every request exactly once.
+func NoRetry() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+// Do calls send until it succeeds, fails permanently or runs out of attempts.
+// send must build a fresh request on every call, since request bodies cannot be
+// replayed. The last response is returned whatever its status, so callers handle
+// errors exactly as without retries; bodies of discarded responses are closed.
+func (p RetryPolicy) Do(ctx context.Context, send func() (*http.Response, error)) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := send()
+
+		if attempt >= p.MaxAttempts || !p.shouldRetry(ctx, resp, err) {
+			return resp, err
+		}
+
+		delay := p.backoff(attempt)
+		if resp != nil {
+			if wait, ok := serverDelay(resp.Header, time.Now()); ok {
+				if p.MaxRetryAfter > 0 && wait > p.MaxRetryAfter {
+					return resp, err
+				}
+				delay = wait
+			}
+
+			// Drain the body so the connection can be reused
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// shouldRetry reports whether a failed attempt may be retried. Requests to
+// models are not idempotent: one the server has already processed would be
+// answered and billed twice. Attempts are therefore only retried when the
+// request cannot have been processed, because the connection could not be
+// established, or when an error response asks for a retry: rate limits (429),
+// unavailable or overloaded servers (503, 529), a Retry-After or
+// retry-after-ms header, or x-should-retry: true. Successful responses, other
+// server errors, timeouts and connections dropped mid-request are returned to
+// the caller.
+func (p RetryPolicy) shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		// Requests cancelled by the caller are not transient failures
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+
+		// Only connections that could not be established, including refused
+		// ones, guarantee the server never saw the request
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+
+	// Successful responses are never sent again, whatever their headers say
+	if resp.StatusCode < 400 {
+		return false
+	}
+
+	// Servers can explicitly allow or forbid retries
+	switch resp.Header.Get("x-should-retry") {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	if resp.Header.Get("Retry-After") != "" || resp.Header.Get("retry-after-ms") != "" {
+		return true
+	}
+
+	return IsRetryableStatus(resp.StatusCode)
+}
+
+// IsRetryableStatus reports whether a response status asks for the request to
+// be retried later: rate limits (429), unavailable servers (503) and
+// Anthropic's 529 "overloaded". Requests failing with these were not processed.
+func IsRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable, 529:
+		return true
+	}
+	return false
+}
+
+// backoff returns the jittered delay before the retry following the given attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		delay -= delay * p.Jitter * rand.Float64()
+	}
+
+	return time.Duration(delay)
+}
+
+// serverDelay extracts the delay a server asked for before the next attempt.
+// It understands Retry-After (seconds or HTTP date), retry-after-ms, and the
+// reset times of exhausted limits in anthropic-ratelimit-* and x-ratelimit-*
+// headers. It returns false when the response carries no such hint.
+func serverDelay(header http.Header, now time.Time) (time.Duration, bool) {
+	if ms := header.Get("retry-after-ms"); ms != "" {
+		if value, err := strconv.ParseFloat(ms, 64); err == nil && value >= 0 {
+			return time.Duration(value * float64(time.Millisecond)), true
+		}
+	}
+
+	if retryAfter := header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.ParseFloat(retryAfter, 64); err == nil && seconds >= 0 {
+			return time.Duration(seconds * float64(time.Second)), true
+		}
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			return nonNegative(date.Sub(now)), true
+		}
+	}
+
+	// Without an explicit hint, wait until every exhausted limit has reset
+	var delay time.Duration
+	found := false
+	for name := range header {
+		name = strings.ToLower(name)
+		reset, ok := limitReset(header, name, now)
+		if !ok {
+			continue
+		}
+		if reset > delay {
+			delay = reset
+		}
+		found = true
+	}
+
+	return delay, found
+}
+
+// limitReset returns how long until the rate limit reported by the named header
+// resets, if the header is a reset header for a limit with nothing remaining.
+//
+// Anthropic reports resets as RFC 3339 timestamps, e.g.
+// anthropic-ratelimit-tokens-reset alongside anthropic-ratelimit-tokens-remaining.
+// OpenAI reports them as durations, e.g. x-ratelimit-reset-tokens: 6m0s alongside
+// x-ratelimit-remaining-tokens.
+func limitReset(header http.Header, name string, now time.Time) (time.Duration, bool) {
+	switch {
+	case strings.HasPrefix(name, "anthropic-ratelimit-") && strings.HasSuffix(name, "-reset"):
+		remaining := strings.TrimSuffix(name, "-reset") + "-remaining"
+		if header.Get(remaining) != "0" {
+			return 0, false
+		}
+		reset, err := time.Parse(time.RFC3339, header.Get(name))
+		if err != nil {
+			return 0, false
+		}
+		return nonNegative(reset.Sub(now)), true
+
+	case strings.HasPrefix(name, "x-ratelimit-reset-"):
+		remaining := "x-ratelimit-remaining-" + strings.TrimPrefix(name, "x-ratelimit-reset-")
+		if header.Get(remaining) != "0" {
+			return 0, false
+		}
+		reset, err := time.ParseDuration(header.Get(name))
+		if err != nil {
+			return 0, false
+		}
+		return nonNegative(reset), true
+	}
+
+	return 0, false
+}
+
+// nonNegative clamps negative durations, e.g. reset times already in the past, to zero.
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,257 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fastRetry is a policy with short delays so tests run quickly
+func fastRetry() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		Multiplier:     2,
+		Jitter:         0.5,
+		MaxRetryAfter:  time.Second,
+	}
+}
+
+// scriptedServer returns a server answering with the given statuses in order,
+// followed by 200 OK, along with a pointer to the number of requests received
+func scriptedServer(t *testing.T, statuses []int, header http.Header) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= len(statuses) {
+			for name, values := range header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(statuses[requests-1])
+			w.Write([]byte(`{"error": {"type": "overloaded_error", "message": "Overloaded"}}`))
+			return
+		}
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// TestDoHTTPRequestRetries tests that transient failures are retried until success
+func TestDoHTTPRequestRetries(t *testing.T) {
+	server, requests := scriptedServer(t, []int{529, http.StatusTooManyRequests}, nil)
+
+	client := NewBaseClient("key", server.URL, "model")
+	client.SetRetryPolicy(fastRetry())
+
+	body, err := client.DoHTTPRequest(context.Background(), HTTPRequest{
+		Method: "POST",
+		URL:    server.URL,
+		Body:   []byte(`{"prompt": "hello"}`),
+	})
+	if err != nil {
+		t.Fatalf("Expected request to succeed after retries, got %v", err)
+	}
+
+	if string(body) != `{"ok": true}` {
+		t.Errorf("Unexpected body '%s'", body)
+	}
+
+	if *requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", *requests)
+	}
+}
+
+// TestDoHTTPRequestGivesUp tests that retries stop after MaxAttempts
+func TestDoHTTPRequestGivesUp(t *testing.T) {
+	server, requests := scriptedServer(t, []int{503, 503, 503, 503}, nil)
+
+	client := NewBaseClient("key", server.URL, "model")
+	client.SetRetryPolicy(fastRetry())
+
+	_, err := client.DoHTTPRequest(context.Background(), HTTPRequest{Method: "POST", URL: server.URL})
+
+	var overloaded *OverloadedError
+	if !errors.As(err, &overloaded) || overloaded.StatusCode != 503 {
+		t.Fatalf("Expected overloaded 503 error, got %v", err)
+	}
+
+	if *requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", *requests)
+	}
+}
+
+// TestDoHTTPRequestNoRetryOnClientError tests that client errors are not retried
+func TestDoHTTPRequestNoRetryOnClientError(t *testing.T) {
+	server, requests := scriptedServer(t, []int{http.StatusBadRequest}, nil)
+
+	client := NewBaseClient("key", server.URL, "model")
+	client.SetRetryPolicy(fastRetry())
+
+	if _, err := client.DoHTTPRequest(context.Background(), HTTPRequest{Method: "POST", URL: server.URL}); err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	if *requests != 1 {
+		t.Errorf("Expected a single request, got %d", *requests)
+	}
+}
+
+// TestDoHTTPRequestRetryAfterTooLong tests that a Retry-After beyond MaxRetryAfter ends the retries
+func TestDoHTTPRequestRetryAfterTooLong(t *testing.T) {
+	server, requests := scriptedServer(t, []int{http.StatusTooManyRequests}, http.Header{"Retry-After": {"120"}})
+
+	client := NewBaseClient("key", server.URL, "model")
+	client.SetRetryPolicy(fastRetry())
+
+	if _, err := client.DoHTTPRequest(context.Background(), HTTPRequest{Method: "POST", URL: server.URL}); err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	if *requests != 1 {
+		t.Errorf("Expected a single request, got %d", *requests)
+	}
+}
+
+// TestDoHTTPRequestShouldRetryHeader tests that x-should-retry overrides the status
+func TestDoHTTPRequestShouldRetryHeader(t *testing.T) {
+	server, requests := scriptedServer(t, []int{http.StatusServiceUnavailable}, http.Header{"X-Should-Retry": {"false"}})
+
+	client := NewBaseClient("key", server.URL, "model")
+	client.SetRetryPolicy(fastRetry())
+
+	if _, err := client.DoHTTPRequest(context.Background(), HTTPRequest{Method: "POST", URL: server.URL}); err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	if *requests != 1 {
+		t.Errorf("Expected a single request, got %d", *requests)
+	}
+}
+
+// TestShouldRetry tests that only requests the server cannot have processed are retried
+func TestShouldRetry(t *testing.T) {
+	response := func(status int, header http.Header) *http.Response {
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{StatusCode: status, Header: header}
+	}
+
+	tests := []struct {
+		name string
+		resp *http.Response
+		err  error
+		want bool
+	}{
+		{name: "rate limit", resp: response(http.StatusTooManyRequests, nil), want: true},
+		{name: "unavailable", resp: response(http.StatusServiceUnavailable, nil), want: true},
+		{name: "overloaded", resp: response(529, nil), want: true},
+		{name: "server error", resp: response(http.StatusInternalServerError, nil), want: false},
+		{name: "bad gateway", resp: response(http.StatusBadGateway, nil), want: false},
+		{name: "request timeout", resp: response(http.StatusRequestTimeout, nil), want: false},
+		{name: "conflict", resp: response(http.StatusConflict, nil), want: false},
+		{name: "server error with Retry-After", resp: response(http.StatusInternalServerError, http.Header{"Retry-After": {"1"}}), want: true},
+		{name: "server error with x-should-retry", resp: response(http.StatusInternalServerError, http.Header{"X-Should-Retry": {"true"}}), want: true},
+		{name: "success with x-should-retry", resp: response(http.StatusOK, http.Header{"X-Should-Retry": {"true"}}), want: false},
+		{name: "refused connection", err: &url.Error{Op: "Post", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}, want: true},
+		{name: "dropped connection", err: &url.Error{Op: "Post", Err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}}, want: false},
+		{name: "unexpected EOF", err: io.ErrUnexpectedEOF, want: false},
+		{name: "cancelled", err: context.Canceled, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fastRetry().shouldRetry(context.Background(), tt.resp, tt.err); got != tt.want {
+				t.Errorf("Expected shouldRetry to be %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestServerDelay tests the parsing of retry hints from response headers
+func TestServerDelay(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		header   http.Header
+		expected time.Duration
+		found    bool
+	}{
+		{
+			name:     "retry-after seconds",
+			header:   http.Header{"Retry-After": {"3"}},
+			expected: 3 * time.Second,
+			found:    true,
+		},
+		{
+			name:     "retry-after date",
+			header:   http.Header{"Retry-After": {"Mon, 01 Jan 2024 12:00:10 GMT"}},
+			expected: 10 * time.Second,
+			found:    true,
+		},
+		{
+			name:     "retry-after-ms takes precedence",
+			header:   http.Header{"Retry-After": {"3"}, "Retry-After-Ms": {"250"}},
+			expected: 250 * time.Millisecond,
+			found:    true,
+		},
+		{
+			name: "anthropic exhausted limit",
+			header: http.Header{
+				"Anthropic-Ratelimit-Tokens-Remaining":   {"0"},
+				"Anthropic-Ratelimit-Tokens-Reset":       {"2024-01-01T12:00:20Z"},
+				"Anthropic-Ratelimit-Requests-Remaining": {"10"},
+				"Anthropic-Ratelimit-Requests-Reset":     {"2024-01-01T12:01:00Z"},
+			},
+			expected: 20 * time.Second,
+			found:    true,
+		},
+		{
+			name: "openai exhausted limit",
+			header: http.Header{
+				"X-Ratelimit-Remaining-Requests": {"0"},
+				"X-Ratelimit-Reset-Requests":     {"1m30s"},
+				"X-Ratelimit-Remaining-Tokens":   {"0"},
+				"X-Ratelimit-Reset-Tokens":       {"6s"},
+			},
+			expected: 90 * time.Second,
+			found:    true,
+		},
+		{
+			name:   "limits not exhausted",
+			header: http.Header{"X-Ratelimit-Remaining-Tokens": {"100"}, "X-Ratelimit-Reset-Tokens": {"6s"}},
+			found:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, found := serverDelay(tt.header, now)
+			if found != tt.found || delay != tt.expected {
+				t.Errorf("Expected (%v, %v), got (%v, %v)", tt.expected, tt.found, delay, found)
+			}
+		})
+	}
+}
+
+// TestBackoff tests that backoff grows exponentially within its bounds
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2, Jitter: 0.2}
+
+	for attempt, expected := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		delay := policy.backoff(attempt)
+		if delay > expected || delay < expected*8/10 {
+			t.Errorf("Attempt %d: expected delay in [%v, %v], got %v", attempt, expected*8/10, expected, delay)
+		}
+	}
+}
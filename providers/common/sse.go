@@ -0,0 +1,102 @@
+package common
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// SSEEvent represents a single server-sent event read from a streaming response.
+type SSEEvent struct {
+	// Event is the event name from the "event:" field, empty if not set
+	Event string
+
+	// Data is the payload from one or more "data:" fields joined by newlines
+	Data string
+}
+
+// SSEReader reads server-sent events from a streaming HTTP response body.
+// It implements the subset of the SSE format used by LLM provider APIs.
+type SSEReader struct {
+	scanner *bufio.Scanner
+}
+
+// NewSSEReader creates a new reader for the server-sent events in r.
+func NewSSEReader(r io.Reader) *SSEReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024) // Events can carry large payloads
+
+	return &SSEReader{scanner: scanner}
+}
+
+// Next returns the next event in the stream.
+// It returns io.EOF once the stream is exhausted.
+func (r *SSEReader) Next() (SSEEvent, error) {
+	var event SSEEvent
+	var data []string
+	seen := false
+
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+
+		// A blank line terminates the current event
+		if line == "" {
+			if seen {
+				event.Data = strings.Join(data, "\n")
+				return event, nil
+			}
+			continue
+		}
+
+		// Lines starting with a colon are comments
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			event.Event = value
+			seen = true
+		case "data":
+			data = append(data, value)
+			seen = true
+		}
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return SSEEvent{}, err
+	}
+
+	// Flush a final event that was not followed by a blank line
+	if seen {
+		event.Data = strings.Join(data, "\n")
+		return event, nil
+	}
+
+	return SSEEvent{}, io.EOF
+}
+
+// SendStreamEvent delivers an event on a provider's stream channel unless the
+// context is cancelled first. It reports whether the event was delivered.
+func SendStreamEvent(ctx context.Context, events chan<- models.StreamEvent, event models.StreamEvent) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// NewTruncatedStreamError reports a streamed response that ended before the
+// provider marked it complete, such as a connection dropped mid-response.
+// The error wraps io.ErrUnexpectedEOF.
+func NewTruncatedStreamError(provider string) error {
+	return fmt.Errorf("%s stream ended before the response was complete: %w", provider, io.ErrUnexpectedEOF)
+}
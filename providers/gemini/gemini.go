@@ -0,0 +1,474 @@
+// Package gemini implements the Provider interface for Google's Gemini models.
+// It communicates with the generateContent endpoint of the Gemini API, mapping
+// tool schemas to function declarations and tool calls to functionCall and
+// functionResponse parts.
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/devOpifex/bond/models"
+	"github.com/devOpifex/bond/providers/common"
+)
+
+// roleModel is the role Gemini uses for the model's turns
+const roleModel = "model"
+
+// GenerateContentRequest represents a request to the generateContent endpoint
+type GenerateContentRequest struct {
+	Contents          []Content         `json:"contents"`
+	SystemInstruction *Content          `json:"systemInstruction,omitempty"`
+	Tools             []Tool            `json:"tools,omitempty"`
+	ToolConfig        *ToolConfig       `json:"toolConfig,omitempty"`
+	GenerationConfig  *GenerationConfig `json:"generationConfig,omitempty"`
+}
+
+// Content represents a turn in the conversation, made of one or more parts
+type Content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []Part `json:"parts"`
+}
+
+// Part represents a single piece of content. Exactly one field is set.
+type Part struct {
+	Text             string            `json:"text,omitempty"`
+	InlineData       *InlineData       `json:"inlineData,omitempty"`
+	FunctionCall     *FunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *FunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// InlineData carries base64 encoded media, such as images and PDFs
+type InlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+// FunctionCall represents a request from the model to call a function
+type FunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+// FunctionResponse carries the result of a function call back to the model
+type FunctionResponse struct {
+	ID       string         `json:"id,omitempty"`
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+// Tool groups the function declarations available to the model
+type Tool struct {
+	FunctionDeclarations []FunctionDeclaration `json:"functionDeclarations"`
+}
+
+// FunctionDeclaration describes a function that the model can call
+type FunctionDeclaration struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Parameters  *Schema `json:"parameters,omitempty"`
+}
+
+// ToolConfig controls how the model uses the functions it is offered
+type ToolConfig struct {
+	FunctionCallingConfig FunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+// FunctionCallingConfig sets the function calling mode, optionally restricting
+// the functions the model may call
+type FunctionCallingConfig struct {
+	Mode                 string   `json:"mode"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+// GenerationConfig holds the sampling parameters for a request
+type GenerationConfig struct {
+	Temperature     float64  `json:"temperature"`
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	TopK            *int     `json:"topK,omitempty"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
+	Seed            *int     `json:"seed,omitempty"`
+}
+
+// GenerateContentResponse represents a response from the generateContent endpoint
+type GenerateContentResponse struct {
+	Candidates     []Candidate     `json:"candidates"`
+	PromptFeedback *PromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *UsageMetadata  `json:"usageMetadata,omitempty"`
+	ModelVersion   string          `json:"modelVersion,omitempty"`
+}
+
+// Candidate represents a generated response
+type Candidate struct {
+	Content      Content `json:"content"`
+	FinishReason string  `json:"finishReason"`
+}
+
+// PromptFeedback reports whether the prompt was blocked
+type PromptFeedback struct {
+	BlockReason string `json:"blockReason,omitempty"`
+}
+
+// UsageMetadata reports the tokens used by a request
+type UsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount,omitempty"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+}
+
+// convert converts the reported usage to Bond's format. Gemini counts cached
+// tokens in promptTokenCount, so they are moved to CacheReadTokens.
+func (u *UsageMetadata) convert() models.Usage {
+	return models.Usage{
+		InputTokens:     u.PromptTokenCount - u.CachedContentTokenCount,
+		OutputTokens:    u.CandidatesTokenCount,
+		CacheReadTokens: u.CachedContentTokenCount,
+	}
+}
+
+// Client is the Gemini API client implementation.
+// It handles communication with the Gemini API and implements the models.Provider interface.
+type Client struct {
+	common.BaseClient
+}
+
+// Client must satisfy the models.Provider interface
+var _ models.Provider = (*Client)(nil)
+
+// NewClient creates a new Gemini client with the provided API key.
+// It initializes the client with default settings for the Gemini API.
+func NewClient(apiKey string) *Client {
+	baseClient := common.NewBaseClient(
+		apiKey,
+		"https://generativelanguage.googleapis.com/v1beta/models",
+		"gemini-2.0-flash",
+	)
+	baseClient.Provider = "Gemini"
+
+	return &Client{BaseClient: baseClient}
+}
+
+// SendMessage sends a message to Gemini and returns the model's response.
+// This method does not include tool information in the request.
+func (c *Client) SendMessage(ctx context.Context, message models.Message) (string, error) {
+	conversation := models.NewConversation("").Append(message)
+	return c.sendRequest(ctx, conversation, false)
+}
+
+// SendMessageWithTools sends a message to Gemini with registered tools.
+// Function calls are executed and their results sent back until the model answers.
+func (c *Client) SendMessageWithTools(ctx context.Context, message models.Message) (string, error) {
+	conversation := models.NewConversation("").Append(message)
+	return c.sendRequest(ctx, conversation, true)
+}
+
+// SendConversation sends the whole conversation to Gemini with registered tools.
+// The model's turns, including function calls and their results, are appended to
+// the conversation.
+func (c *Client) SendConversation(ctx context.Context, conversation *models.Conversation) (string, error) {
+	return c.sendRequest(ctx, conversation, true)
+}
+
+// endpoint returns the URL of the generateContent method for the configured model.
+func (c *Client) endpoint(model string) string {
+	return fmt.Sprintf("%s/%s:generateContent", strings.TrimSuffix(c.BaseURL, "/"), model)
+}
+
+// headers returns the HTTP headers for requests to the Gemini API.
+func (c *Client) headers() map[string]string {
+	return map[string]string{
+		"Content-Type":   "application/json",
+		"x-goog-api-key": c.ApiKey,
+	}
+}
+
+// buildRequest creates the generateContent request for a conversation.
+// Registered tools are converted to function declarations when withTools is set.
+func (c *Client) buildRequest(conversation *models.Conversation, withTools bool) GenerateContentRequest {
+	request := GenerateContentRequest{
+		GenerationConfig: &GenerationConfig{
+			Temperature:     c.Temperature,
+			MaxOutputTokens: c.MaxTokens,
+		},
+	}
+	request.Contents, request.SystemInstruction = c.convertMessages(conversation)
+
+	if !withTools || len(c.Tools) == 0 {
+		return request
+	}
+
+	tool := Tool{}
+	for _, registered := range c.Tools {
+		tool.FunctionDeclarations = append(tool.FunctionDeclarations, FunctionDeclaration{
+			Name:        registered.GetName(),
+			Description: registered.GetDescription(),
+			Parameters:  convertSchema(registered.GetSchema()),
+		})
+	}
+	request.Tools = []Tool{tool}
+
+	return request
+}
+
+// convertMessages transforms a conversation to Gemini contents and a system instruction.
+// System messages within the conversation are added to the system instruction, as
+// Gemini only accepts user and model turns in contents.
+func (c *Client) convertMessages(conversation *models.Conversation) ([]Content, *Content) {
+	var contents []Content
+	var system []Part
+
+	// Use the conversation's system prompt if set, otherwise the client's
+	systemPrompt := c.SystemPrompt
+	if conversation.SystemPrompt != "" {
+		systemPrompt = conversation.SystemPrompt
+	}
+	if systemPrompt != "" {
+		system = append(system, Part{Text: systemPrompt})
+	}
+
+	for index, msg := range conversation.Messages {
+		switch {
+		case msg.Role == models.RoleSystem:
+			system = append(system, Part{Text: msg.Content})
+			continue
+		case msg.Role == models.RoleFunction && msg.ToolResult != nil:
+			contents = append(contents, Content{
+				Role: models.RoleUser,
+				Parts: []Part{{FunctionResponse: &FunctionResponse{
+					Name:     msg.ToolResult.Name,
+					Response: map[string]any{"result": msg.Content},
+				}}},
+			})
+			continue
+		case msg.Role == models.RoleFunction:
+			contents = append(contents, Content{
+				Role:  models.RoleUser,
+				Parts: []Part{{Text: fmt.Sprintf("Function returned: %s", msg.Content)}},
+			})
+			continue
+		}
+
+		role := models.RoleUser
+		if msg.Role == models.RoleAssistant {
+			role = roleModel
+		}
+
+		if len(msg.Blocks) == 0 {
+			contents = append(contents, Content{Role: role, Parts: []Part{{Text: msg.Content}}})
+			continue
+		}
+
+		converted := Content{Role: role}
+		for _, block := range msg.Blocks {
+			switch block.Type {
+			case models.BlockText:
+				converted.Parts = append(converted.Parts, Part{Text: block.Text})
+			case models.BlockImage, models.BlockDocument:
+				converted.Parts = append(converted.Parts, Part{InlineData: &InlineData{MimeType: block.MimeType, Data: block.Data}})
+			case models.BlockToolUse:
+				converted.Parts = append(converted.Parts, Part{FunctionCall: &FunctionCall{
+					ID:   functionID(block.ID),
+					Name: block.Name,
+					Args: block.Input,
+				}})
+			case models.BlockToolResult:
+				key := "result"
+				if block.IsError {
+					key = "error"
+				}
+				converted.Parts = append(converted.Parts, Part{FunctionResponse: &FunctionResponse{
+					ID:       functionID(block.ToolUseID),
+					Name:     toolName(conversation.Messages[:index], block.ToolUseID),
+					Response: map[string]any{key: block.Content},
+				}})
+			}
+		}
+		contents = append(contents, converted)
+	}
+
+	if len(system) == 0 {
+		return contents, nil
+	}
+
+	return contents, &Content{Parts: system}
+}
+
+// toolName finds the name of the function called by the tool_use block with the
+// given ID, searching the messages from the newest, as a result follows its call.
+// Gemini matches function responses to calls by name, so the name must be sent back.
+func toolName(messages []models.Message, id string) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		for _, block := range messages[i].Blocks {
+			if block.Type == models.BlockToolUse && block.ID == id {
+				return block.Name
+			}
+		}
+	}
+	return ""
+}
+
+// generatedIDPrefix marks tool call IDs made up by the client for calls Gemini did not identify
+const generatedIDPrefix = "gemini_call_"
+
+// functionID returns the ID to send to Gemini for a tool call, omitting IDs
+// the client generated itself.
+func functionID(id string) string {
+	if strings.HasPrefix(id, generatedIDPrefix) {
+		return ""
+	}
+	return id
+}
+
+// sendRequest sends a conversation to Gemini and processes the response.
+// If the model calls functions, every call is executed and the results are sent
+// back until the model produces a final answer. Every turn is appended to the
+// conversation. Generation options in the context apply to every request, see
+// models.GenerationOptions.FollowUp.
+func (c *Client) sendRequest(ctx context.Context, conversation *models.Conversation, withTools bool) (string, error) {
+	request := c.buildRequest(conversation, withTools)
+	opts := models.OptionsFrom(ctx)
+
+	for round := 0; ; round++ {
+		if err := models.CheckBudget(ctx, conversation.Messages); err != nil {
+			return "", err
+		}
+
+		if err := models.FitConversation(ctx, conversation); err != nil {
+			return "", err
+		}
+		request.Contents, request.SystemInstruction = c.convertMessages(conversation)
+
+		model, err := c.applyOptions(&request, opts)
+		if err != nil {
+			return "", err
+		}
+		opts = opts.FollowUp()
+
+		candidate, err := c.generateContent(ctx, model, request)
+		if err != nil {
+			return "", err
+		}
+
+		assistant := assistantMessage(candidate.Content, len(conversation.Messages))
+		conversation.Append(assistant)
+
+		calls := toolCalls(assistant)
+		if len(calls) == 0 {
+			return assistant.Text(), nil
+		}
+
+		if common.ToolRoundsExceeded(round, c.MaxToolRounds) {
+			return "", common.ErrMaxToolRounds
+		}
+
+		conversation.Append(c.runToolCalls(ctx, calls))
+	}
+}
+
+// generateContent sends a single request to the model's generateContent endpoint
+// and returns the first candidate.
+func (c *Client) generateContent(ctx context.Context, model string, request GenerateContentRequest) (Candidate, error) {
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return Candidate{}, err
+	}
+
+	body, err := c.DoHTTPRequest(ctx, common.HTTPRequest{
+		Method:  "POST",
+		URL:     c.endpoint(model),
+		Headers: c.headers(),
+		Body:    jsonData,
+	})
+	if err != nil {
+		return Candidate{}, err
+	}
+
+	var response GenerateContentResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return Candidate{}, err
+	}
+
+	if response.UsageMetadata != nil {
+		model := response.ModelVersion
+		if model == "" {
+			model = c.Model
+		}
+		models.ReportUsage(ctx, model, response.UsageMetadata.convert())
+	}
+
+	if response.PromptFeedback != nil && response.PromptFeedback.BlockReason != "" {
+		return Candidate{}, fmt.Errorf("Gemini blocked the prompt: %s", response.PromptFeedback.BlockReason)
+	}
+
+	if len(response.Candidates) == 0 {
+		return Candidate{}, fmt.Errorf("no response from Gemini")
+	}
+
+	candidate := response.Candidates[0]
+	if len(candidate.Content.Parts) == 0 && candidate.FinishReason != "STOP" {
+		return Candidate{}, fmt.Errorf("Gemini returned no content: %s", candidate.FinishReason)
+	}
+
+	return candidate, nil
+}
+
+// assistantMessage converts a candidate's content to a Bond message. Function calls
+// without an ID are given one derived from the index the message takes in the
+// conversation and their position.
+func assistantMessage(content Content, index int) models.Message {
+	message := models.Message{Role: models.RoleAssistant}
+
+	for i, part := range content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			id := part.FunctionCall.ID
+			if id == "" {
+				id = fmt.Sprintf("%s%d_%d", generatedIDPrefix, index, i)
+			}
+			args := part.FunctionCall.Args
+			if len(args) == 0 {
+				args = json.RawMessage("{}")
+			}
+			message.Blocks = append(message.Blocks, models.ToolUseBlock(id, part.FunctionCall.Name, args))
+		case part.Text != "":
+			message.Blocks = append(message.Blocks, models.TextBlock(part.Text))
+		}
+	}
+
+	// Plain answers are stored as text content like other providers
+	if len(toolCalls(message)) == 0 {
+		return models.Message{Role: models.RoleAssistant, Content: message.Text()}
+	}
+
+	return message
+}
+
+// toolCalls extracts the tool calls from the tool_use blocks of a message.
+func toolCalls(message models.Message) []common.ToolCall {
+	var calls []common.ToolCall
+	for _, block := range message.Blocks {
+		if block.Type == models.BlockToolUse {
+			calls = append(calls, common.ToolCall{ID: block.ID, Name: block.Name, Input: block.Input})
+		}
+	}
+	return calls
+}
+
+// runToolCalls executes the tool calls and returns a message carrying a
+// tool_result block for each of them.
+func (c *Client) runToolCalls(ctx context.Context, calls []common.ToolCall) models.Message {
+	results := models.Message{Role: models.RoleUser}
+	for _, result := range c.HandleToolCalls(ctx, calls) {
+		if result.Err != nil {
+			results.Blocks = append(results.Blocks, models.ToolResultBlock(result.ID, fmt.Sprintf("Error: %v", result.Err), true))
+			continue
+		}
+		results.Blocks = append(results.Blocks, models.ToolResultBlock(result.ID, result.Output, false))
+	}
+	return results
+}
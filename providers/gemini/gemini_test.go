@@ -0,0 +1,268 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devOpifex/bond/models"
+	"github.com/devOpifex/bond/tools"
+)
+
+// TestNewClient tests that the Gemini client is properly initialized
+func TestNewClient(t *testing.T) {
+	client := NewClient("test-api-key")
+
+	if client.ApiKey != "test-api-key" {
+		t.Errorf("Expected API key 'test-api-key', got '%s'", client.ApiKey)
+	}
+
+	if client.endpoint(client.Model) != "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:generateContent" {
+		t.Errorf("Unexpected endpoint '%s'", client.endpoint(client.Model))
+	}
+}
+
+// TestConvertSchema tests that unsupported JSON Schema keywords are dropped
+func TestConvertSchema(t *testing.T) {
+	closed := false
+	schema := models.InputSchema{
+		Type:                 "object",
+		AdditionalProperties: &closed,
+		Properties: map[string]models.Property{
+			"unit": {
+				Type:        "string",
+				Description: "Temperature unit",
+				Enum:        []any{"celsius", "fahrenheit"},
+			},
+			"days": {
+				Type:       "integer",
+				Format:     "uint8",
+				MultipleOf: new(float64),
+			},
+			"tags": {
+				Type:                 "array",
+				AdditionalProperties: &closed,
+				Items:                &models.Property{Type: "string", Format: "email"},
+			},
+		},
+		Required: []string{"unit"},
+	}
+
+	data, err := json.Marshal(convertSchema(schema))
+	if err != nil {
+		t.Fatalf("Failed to marshal schema: %v", err)
+	}
+
+	var converted map[string]any
+	json.Unmarshal(data, &converted)
+
+	if converted["type"] != "OBJECT" {
+		t.Errorf("Expected type 'OBJECT', got %v", converted["type"])
+	}
+
+	if _, ok := converted["additionalProperties"]; ok {
+		t.Errorf("Expected additionalProperties to be dropped")
+	}
+
+	properties := converted["properties"].(map[string]any)
+
+	unit := properties["unit"].(map[string]any)
+	if unit["format"] != "enum" || len(unit["enum"].([]any)) != 2 {
+		t.Errorf("Expected string enum, got %v", unit)
+	}
+
+	days := properties["days"].(map[string]any)
+	if _, ok := days["format"]; ok {
+		t.Errorf("Expected unsupported format to be dropped, got %v", days)
+	}
+	if _, ok := days["multipleOf"]; ok {
+		t.Errorf("Expected multipleOf to be dropped, got %v", days)
+	}
+
+	tags := properties["tags"].(map[string]any)
+	if tags["items"].(map[string]any)["type"] != "STRING" {
+		t.Errorf("Expected array items to be converted, got %v", tags)
+	}
+
+	if convertSchema(models.InputSchema{Type: "object"}) != nil {
+		t.Errorf("Expected schema without properties to be omitted")
+	}
+}
+
+// TestSendMessageWithTools tests the functionCall and functionResponse round trip
+func TestSendMessageWithTools(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if r.URL.Path != "/gemini-2.0-flash:generateContent" {
+			t.Errorf("Unexpected path '%s'", r.URL.Path)
+		}
+
+		if r.Header.Get("x-goog-api-key") != "test-api-key" {
+			t.Errorf("Expected x-goog-api-key header, got '%s'", r.Header.Get("x-goog-api-key"))
+		}
+
+		var request GenerateContentRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+			return
+		}
+
+		if request.SystemInstruction == nil || request.SystemInstruction.Parts[0].Text != "You are a weather bot." {
+			t.Errorf("Expected system instruction, got %+v", request.SystemInstruction)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			if len(request.Tools) != 1 || request.Tools[0].FunctionDeclarations[0].Name != "get_weather" {
+				t.Errorf("Expected get_weather declaration, got %+v", request.Tools)
+			}
+			w.Write([]byte(`{
+				"candidates": [{
+					"content": {"role": "model", "parts": [{"functionCall": {"name": "get_weather", "args": {"location": "Brussels"}}}]},
+					"finishReason": "STOP"
+				}]
+			}`))
+			return
+		}
+
+		if len(request.Contents) != 3 {
+			t.Errorf("Expected 3 contents in follow-up request, got %d", len(request.Contents))
+			return
+		}
+
+		call := request.Contents[1]
+		if call.Role != roleModel || call.Parts[0].FunctionCall == nil || call.Parts[0].FunctionCall.ID != "" {
+			t.Errorf("Expected model functionCall turn without generated ID, got %+v", call)
+		}
+
+		response := request.Contents[2].Parts[0].FunctionResponse
+		if response == nil || response.Name != "get_weather" || response.Response["result"] != "5°C" {
+			t.Errorf("Unexpected functionResponse: %+v", response)
+		}
+
+		w.Write([]byte(`{
+			"candidates": [{
+				"content": {"role": "model", "parts": [{"text": "It is 5°C in Brussels."}]},
+				"finishReason": "STOP"
+			}]
+		}`))
+	}))
+	defer server.Close()
+
+	var received map[string]any
+	client := NewClient("test-api-key")
+	client.BaseURL = server.URL
+	client.SetSystemPrompt("You are a weather bot.")
+	client.RegisterTool(tools.NewTool(
+		"get_weather",
+		"Get the weather",
+		models.InputSchema{
+			Type:       "object",
+			Properties: map[string]models.Property{"location": {Type: "string"}},
+		},
+		func(params map[string]any) (string, error) {
+			received = params
+			return "5°C", nil
+		},
+	))
+
+	response, err := client.SendMessageWithTools(context.Background(), models.Message{
+		Role:    models.RoleUser,
+		Content: "What's the weather in Brussels?",
+	})
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	if response != "It is 5°C in Brussels." {
+		t.Errorf("Expected final answer, got '%s'", response)
+	}
+
+	if received["location"] != "Brussels" {
+		t.Errorf("Expected tool to receive location 'Brussels', got %v", received["location"])
+	}
+}
+
+// TestSendConversationToolIDs tests that function calls made in later calls on
+// the same conversation get their own IDs and their responses the right name
+func TestSendConversationToolIDs(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		var request GenerateContentRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch requests {
+		case 1:
+			w.Write([]byte(`{"candidates": [{"content": {"role": "model", "parts": [{"functionCall": {"name": "get_weather"}}]}, "finishReason": "STOP"}]}`))
+		case 3:
+			w.Write([]byte(`{"candidates": [{"content": {"role": "model", "parts": [{"functionCall": {"name": "get_time"}}]}, "finishReason": "STOP"}]}`))
+		case 4:
+			response := request.Contents[len(request.Contents)-1].Parts[0].FunctionResponse
+			if response == nil || response.Name != "get_time" {
+				t.Errorf("Expected a get_time functionResponse, got %+v", response)
+			}
+			fallthrough
+		default:
+			w.Write([]byte(`{"candidates": [{"content": {"role": "model", "parts": [{"text": "Done."}]}, "finishReason": "STOP"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("test-api-key")
+	client.BaseURL = server.URL
+	for _, name := range []string{"get_weather", "get_time"} {
+		client.RegisterTool(tools.NewTool(name, "Get "+name, models.InputSchema{Type: "object"}, func(map[string]any) (string, error) {
+			return "ok", nil
+		}))
+	}
+
+	conversation := models.NewConversation("")
+	for _, question := range []string{"Weather?", "Time?"} {
+		conversation.Append(models.Message{Role: models.RoleUser, Content: question})
+		if _, err := client.SendConversation(context.Background(), conversation); err != nil {
+			t.Fatalf("Failed to send conversation: %v", err)
+		}
+	}
+
+	ids := map[string]bool{}
+	for _, msg := range conversation.Messages {
+		for _, block := range msg.Blocks {
+			if block.Type == models.BlockToolUse {
+				if ids[block.ID] {
+					t.Errorf("Expected unique function call IDs, got %s twice", block.ID)
+				}
+				ids[block.ID] = true
+			}
+		}
+	}
+	if len(ids) != 2 {
+		t.Errorf("Expected 2 function calls, got %d", len(ids))
+	}
+}
+
+// TestSendMessageBlocked tests that a blocked prompt is reported as an error
+func TestSendMessageBlocked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"promptFeedback": {"blockReason": "SAFETY"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-api-key")
+	client.BaseURL = server.URL
+
+	_, err := client.SendMessage(context.Background(), models.Message{Role: models.RoleUser, Content: "Hi"})
+	if err == nil {
+		t.Fatal("Expected an error for a blocked prompt")
+	}
+}
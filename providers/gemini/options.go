@@ -0,0 +1,77 @@
+package gemini
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// Function calling modes of Gemini's tool config
+const (
+	functionCallingAuto = "AUTO"
+	functionCallingAny  = "ANY"
+	functionCallingNone = "NONE"
+)
+
+// applyOptions applies the per-request generation options to the request,
+// rejecting those Gemini does not support. The model is part of the endpoint,
+// so it is returned rather than set on the request.
+func (c *Client) applyOptions(request *GenerateContentRequest, opts models.GenerationOptions) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", fmt.Errorf("invalid generation options: %w", err)
+	}
+
+	if opts.UserID != "" || len(opts.Metadata) > 0 {
+		return "", errors.New("Gemini does not support a user ID or request metadata")
+	}
+
+	model := c.Model
+	if opts.Model != "" {
+		model = opts.Model
+	}
+
+	config := request.GenerationConfig
+	if opts.MaxTokens > 0 {
+		config.MaxOutputTokens = opts.MaxTokens
+	}
+	if opts.Temperature != nil {
+		config.Temperature = *opts.Temperature
+	}
+	config.TopP = opts.TopP
+	config.TopK = opts.TopK
+	config.StopSequences = opts.StopSequences
+	config.Seed = opts.Seed
+
+	var offered []string
+	for _, tool := range request.Tools {
+		for _, declaration := range tool.FunctionDeclarations {
+			offered = append(offered, declaration.Name)
+		}
+	}
+	if err := opts.ValidateToolChoice(offered); err != nil {
+		return "", err
+	}
+
+	// Without tools there is nothing to choose from
+	request.ToolConfig = nil
+	if len(offered) == 0 {
+		return model, nil
+	}
+
+	switch opts.ToolChoice.Mode {
+	case models.ToolChoiceAuto:
+		request.ToolConfig = &ToolConfig{FunctionCallingConfig: FunctionCallingConfig{Mode: functionCallingAuto}}
+	case models.ToolChoiceNone:
+		request.ToolConfig = &ToolConfig{FunctionCallingConfig: FunctionCallingConfig{Mode: functionCallingNone}}
+	case models.ToolChoiceAny:
+		request.ToolConfig = &ToolConfig{FunctionCallingConfig: FunctionCallingConfig{Mode: functionCallingAny}}
+	case models.ToolChoiceTool:
+		request.ToolConfig = &ToolConfig{FunctionCallingConfig: FunctionCallingConfig{
+			Mode:                 functionCallingAny,
+			AllowedFunctionNames: []string{opts.ToolChoice.Name},
+		}}
+	}
+
+	return model, nil
+}
@@ -0,0 +1,133 @@
+package gemini
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// Schema is the subset of the OpenAPI schema object accepted by Gemini function
+// declarations. Gemini rejects requests containing JSON Schema keywords outside
+// this subset, such as additionalProperties, oneOf or allOf, so tool schemas are
+// converted field by field rather than passed through.
+type Schema struct {
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Nullable    bool               `json:"nullable,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	AnyOf       []*Schema          `json:"anyOf,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	MinItems    *int               `json:"minItems,omitempty"`
+	MaxItems    *int               `json:"maxItems,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	Default     any                `json:"default,omitempty"`
+}
+
+// supportedFormats lists the formats Gemini accepts for each type.
+// Other formats are dropped from the converted schema.
+var supportedFormats = map[string][]string{
+	"STRING":  {"enum", "date-time"},
+	"INTEGER": {"int32", "int64"},
+	"NUMBER":  {"float", "double"},
+}
+
+// convertSchema converts a tool's input schema to a Gemini function declaration schema.
+// Keywords Gemini does not understand are dropped, and oneOf is relaxed to anyOf.
+// It returns nil for tools without parameters, which Gemini requires to be omitted.
+func convertSchema(schema models.InputSchema) *Schema {
+	if len(schema.Properties) == 0 && len(schema.OneOf) == 0 && len(schema.AnyOf) == 0 {
+		return nil
+	}
+
+	converted := &Schema{
+		Type:     geminiType(schema.Type),
+		Required: schema.Required,
+	}
+
+	if converted.Type == "" {
+		converted.Type = "OBJECT"
+	}
+
+	if len(schema.Properties) > 0 {
+		converted.Properties = make(map[string]*Schema, len(schema.Properties))
+		for name, prop := range schema.Properties {
+			converted.Properties[name] = convertProperty(prop)
+		}
+	}
+
+	for _, alternative := range append(schema.OneOf, schema.AnyOf...) {
+		if alt := convertSchema(alternative); alt != nil {
+			converted.AnyOf = append(converted.AnyOf, alt)
+		}
+	}
+
+	return converted
+}
+
+// convertProperty converts a single property, recursing into nested objects and array items.
+func convertProperty(prop models.Property) *Schema {
+	converted := &Schema{
+		Type:        geminiType(prop.Type),
+		Description: prop.Description,
+		Required:    prop.Required,
+		Minimum:     prop.Minimum,
+		Maximum:     prop.Maximum,
+		MinItems:    prop.MinItems,
+		MaxItems:    prop.MaxItems,
+		MinLength:   prop.MinLength,
+		MaxLength:   prop.MaxLength,
+		Pattern:     prop.Pattern,
+		Default:     prop.Default,
+	}
+
+	for _, format := range supportedFormats[converted.Type] {
+		if prop.Format == format {
+			converted.Format = format
+		}
+	}
+
+	// Gemini only accepts enums of strings
+	for _, value := range prop.Enum {
+		converted.Enum = append(converted.Enum, fmt.Sprint(value))
+	}
+	if len(converted.Enum) > 0 {
+		converted.Type = "STRING"
+		converted.Format = "enum"
+	}
+
+	if len(prop.Properties) > 0 {
+		converted.Properties = make(map[string]*Schema, len(prop.Properties))
+		for name, nested := range prop.Properties {
+			converted.Properties[name] = convertProperty(nested)
+		}
+	}
+
+	if prop.Items != nil {
+		converted.Items = convertProperty(*prop.Items)
+	}
+
+	for _, alternative := range append(prop.OneOf, prop.AnyOf...) {
+		converted.AnyOf = append(converted.AnyOf, convertProperty(alternative))
+	}
+
+	return converted
+}
+
+// geminiType maps a JSON Schema type to the upper case type names Gemini uses.
+// Types Gemini does not support, such as "null", are left empty and omitted.
+func geminiType(jsonType string) string {
+	switch strings.ToLower(jsonType) {
+	case "string", "number", "integer", "boolean", "array", "object":
+		return strings.ToUpper(jsonType)
+	default:
+		return ""
+	}
+}
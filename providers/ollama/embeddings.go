@@ -0,0 +1,105 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/devOpifex/bond/models"
+	"github.com/devOpifex/bond/providers/common"
+)
+
+// defaultEmbeddingBatchSize bounds the texts per request, so that large inputs
+// do not make a single request outlive its context
+const defaultEmbeddingBatchSize = 256
+
+// Client must satisfy the models.Embedder interface
+var _ models.Embedder = (*Client)(nil)
+
+// EmbedRequest represents a request to Ollama's /api/embed endpoint
+type EmbedRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+// EmbedResponse represents a response from Ollama's /api/embed endpoint
+type EmbedResponse struct {
+	Model           string      `json:"model"`
+	Embeddings      [][]float32 `json:"embeddings"`
+	PromptEvalCount int         `json:"prompt_eval_count,omitempty"`
+	Error           string      `json:"error,omitempty"`
+}
+
+// Embed returns the embeddings of the texts, computed by the model in
+// Embedding.Model at most Embedding.BatchSize texts per request. Texts longer
+// than the model's context are truncated by Ollama. Usage is reported for
+// every request.
+// This implements the models.Embedder interface.
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return common.EmbedBatches(ctx, texts, c.Embedding.BatchSize, c.embedBatch)
+}
+
+// Dimensions returns Embedding.Dimensions if set, otherwise the length of the
+// vectors last returned by Embed, or 0 before the first call.
+// This implements the models.Embedder interface.
+func (c *Client) Dimensions() int {
+	if c.Embedding.Dimensions > 0 {
+		return c.Embedding.Dimensions
+	}
+	return int(c.embeddingDimensions.Load())
+}
+
+// EmbeddingModel returns the name of the embedding model.
+// This implements the models.Embedder interface.
+func (c *Client) EmbeddingModel() string {
+	return c.Embedding.Model
+}
+
+// embedBatch sends a single request to /api/embed
+func (c *Client) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(EmbedRequest{
+		Model:      c.Embedding.Model,
+		Input:      texts,
+		Dimensions: c.Embedding.Dimensions,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.DoHTTPRequest(ctx, common.HTTPRequest{
+		Method:  "POST",
+		URL:     c.embedURL(),
+		Headers: c.headers(),
+		Body:    jsonData,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var response EmbedResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Error != "" {
+		return nil, fmt.Errorf("Ollama error: %s", response.Error)
+	}
+
+	model := response.Model
+	if model == "" {
+		model = c.Embedding.Model
+	}
+	models.ReportUsage(ctx, model, models.Usage{InputTokens: response.PromptEvalCount})
+
+	if len(response.Embeddings) > 0 {
+		c.embeddingDimensions.Store(int64(len(response.Embeddings[0])))
+	}
+	return response.Embeddings, nil
+}
+
+// embedURL derives the /api/embed endpoint from the /api/chat endpoint in BaseURL
+func (c *Client) embedURL() string {
+	return strings.TrimSuffix(c.BaseURL, "/api/chat") + "/api/embed"
+}
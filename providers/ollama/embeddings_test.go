@@ -0,0 +1,104 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// TestEmbed tests that texts are embedded in batches with usage reported
+func TestEmbed(t *testing.T) {
+	var batches [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("Expected request to /api/embed, got %s", r.URL.Path)
+		}
+
+		var request EmbedRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		batches = append(batches, request.Input)
+
+		response := EmbedResponse{Model: request.Model, PromptEvalCount: 4 * len(request.Input)}
+		for _, text := range request.Input {
+			response.Embeddings = append(response.Embeddings, []float32{float32(len(text)), 0, 1})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.BaseURL = server.URL + "/api/chat"
+	client.Embedding.BatchSize = 2
+
+	if client.Dimensions() != 0 {
+		t.Errorf("Expected unknown dimensions before the first call, got %d", client.Dimensions())
+	}
+
+	tracker := models.NewUsageTracker()
+	ctx := models.WithUsageTracker(context.Background(), tracker)
+
+	vectors, err := client.Embed(ctx, []string{"a", "bb", "ccc"})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 {
+		t.Errorf("Expected batches of 2 and 1 texts, got %v", batches)
+	}
+	for i, vector := range vectors {
+		if vector[0] != float32(i+1) {
+			t.Errorf("Expected vector %d to embed text %d, got %v", i, i, vector)
+		}
+	}
+	if usage := tracker.ByModel()["nomic-embed-text"]; usage.InputTokens != 12 {
+		t.Errorf("Expected 12 input tokens over both requests, got %+v", usage)
+	}
+	if client.Dimensions() != 3 {
+		t.Errorf("Expected 3 dimensions after the first call, got %d", client.Dimensions())
+	}
+}
+
+// TestEmbedErrors tests that failed requests are retried, that missing vectors
+// are reported and that the budget is checked
+func TestEmbedErrors(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			w.Header().Set("retry-after-ms", "5")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": "server busy"}`))
+			return
+		}
+		w.Write([]byte(`{"model": "nomic-embed-text", "embeddings": [[0.1, 0.2]]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.BaseURL = server.URL
+
+	_, err := client.Embed(context.Background(), []string{"one", "two"})
+	if err == nil || err.Error() != "expected 2 embeddings, got 1" {
+		t.Errorf("Expected a missing embedding error, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected the request to be retried once, got %d requests", requests)
+	}
+
+	budget := &models.Budget{MaxToolCalls: 1}
+	budget.StartToolCall()
+	_, err = client.Embed(models.WithBudget(context.Background(), budget), []string{"one"})
+	var exceeded *models.BudgetExceededError
+	if !errors.As(err, &exceeded) || requests != 2 {
+		t.Errorf("Expected the budget to stop the request, got %v", err)
+	}
+}
@@ -0,0 +1,602 @@
+// Package ollama implements the Provider interface for models served locally by Ollama.
+// It communicates with Ollama's /api/chat endpoint, including tool calling,
+// streaming responses and Ollama-specific model options, and computes
+// embeddings with its /api/embed endpoint.
+package ollama
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/devOpifex/bond/models"
+	"github.com/devOpifex/bond/providers/common"
+)
+
+// roleTool is the role Ollama uses for messages carrying tool results
+const roleTool = "tool"
+
+// ChatRequest represents a request to Ollama's /api/chat endpoint
+type ChatRequest struct {
+	Model    string        `json:"model"`
+	Messages []ChatMessage `json:"messages"`
+	Tools    []Tool        `json:"tools,omitempty"`
+	Stream   bool          `json:"stream"`
+	Options  Options       `json:"options"`
+}
+
+// ChatMessage represents a message in Ollama format
+type ChatMessage struct {
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	ToolName  string     `json:"tool_name,omitempty"`
+	Images    []string   `json:"images,omitempty"`
+}
+
+// Tool represents a tool definition in Ollama format
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a function that the model can call
+type ToolFunction struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Parameters  models.InputSchema `json:"parameters"`
+}
+
+// ToolCall represents a tool call made by the model.
+// Unlike other providers, Ollama sends the arguments as a JSON object
+// and does not assign IDs to tool calls.
+type ToolCall struct {
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction holds the name and arguments of a tool call
+type ToolCallFunction struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ChatResponse represents a response, or a chunk of a streamed response, from /api/chat
+type ChatResponse struct {
+	Model           string      `json:"model"`
+	CreatedAt       string      `json:"created_at"`
+	Message         ChatMessage `json:"message"`
+	Done            bool        `json:"done"`
+	DoneReason      string      `json:"done_reason,omitempty"`
+	PromptEvalCount int         `json:"prompt_eval_count,omitempty"`
+	EvalCount       int         `json:"eval_count,omitempty"`
+	Error           string      `json:"error,omitempty"`
+}
+
+// Options holds the model parameters Ollama accepts with each request
+type Options struct {
+	// Temperature controls randomness in the model's output
+	Temperature float64 `json:"temperature"`
+
+	// NumPredict limits the number of tokens the model generates
+	NumPredict int `json:"num_predict,omitempty"`
+
+	// NumCtx sets the size of the context window in tokens
+	NumCtx int `json:"num_ctx,omitempty"`
+
+	// Seed makes generation reproducible when set
+	Seed *int `json:"seed,omitempty"`
+
+	// TopK limits sampling to the K most likely tokens
+	TopK int `json:"top_k,omitempty"`
+
+	// TopP limits sampling to the most likely tokens whose probabilities add up to TopP
+	TopP *float64 `json:"top_p,omitempty"`
+
+	// Stop ends the generation when the model produces one of the sequences
+	Stop []string `json:"stop,omitempty"`
+}
+
+// Client is the Ollama API client implementation.
+// It handles communication with a local or remote Ollama server and
+// implements the models.Provider interface.
+type Client struct {
+	common.BaseClient
+
+	// Options holds Ollama-specific model parameters sent with every request.
+	// Temperature and NumPredict are taken from the base client settings.
+	Options Options
+
+	// Embedding configures the embeddings computed by Embed.
+	Embedding common.EmbeddingConfig
+
+	// embeddingDimensions is the length of the last vectors returned by Embed
+	embeddingDimensions atomic.Int64
+}
+
+// Client must satisfy the models.Provider interface
+var _ models.Provider = (*Client)(nil)
+
+// NewClient creates a new Ollama client for a server running on localhost.
+// Ollama does not require an API key, but one can be set on the client when
+// the server sits behind an authenticating proxy.
+func NewClient() *Client {
+	baseClient := common.NewBaseClient(
+		"",
+		"http://localhost:11434/api/chat",
+		"llama3.1",
+	)
+
+	baseClient.Provider = "Ollama"
+
+	// Local models can take a while to load and respond
+	baseClient.HttpClient.Timeout = 0
+
+	return &Client{
+		BaseClient: baseClient,
+		Embedding: common.EmbeddingConfig{
+			Model:     "nomic-embed-text",
+			BatchSize: defaultEmbeddingBatchSize,
+		},
+	}
+}
+
+// SetNumCtx configures the size of the context window in tokens.
+func (c *Client) SetNumCtx(tokens int) {
+	c.Options.NumCtx = tokens
+}
+
+// SetSeed configures the random seed used for generation, making
+// responses reproducible for the same prompt.
+func (c *Client) SetSeed(seed int) {
+	c.Options.Seed = &seed
+}
+
+// SetTopK configures top-k sampling, limiting the model to the K most likely tokens.
+func (c *Client) SetTopK(k int) {
+	c.Options.TopK = k
+}
+
+// SendMessage sends a message to Ollama and returns the model's response.
+// This method does not include tool information in the request.
+func (c *Client) SendMessage(ctx context.Context, message models.Message) (string, error) {
+	conversation := models.NewConversation("").Append(message)
+	return c.sendRequest(ctx, conversation, false)
+}
+
+// SendMessageWithTools sends a message to Ollama with registered tools.
+// Tool calls are executed and their results sent back until the model answers.
+func (c *Client) SendMessageWithTools(ctx context.Context, message models.Message) (string, error) {
+	conversation := models.NewConversation("").Append(message)
+	return c.sendRequest(ctx, conversation, true)
+}
+
+// SendConversation sends the whole conversation to Ollama with registered tools.
+// The model's turns, including tool calls and their results, are appended to
+// the conversation.
+func (c *Client) SendConversation(ctx context.Context, conversation *models.Conversation) (string, error) {
+	return c.sendRequest(ctx, conversation, true)
+}
+
+// buildRequest creates the chat request, without the messages, which are
+// converted before every request of the tool loop. Registered tools are
+// included when withTools is set.
+func (c *Client) buildRequest(withTools bool) ChatRequest {
+	options := c.Options
+	options.Temperature = c.Temperature
+	options.NumPredict = c.MaxTokens
+
+	request := ChatRequest{
+		Model:   c.Model,
+		Options: options,
+	}
+
+	if !withTools {
+		return request
+	}
+
+	for _, tool := range c.Tools {
+		request.Tools = append(request.Tools, Tool{
+			Type: "function",
+			Function: ToolFunction{
+				Name:        tool.GetName(),
+				Description: tool.GetDescription(),
+				Parameters:  tool.GetSchema(),
+			},
+		})
+	}
+
+	return request
+}
+
+// convertMessages transforms a conversation to Ollama's message format.
+// Tool calls become assistant tool_calls and tool results become tool messages.
+// Text documents are added to the message's content; other documents, such as
+// PDFs, are not supported.
+func (c *Client) convertMessages(conversation *models.Conversation) ([]ChatMessage, error) {
+	var messages []ChatMessage
+
+	// Add system prompt if set, preferring the conversation's own
+	systemPrompt := c.SystemPrompt
+	if conversation.SystemPrompt != "" {
+		systemPrompt = conversation.SystemPrompt
+	}
+	if systemPrompt != "" {
+		messages = append(messages, ChatMessage{
+			Role:    models.RoleSystem,
+			Content: systemPrompt,
+		})
+	}
+
+	for index, msg := range conversation.Messages {
+		if msg.Role == models.RoleFunction {
+			name := ""
+			content := fmt.Sprintf("Function returned: %s", msg.Content)
+			if msg.ToolResult != nil {
+				name = msg.ToolResult.Name
+				content = msg.Content
+			}
+			messages = append(messages, ChatMessage{Role: roleTool, Content: content, ToolName: name})
+			continue
+		}
+
+		if len(msg.Blocks) == 0 {
+			messages = append(messages, ChatMessage{Role: msg.Role, Content: msg.Content})
+			continue
+		}
+
+		converted := ChatMessage{Role: msg.Role}
+		var toolMessages []ChatMessage
+		for _, block := range msg.Blocks {
+			switch block.Type {
+			case models.BlockText:
+				converted.Content += block.Text
+			case models.BlockImage:
+				// Vision models take base64 images alongside the text
+				converted.Images = append(converted.Images, block.Data)
+			case models.BlockDocument:
+				text, ok := block.DocumentText()
+				if !ok {
+					return nil, fmt.Errorf("Ollama does not support %s documents", block.MimeType)
+				}
+				if converted.Content != "" {
+					converted.Content += "\n\n"
+				}
+				if block.Name != "" {
+					converted.Content += block.Name + ":\n"
+				}
+				converted.Content += text
+			case models.BlockToolUse:
+				converted.ToolCalls = append(converted.ToolCalls, ToolCall{
+					Function: ToolCallFunction{Name: block.Name, Arguments: block.Input},
+				})
+			case models.BlockToolResult:
+				toolMessages = append(toolMessages, ChatMessage{
+					Role:     roleTool,
+					Content:  block.Content,
+					ToolName: toolName(conversation.Messages[:index], block.ToolUseID),
+				})
+			}
+		}
+
+		// Tool messages must directly follow the assistant message that made the calls
+		messages = append(messages, toolMessages...)
+		if converted.Content != "" || len(converted.ToolCalls) > 0 || len(converted.Images) > 0 {
+			messages = append(messages, converted)
+		}
+	}
+
+	return messages, nil
+}
+
+// toolName finds the name of the tool called by the tool_use block with the
+// given ID, searching the messages from the newest, as a result follows its call.
+func toolName(messages []models.Message, id string) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		for _, block := range messages[i].Blocks {
+			if block.Type == models.BlockToolUse && block.ID == id {
+				return block.Name
+			}
+		}
+	}
+	return ""
+}
+
+// headers returns the HTTP headers for requests to the Ollama server.
+func (c *Client) headers() map[string]string {
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+	if c.ApiKey != "" {
+		headers["Authorization"] = "Bearer " + c.ApiKey
+	}
+	return headers
+}
+
+// sendRequest sends a conversation to Ollama and processes the response.
+// If the model requests tools, every call is executed and the results are sent
+// back until the model produces a final answer. Every turn is appended to the
+// conversation. Generation options in the context apply to every request.
+func (c *Client) sendRequest(ctx context.Context, conversation *models.Conversation, withTools bool) (string, error) {
+	request := c.buildRequest(withTools)
+	if err := c.applyOptions(&request, models.OptionsFrom(ctx)); err != nil {
+		return "", err
+	}
+
+	for round := 0; ; round++ {
+		if err := models.CheckBudget(ctx, conversation.Messages); err != nil {
+			return "", err
+		}
+
+		if err := models.FitConversation(ctx, conversation); err != nil {
+			return "", err
+		}
+
+		messages, err := c.convertMessages(conversation)
+		if err != nil {
+			return "", err
+		}
+		request.Messages = messages
+
+		jsonData, err := json.Marshal(request)
+		if err != nil {
+			return "", err
+		}
+
+		body, err := c.DoHTTPRequest(ctx, common.HTTPRequest{
+			Method:  "POST",
+			URL:     c.BaseURL,
+			Headers: c.headers(),
+			Body:    jsonData,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		var chatResp ChatResponse
+		if err := json.Unmarshal(body, &chatResp); err != nil {
+			return "", err
+		}
+
+		if chatResp.Error != "" {
+			return "", fmt.Errorf("Ollama error: %s", chatResp.Error)
+		}
+		c.reportUsage(ctx, chatResp)
+
+		assistant := assistantMessage(chatResp.Message, len(conversation.Messages))
+		conversation.Append(assistant)
+
+		// Without tool calls the model has produced its answer
+		if len(chatResp.Message.ToolCalls) == 0 {
+			return chatResp.Message.Content, nil
+		}
+
+		if common.ToolRoundsExceeded(round, c.MaxToolRounds) {
+			return "", common.ErrMaxToolRounds
+		}
+
+		conversation.Append(c.runToolCalls(ctx, assistant))
+	}
+}
+
+// reportUsage reports the usage of a completed response to the usage tracker in the context.
+func (c *Client) reportUsage(ctx context.Context, response ChatResponse) {
+	model := response.Model
+	if model == "" {
+		model = c.Model
+	}
+	models.ReportUsage(ctx, model, models.Usage{
+		InputTokens:  response.PromptEvalCount,
+		OutputTokens: response.EvalCount,
+	})
+}
+
+// runToolCalls executes every tool_use block of the assistant message and returns
+// a message carrying a tool_result block for each of them.
+func (c *Client) runToolCalls(ctx context.Context, assistant models.Message) models.Message {
+	var calls []common.ToolCall
+	for _, block := range assistant.Blocks {
+		if block.Type == models.BlockToolUse {
+			calls = append(calls, common.ToolCall{ID: block.ID, Name: block.Name, Input: block.Input})
+		}
+	}
+
+	results := models.Message{Role: models.RoleUser}
+	for _, result := range c.HandleToolCalls(ctx, calls) {
+		if result.Err != nil {
+			results.Blocks = append(results.Blocks, models.ToolResultBlock(result.ID, fmt.Sprintf("Error: %v", result.Err), true))
+			continue
+		}
+		results.Blocks = append(results.Blocks, models.ToolResultBlock(result.ID, result.Output, false))
+	}
+
+	return results
+}
+
+// assistantMessage converts a response message to a Bond message. Ollama does not
+// identify tool calls, so IDs are generated from the index the message takes in
+// the conversation and the position of each call.
+func assistantMessage(message ChatMessage, index int) models.Message {
+	if len(message.ToolCalls) == 0 {
+		return models.Message{Role: models.RoleAssistant, Content: message.Content}
+	}
+
+	converted := models.Message{Role: models.RoleAssistant}
+	if message.Content != "" {
+		converted.Blocks = append(converted.Blocks, models.TextBlock(message.Content))
+	}
+
+	for i, toolCall := range message.ToolCalls {
+		input := toolCall.Function.Arguments
+		if len(input) == 0 {
+			input = json.RawMessage("{}")
+		}
+		id := fmt.Sprintf("call_%d_%d", index, i)
+		converted.Blocks = append(converted.Blocks, models.ToolUseBlock(id, toolCall.Function.Name, input))
+	}
+
+	return converted
+}
+
+// SendMessageStream sends a message to Ollama with registered tools and streams the response.
+// Ollama streams newline-delimited JSON objects, which are translated to the same
+// events as other providers. Tool calls are executed and the conversation continues
+// on the same channel. This implements the models.Streamer interface.
+func (c *Client) SendMessageStream(ctx context.Context, message models.Message) (<-chan models.StreamEvent, error) {
+	conversation := models.NewConversation("").Append(message)
+	request := c.buildRequest(true)
+	if err := c.applyOptions(&request, models.OptionsFrom(ctx)); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.openStream(ctx, conversation, request)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan models.StreamEvent)
+	go func() {
+		defer close(events)
+		c.streamResponses(ctx, conversation, request, resp, events)
+	}()
+
+	return events, nil
+}
+
+// openStream sends a streaming chat request to the Ollama server with the
+// messages of the conversation, unless the budget in the context is spent.
+// The conversation is fitted first, see models.FitConversation.
+func (c *Client) openStream(ctx context.Context, conversation *models.Conversation, request ChatRequest) (*http.Response, error) {
+	if err := models.CheckBudget(ctx, conversation.Messages); err != nil {
+		return nil, err
+	}
+
+	if err := models.FitConversation(ctx, conversation); err != nil {
+		return nil, err
+	}
+
+	messages, err := c.convertMessages(conversation)
+	if err != nil {
+		return nil, err
+	}
+	request.Messages = messages
+
+	request.Stream = true
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.DoStreamRequest(ctx, common.HTTPRequest{
+		Method:  "POST",
+		URL:     c.BaseURL,
+		Headers: c.headers(),
+		Body:    jsonData,
+	})
+}
+
+// streamResponses forwards the events of a streamed response to the events channel,
+// running the tool loop whenever the model calls tools.
+func (c *Client) streamResponses(ctx context.Context, conversation *models.Conversation, request ChatRequest, resp *http.Response, events chan<- models.StreamEvent) {
+	for round := 0; ; round++ {
+		message, doneReason, err := c.readStream(ctx, resp.Body, events, len(conversation.Messages))
+		resp.Body.Close()
+		if err != nil {
+			common.SendStreamEvent(ctx, events, models.StreamEvent{Type: models.StreamError, Err: err})
+			return
+		}
+
+		assistant := assistantMessage(message, len(conversation.Messages))
+		conversation.Append(assistant)
+
+		if len(message.ToolCalls) == 0 {
+			common.SendStreamEvent(ctx, events, models.StreamEvent{Type: models.StreamStop, StopReason: doneReason})
+			return
+		}
+
+		if common.ToolRoundsExceeded(round, c.MaxToolRounds) {
+			common.SendStreamEvent(ctx, events, models.StreamEvent{Type: models.StreamError, Err: common.ErrMaxToolRounds})
+			return
+		}
+
+		conversation.Append(c.runToolCalls(ctx, assistant))
+
+		resp, err = c.openStream(ctx, conversation, request)
+		if err != nil {
+			common.SendStreamEvent(ctx, events, models.StreamEvent{Type: models.StreamError, Err: err})
+			return
+		}
+	}
+}
+
+// readStream parses the newline-delimited JSON chunks of a streamed response,
+// forwarding text and tool calls as they arrive. It returns the complete
+// assistant message and the reason the model stopped. The usage reported in
+// the final chunk is sent to the context's usage tracker. A stream ending
+// without a done chunk fails with an error wrapping io.ErrUnexpectedEOF.
+func (c *Client) readStream(ctx context.Context, body io.Reader, events chan<- models.StreamEvent, index int) (ChatMessage, string, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+
+	message := ChatMessage{Role: models.RoleAssistant}
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk ChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return ChatMessage{}, "", fmt.Errorf("failed to parse Ollama stream chunk: %w", err)
+		}
+
+		if chunk.Error != "" {
+			return ChatMessage{}, "", fmt.Errorf("Ollama error: %s", chunk.Error)
+		}
+
+		if chunk.Message.Content != "" {
+			message.Content += chunk.Message.Content
+			if !common.SendStreamEvent(ctx, events, models.StreamEvent{Type: models.StreamText, Text: chunk.Message.Content}) {
+				return ChatMessage{}, "", ctx.Err()
+			}
+		}
+
+		// Ollama sends each tool call whole rather than in fragments
+		for _, toolCall := range chunk.Message.ToolCalls {
+			id := fmt.Sprintf("call_%d_%d", index, len(message.ToolCalls))
+			message.ToolCalls = append(message.ToolCalls, toolCall)
+
+			if !common.SendStreamEvent(ctx, events, models.StreamEvent{
+				Type:     models.StreamToolUse,
+				ToolID:   id,
+				ToolName: toolCall.Function.Name,
+			}) {
+				return ChatMessage{}, "", ctx.Err()
+			}
+
+			if !common.SendStreamEvent(ctx, events, models.StreamEvent{
+				Type:         models.StreamToolInput,
+				ToolID:       id,
+				PartialInput: string(toolCall.Function.Arguments),
+			}) {
+				return ChatMessage{}, "", ctx.Err()
+			}
+		}
+
+		if chunk.Done {
+			c.reportUsage(ctx, chunk)
+			return message, chunk.DoneReason, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return ChatMessage{}, "", fmt.Errorf("failed to read Ollama stream: %w", err)
+	}
+
+	return ChatMessage{}, "", common.NewTruncatedStreamError("Ollama")
+}
@@ -0,0 +1,414 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/devOpifex/bond/models"
+	"github.com/devOpifex/bond/providers/common"
+	"github.com/devOpifex/bond/tools"
+)
+
+// Recorded NDJSON chunks for a plain text response
+const textStream = `{"model":"llama3.1","created_at":"2024-07-22T20:33:28Z","message":{"role":"assistant","content":"It is 5°C"},"done":false}
+{"model":"llama3.1","created_at":"2024-07-22T20:33:28Z","message":{"role":"assistant","content":" in Brussels."},"done":false}
+{"model":"llama3.1","created_at":"2024-07-22T20:33:28Z","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":26,"eval_count":9}
+`
+
+// Recorded NDJSON chunks for a response that calls a tool
+const toolStream = `{"model":"llama3.1","created_at":"2024-07-22T20:33:28Z","message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_weather","arguments":{"location":"Brussels"}}}]},"done":false}
+{"model":"llama3.1","created_at":"2024-07-22T20:33:28Z","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop"}
+`
+
+// weatherTool returns a tool that records the parameters it was called with
+func weatherTool(received *map[string]any) models.ToolExecutor {
+	return tools.NewTool(
+		"get_weather",
+		"Get the weather",
+		models.InputSchema{Type: "object"},
+		func(params map[string]any) (string, error) {
+			*received = params
+			return "5°C", nil
+		},
+	)
+}
+
+// TestNewClient tests that the Ollama client is properly initialized
+func TestNewClient(t *testing.T) {
+	client := NewClient()
+
+	if client.BaseURL != "http://localhost:11434/api/chat" {
+		t.Errorf("Expected base URL 'http://localhost:11434/api/chat', got '%s'", client.BaseURL)
+	}
+
+	if client.Model != "llama3.1" {
+		t.Errorf("Expected default model 'llama3.1', got '%s'", client.Model)
+	}
+
+	if client.ApiKey != "" {
+		t.Errorf("Expected no API key, got '%s'", client.ApiKey)
+	}
+}
+
+// TestSendMessageOptions tests that model options are sent with the request
+func TestSendMessageOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Expected path '/api/chat', got '%s'", r.URL.Path)
+		}
+
+		var request ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+			return
+		}
+
+		if request.Stream {
+			t.Errorf("Expected stream to be false")
+		}
+
+		if len(request.Tools) != 0 {
+			t.Errorf("Expected no tools, got %d", len(request.Tools))
+		}
+
+		if request.Options.NumCtx != 8192 || request.Options.TopK != 20 {
+			t.Errorf("Unexpected options: %+v", request.Options)
+		}
+
+		if request.Options.Seed == nil || *request.Options.Seed != 42 {
+			t.Errorf("Expected seed 42, got %v", request.Options.Seed)
+		}
+
+		if request.Options.Temperature != 0.2 {
+			t.Errorf("Expected temperature 0.2, got %f", request.Options.Temperature)
+		}
+
+		if len(request.Messages) != 2 || request.Messages[0].Role != models.RoleSystem {
+			t.Errorf("Expected system and user messages, got %+v", request.Messages)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","content":"Hello!"},"done":true,"done_reason":"stop"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.BaseURL = server.URL + "/api/chat"
+	client.SetSystemPrompt("You are terse.")
+	client.SetTemperature(0.2)
+	client.SetNumCtx(8192)
+	client.SetSeed(42)
+	client.SetTopK(20)
+
+	response, err := client.SendMessage(context.Background(), models.Message{
+		Role:    models.RoleUser,
+		Content: "Hi",
+	})
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	if response != "Hello!" {
+		t.Errorf("Expected response 'Hello!', got '%s'", response)
+	}
+}
+
+// TestSendMessageDocuments tests that text documents are sent in the content and other documents are rejected
+func TestSendMessageDocuments(t *testing.T) {
+	var received []ChatMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		received = request.Messages
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","content":"Done."},"done":true,"done_reason":"stop"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.BaseURL = server.URL
+
+	_, err := client.SendMessage(context.Background(), models.Message{
+		Role: models.RoleUser,
+		Blocks: []models.ContentBlock{
+			models.TextBlock("Summarise this."),
+			models.DocumentBlock("text/plain", "SGVsbG8=", "notes.txt"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	if len(received) != 1 || received[0].Content != "Summarise this.\n\nnotes.txt:\nHello" {
+		t.Errorf("Expected the document in the content, got %+v", received)
+	}
+
+	_, err = client.SendMessage(context.Background(), models.Message{
+		Role:   models.RoleUser,
+		Blocks: []models.ContentBlock{models.DocumentBlock("application/pdf", "JVBERi0xLjc=", "report.pdf")},
+	})
+	if err == nil || !strings.Contains(err.Error(), "application/pdf") {
+		t.Errorf("Expected PDF documents to be rejected, got %v", err)
+	}
+}
+
+// TestSendConversationToolLoop tests that tool calls are executed and sent back as tool messages
+func TestSendConversationToolLoop(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		var request ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			if len(request.Tools) != 1 || request.Tools[0].Function.Name != "get_weather" {
+				t.Errorf("Expected get_weather tool, got %+v", request.Tools)
+			}
+			w.Write([]byte(`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_weather","arguments":{"location":"Brussels"}}}]},"done":true}`))
+			return
+		}
+
+		if len(request.Messages) != 3 {
+			t.Errorf("Expected 3 messages in follow-up request, got %d", len(request.Messages))
+			return
+		}
+
+		if len(request.Messages[1].ToolCalls) != 1 {
+			t.Errorf("Expected assistant tool call to be preserved, got %+v", request.Messages[1])
+		}
+
+		result := request.Messages[2]
+		if result.Role != roleTool || result.Content != "5°C" || result.ToolName != "get_weather" {
+			t.Errorf("Unexpected tool message: %+v", result)
+		}
+
+		w.Write([]byte(`{"message":{"role":"assistant","content":"It is 5°C in Brussels."},"done":true,"done_reason":"stop"}`))
+	}))
+	defer server.Close()
+
+	var received map[string]any
+	client := NewClient()
+	client.BaseURL = server.URL
+	client.RegisterTool(weatherTool(&received))
+
+	conversation := models.NewConversation("").Append(models.Message{
+		Role:    models.RoleUser,
+		Content: "What's the weather in Brussels?",
+	})
+
+	response, err := client.SendConversation(context.Background(), conversation)
+	if err != nil {
+		t.Fatalf("Failed to send conversation: %v", err)
+	}
+
+	if response != "It is 5°C in Brussels." {
+		t.Errorf("Expected final answer, got '%s'", response)
+	}
+
+	if received["location"] != "Brussels" {
+		t.Errorf("Expected tool to receive location 'Brussels', got %v", received["location"])
+	}
+
+	// user, assistant tool call, tool result, final answer
+	if conversation.Len() != 4 {
+		t.Errorf("Expected 4 messages in conversation, got %d", conversation.Len())
+	}
+}
+
+// TestSendConversationToolIDs tests that tool calls made in later calls on the
+// same conversation get their own IDs and their results the right tool name
+func TestSendConversationToolIDs(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		var request ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch requests {
+		case 1:
+			w.Write([]byte(`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_weather","arguments":{}}}]},"done":true}`))
+		case 3:
+			w.Write([]byte(`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_time","arguments":{}}}]},"done":true}`))
+		case 4:
+			result := request.Messages[len(request.Messages)-1]
+			if result.Role != roleTool || result.ToolName != "get_time" {
+				t.Errorf("Expected a get_time tool message, got %+v", result)
+			}
+			fallthrough
+		default:
+			w.Write([]byte(`{"message":{"role":"assistant","content":"Done."},"done":true,"done_reason":"stop"}`))
+		}
+	}))
+	defer server.Close()
+
+	var received map[string]any
+	client := NewClient()
+	client.BaseURL = server.URL
+	client.RegisterTool(weatherTool(&received))
+	client.RegisterTool(tools.NewTool("get_time", "Get the time", models.InputSchema{Type: "object"}, func(map[string]any) (string, error) {
+		return "12:00", nil
+	}))
+
+	conversation := models.NewConversation("")
+	for _, question := range []string{"Weather?", "Time?"} {
+		conversation.Append(models.Message{Role: models.RoleUser, Content: question})
+		if _, err := client.SendConversation(context.Background(), conversation); err != nil {
+			t.Fatalf("Failed to send conversation: %v", err)
+		}
+	}
+
+	ids := map[string]bool{}
+	for _, msg := range conversation.Messages {
+		for _, block := range msg.Blocks {
+			if block.Type == models.BlockToolUse {
+				if ids[block.ID] {
+					t.Errorf("Expected unique tool call IDs, got %s twice", block.ID)
+				}
+				ids[block.ID] = true
+			}
+		}
+	}
+	if len(ids) != 2 {
+		t.Errorf("Expected 2 tool calls, got %d", len(ids))
+	}
+}
+
+// TestSendMessageStream tests that NDJSON chunks are streamed and tool calls run the loop
+func TestSendMessageStream(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		var request ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+
+		if !request.Stream {
+			t.Errorf("Expected stream to be true")
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		if requests == 1 {
+			w.Write([]byte(toolStream))
+			return
+		}
+		w.Write([]byte(textStream))
+	}))
+	defer server.Close()
+
+	var received map[string]any
+	client := NewClient()
+	client.BaseURL = server.URL
+	client.RegisterTool(weatherTool(&received))
+
+	events, err := client.SendMessageStream(context.Background(), models.Message{
+		Role:    models.RoleUser,
+		Content: "What's the weather in Brussels?",
+	})
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+
+	var text strings.Builder
+	var toolName string
+	var last models.StreamEvent
+	for event := range events {
+		switch event.Type {
+		case models.StreamText:
+			text.WriteString(event.Text)
+		case models.StreamToolUse:
+			toolName = event.ToolName
+		case models.StreamError:
+			t.Fatalf("Unexpected stream error: %v", event.Err)
+		}
+		last = event
+	}
+
+	if toolName != "get_weather" {
+		t.Errorf("Expected tool_use event for 'get_weather', got '%s'", toolName)
+	}
+
+	if received["location"] != "Brussels" {
+		t.Errorf("Expected tool to receive location 'Brussels', got %v", received["location"])
+	}
+
+	if text.String() != "It is 5°C in Brussels." {
+		t.Errorf("Expected streamed text 'It is 5°C in Brussels.', got '%s'", text.String())
+	}
+
+	if last.Type != models.StreamStop || last.StopReason != "stop" {
+		t.Errorf("Expected final stop event with reason 'stop', got %+v", last)
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+}
+
+// TestSendMessageStreamTruncated tests that a stream ending without a done chunk is reported as an error
+func TestSendMessageStreamTruncated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lines := strings.SplitAfter(textStream, "\n")
+		w.Write([]byte(strings.Join(lines[:2], "")))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.BaseURL = server.URL
+
+	events, err := client.SendMessageStream(context.Background(), models.Message{Role: models.RoleUser, Content: "Hi"})
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+
+	var last models.StreamEvent
+	for event := range events {
+		last = event
+	}
+
+	if last.Type != models.StreamError || !errors.Is(last.Err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected an unexpected EOF error, got %+v", last)
+	}
+}
+
+// TestSendMessageError tests that API errors name Ollama as their provider
+func TestSendMessageError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"model \"llama9\" not found, try pulling it first"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.BaseURL = server.URL
+
+	_, err := client.SendMessage(context.Background(), models.Message{Role: models.RoleUser, Content: "Hi"})
+
+	var apiErr *common.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an *common.APIError, got %v", err)
+	}
+	if apiErr.Provider != "Ollama" {
+		t.Errorf("Expected provider 'Ollama', got '%s'", apiErr.Provider)
+	}
+}
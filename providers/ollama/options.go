@@ -0,0 +1,52 @@
+package ollama
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// applyOptions applies the per-request generation options to the request,
+// rejecting those Ollama does not support.
+func (c *Client) applyOptions(request *ChatRequest, opts models.GenerationOptions) error {
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("invalid generation options: %w", err)
+	}
+
+	switch {
+	case opts.UserID != "" || len(opts.Metadata) > 0:
+		return errors.New("Ollama does not support a user ID or request metadata")
+	case opts.ToolChoice.Mode == models.ToolChoiceAny || opts.ToolChoice.Mode == models.ToolChoiceTool:
+		return fmt.Errorf("Ollama does not support tool choice %q", opts.ToolChoice.Mode)
+	}
+
+	if opts.Model != "" {
+		request.Model = opts.Model
+	}
+	if opts.MaxTokens > 0 {
+		request.Options.NumPredict = opts.MaxTokens
+	}
+	if opts.Temperature != nil {
+		request.Options.Temperature = *opts.Temperature
+	}
+	if opts.TopP != nil {
+		request.Options.TopP = opts.TopP
+	}
+	if opts.TopK != nil {
+		request.Options.TopK = *opts.TopK
+	}
+	if len(opts.StopSequences) > 0 {
+		request.Options.Stop = opts.StopSequences
+	}
+	if opts.Seed != nil {
+		request.Options.Seed = opts.Seed
+	}
+
+	// Ollama has no tool_choice, tools are simply not offered instead
+	if opts.ToolChoice.Mode == models.ToolChoiceNone {
+		request.Tools = nil
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package openai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/devOpifex/bond/providers/common"
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI API version used when none is given.
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// NewAzureClient creates a client for a model deployed on Azure OpenAI.
+// Azure identifies the model by the deployment name in the URL path, takes the
+// API version as a query parameter and expects the key in an api-key header.
+// An empty apiVersion selects DefaultAzureAPIVersion.
+func NewAzureClient(endpoint, deployment, apiVersion, apiKey string) *Client {
+	if apiVersion == "" {
+		apiVersion = DefaultAzureAPIVersion
+	}
+
+	client := NewClient(apiKey)
+	client.BaseURL = fmt.Sprintf(
+		"%s/openai/deployments/%s/chat/completions?api-version=%s",
+		strings.TrimSuffix(endpoint, "/"),
+		url.PathEscape(deployment),
+		url.QueryEscape(apiVersion),
+	)
+	client.Model = deployment
+	client.AuthStyle = AuthHeader
+	client.AuthHeader = "api-key"
+
+	return client
+}
+
+// ContentFilterResult reports the verdict of a content filter for one category
+type ContentFilterResult struct {
+	Filtered bool   `json:"filtered"`
+	Severity string `json:"severity,omitempty"`
+	Detected bool   `json:"detected,omitempty"`
+}
+
+// ContentFilterError is returned when Azure's content filters block a prompt or
+// a completion. Use errors.As to detect it and inspect which categories triggered.
+// It wraps a common.APIError, whose StatusCode is 400 when the prompt was rejected,
+// or 200 when the completion was cut off by the filter.
+type ContentFilterError struct {
+	*common.APIError
+
+	// Results maps filter categories (hate, sexual, violence, self_harm,
+	// jailbreak, ...) to their verdicts
+	Results map[string]ContentFilterResult
+}
+
+// Unwrap returns the underlying APIError.
+func (e *ContentFilterError) Unwrap() error { return e.APIError }
+
+// Error implements the error interface.
+func (e *ContentFilterError) Error() string {
+	var categories []string
+	for category, result := range e.Results {
+		if result.Filtered {
+			categories = append(categories, category)
+		}
+	}
+	sort.Strings(categories)
+
+	message := "content filtered"
+	if len(categories) > 0 {
+		message += " (" + strings.Join(categories, ", ") + ")"
+	}
+	if e.Message != "" {
+		message += ": " + e.Message
+	}
+	return message
+}
+
+// azureErrorResponse is the error payload Azure returns for filtered prompts
+type azureErrorResponse struct {
+	Error struct {
+		InnerError struct {
+			Code                string                     `json:"code"`
+			ContentFilterResult map[string]json.RawMessage `json:"content_filter_result"`
+		} `json:"innererror"`
+	} `json:"error"`
+}
+
+// convertError attributes API errors to OpenAI and turns those carrying a
+// content filter payload into a ContentFilterError. Other errors are returned
+// unchanged.
+func convertError(err error) error {
+	var apiErr *common.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+
+	apiErr.Provider = "OpenAI"
+	if apiErr.Type != "content_filter" {
+		return err
+	}
+
+	var payload azureErrorResponse
+	if json.Unmarshal(apiErr.Body, &payload) != nil {
+		return err
+	}
+
+	return &ContentFilterError{
+		APIError: apiErr,
+		Results:  filterResults(payload.Error.InnerError.ContentFilterResult),
+	}
+}
+
+// filteredCompletionError reports a completion cut off by the content filter.
+func filteredCompletionError(results map[string]json.RawMessage) error {
+	return &ContentFilterError{
+		APIError: &common.APIError{Provider: "OpenAI", StatusCode: 200, Type: finishContentFilter},
+		Results:  filterResults(results),
+	}
+}
+
+// filterResults decodes the per-category filter verdicts, skipping entries such
+// as blocklist details that do not follow the common shape.
+func filterResults(raw map[string]json.RawMessage) map[string]ContentFilterResult {
+	results := make(map[string]ContentFilterResult, len(raw))
+	for category, data := range raw {
+		var result ContentFilterResult
+		if json.Unmarshal(data, &result) == nil {
+			results[category] = result
+		}
+	}
+	return results
+}
@@ -0,0 +1,111 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// TestNewAzureClient tests that the Azure endpoint and headers are built from the deployment
+func TestNewAzureClient(t *testing.T) {
+	client := NewAzureClient("https://example.openai.azure.com/", "gpt-4o-prod", "", "azure-key")
+
+	expected := "https://example.openai.azure.com/openai/deployments/gpt-4o-prod/chat/completions?api-version=" + DefaultAzureAPIVersion
+	if client.BaseURL != expected {
+		t.Errorf("Expected URL '%s', got '%s'", expected, client.BaseURL)
+	}
+
+	headers := client.headers()
+	if headers["api-key"] != "azure-key" {
+		t.Errorf("Expected api-key header, got %v", headers)
+	}
+
+	if _, ok := headers["Authorization"]; ok {
+		t.Errorf("Expected no Authorization header, got %v", headers)
+	}
+}
+
+// TestAzureContentFilterError tests that filtered prompts are surfaced as ContentFilterError
+func TestAzureContentFilterError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/deployments/gpt-4o-prod/chat/completions" {
+			t.Errorf("Unexpected path '%s'", r.URL.Path)
+		}
+
+		if r.URL.Query().Get("api-version") != "2024-06-01" {
+			t.Errorf("Expected api-version query parameter, got '%s'", r.URL.RawQuery)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{
+			"error": {
+				"code": "content_filter",
+				"message": "The response was filtered due to the prompt triggering Azure OpenAI's content management policy.",
+				"status": 400,
+				"innererror": {
+					"code": "ResponsibleAIPolicyViolation",
+					"content_filter_result": {
+						"hate": {"filtered": false, "severity": "safe"},
+						"jailbreak": {"filtered": true, "detected": true},
+						"violence": {"filtered": true, "severity": "high"}
+					}
+				}
+			}
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewAzureClient(server.URL, "gpt-4o-prod", "2024-06-01", "azure-key")
+
+	_, err := client.SendMessage(context.Background(), models.Message{Role: models.RoleUser, Content: "..."})
+
+	var filterErr *ContentFilterError
+	if !errors.As(err, &filterErr) {
+		t.Fatalf("Expected ContentFilterError, got %v", err)
+	}
+
+	if filterErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", filterErr.StatusCode)
+	}
+
+	if !filterErr.Results["violence"].Filtered || filterErr.Results["violence"].Severity != "high" {
+		t.Errorf("Expected violence to be filtered, got %+v", filterErr.Results["violence"])
+	}
+
+	if !filterErr.Results["jailbreak"].Detected {
+		t.Errorf("Expected jailbreak to be detected, got %+v", filterErr.Results["jailbreak"])
+	}
+}
+
+// TestAzureFilteredCompletion tests that a completion cut off by the filter is reported
+func TestAzureFilteredCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"choices": [{
+				"message": {"role": "assistant", "content": ""},
+				"finish_reason": "content_filter",
+				"content_filter_results": {"sexual": {"filtered": true, "severity": "medium"}}
+			}]
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewAzureClient(server.URL, "gpt-4o-prod", "", "azure-key")
+
+	_, err := client.SendMessage(context.Background(), models.Message{Role: models.RoleUser, Content: "..."})
+
+	var filterErr *ContentFilterError
+	if !errors.As(err, &filterErr) {
+		t.Fatalf("Expected ContentFilterError, got %v", err)
+	}
+
+	if filterErr.Error() != "content filtered (sexual)" {
+		t.Errorf("Unexpected error message '%s'", filterErr.Error())
+	}
+}
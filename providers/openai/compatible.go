@@ -0,0 +1,169 @@
+package openai
+
+import (
+	"strings"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// AuthStyle selects how the API key is sent to an OpenAI-compatible server.
+type AuthStyle int
+
+const (
+	// AuthBearer sends the key as "Authorization: Bearer <key>", as OpenAI does.
+	AuthBearer AuthStyle = iota
+
+	// AuthHeader sends the key verbatim in the header named by Client.AuthHeader,
+	// for servers expecting e.g. "api-key: <key>" or "x-api-key: <key>".
+	AuthHeader
+
+	// AuthNone sends no credentials, for local servers without authentication.
+	AuthNone
+)
+
+// Quirks describes the ways an OpenAI-compatible server departs from the
+// OpenAI chat completions API. The zero value matches OpenAI itself.
+type Quirks struct {
+	// NoToolChoice omits the tool_choice field, which some servers reject.
+	NoToolChoice bool
+
+	// NoSystemRole merges the system prompt into the first user message for
+	// servers and chat templates that do not accept system messages.
+	NoSystemRole bool
+
+	// UseMaxCompletionTokens sends the token limit as max_completion_tokens
+	// instead of the deprecated max_tokens.
+	UseMaxCompletionTokens bool
+
+	// NoStreamOptions omits stream_options from streaming requests for servers
+	// that reject it. Usage is then not reported for streamed responses.
+	NoStreamOptions bool
+}
+
+// CompatibleConfig configures a client for a server speaking the OpenAI chat
+// completions dialect, such as vLLM, the llama.cpp server, LM Studio, Together or Groq.
+type CompatibleConfig struct {
+	// BaseURL is the root of the API, e.g. "http://localhost:8000/v1".
+	// The /chat/completions path is appended unless already present.
+	BaseURL string
+
+	// APIKey is the key sent to the server according to AuthStyle.
+	APIKey string
+
+	// Model is the name of the model to use on the server.
+	Model string
+
+	// AuthStyle selects how the API key is sent. Defaults to AuthBearer.
+	AuthStyle AuthStyle
+
+	// AuthHeader is the name of the header carrying the key with AuthHeader.
+	// Defaults to "api-key".
+	AuthHeader string
+
+	// Headers are extra headers sent with every request.
+	Headers map[string]string
+
+	// Quirks lists the server's departures from the OpenAI API.
+	Quirks Quirks
+}
+
+// NewCompatibleClient creates a client for an OpenAI-compatible server.
+// The returned client behaves like one created with NewClient, with tool calling,
+// streaming and MCP support, adapted to the server's quirks.
+func NewCompatibleClient(config CompatibleConfig) *Client {
+	client := NewClient(config.APIKey)
+
+	client.BaseURL = chatCompletionsURL(config.BaseURL)
+	if config.Model != "" {
+		client.Model = config.Model
+	}
+
+	client.AuthStyle = config.AuthStyle
+	client.AuthHeader = config.AuthHeader
+	client.Quirks = config.Quirks
+
+	for name, value := range config.Headers {
+		client.Headers[name] = value
+	}
+
+	return client
+}
+
+// chatCompletionsURL appends the chat completions path to a base URL if needed.
+func chatCompletionsURL(baseURL string) string {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if strings.HasSuffix(baseURL, "/chat/completions") {
+		return baseURL
+	}
+	return baseURL + "/chat/completions"
+}
+
+// headers returns the HTTP headers for a request, applying the client's
+// authentication style and extra headers.
+func (c *Client) headers() map[string]string {
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+
+	switch c.AuthStyle {
+	case AuthBearer:
+		headers["Authorization"] = "Bearer " + c.ApiKey
+	case AuthHeader:
+		name := c.AuthHeader
+		if name == "" {
+			name = "api-key"
+		}
+		headers[name] = c.ApiKey
+	}
+
+	for name, value := range c.Headers {
+		headers[name] = value
+	}
+
+	return headers
+}
+
+// applyQuirks adapts a request to the server's departures from the OpenAI API.
+func (c *Client) applyQuirks(request *OpenAIRequest) {
+	if c.Quirks.NoToolChoice {
+		request.ToolChoice = nil
+	}
+
+	if c.Quirks.UseMaxCompletionTokens {
+		request.MaxCompletionTokens = request.MaxTokens
+		request.MaxTokens = 0
+	}
+}
+
+// mergeSystemMessages folds system messages into the first user message for
+// servers that do not accept the system role. Without a user message the
+// system prompt is sent as one.
+func mergeSystemMessages(messages []OpenAIMessage) []OpenAIMessage {
+	var system []string
+	var merged []OpenAIMessage
+
+	for _, message := range messages {
+		if message.Role == models.RoleSystem {
+			system = append(system, message.Content)
+			continue
+		}
+		merged = append(merged, message)
+	}
+
+	if len(system) == 0 {
+		return messages
+	}
+
+	prompt := strings.Join(system, "\n\n")
+	for i, message := range merged {
+		if message.Role == models.RoleUser {
+			merged[i].Content = prompt + "\n\n" + message.Content
+			if len(message.Parts) > 0 {
+				merged[i].Parts = append([]ContentPart{{Type: "text", Text: prompt}}, message.Parts...)
+			}
+			return merged
+		}
+	}
+
+	return append([]OpenAIMessage{{Role: models.RoleUser, Content: prompt}}, merged...)
+}
@@ -0,0 +1,110 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// TestNewCompatibleClient tests that the compatible client is configured from its config
+func TestNewCompatibleClient(t *testing.T) {
+	client := NewCompatibleClient(CompatibleConfig{
+		BaseURL: "http://localhost:8000/v1/",
+		Model:   "meta-llama/Llama-3.1-8B-Instruct",
+	})
+
+	if client.BaseURL != "http://localhost:8000/v1/chat/completions" {
+		t.Errorf("Expected chat completions URL, got '%s'", client.BaseURL)
+	}
+
+	if client.Model != "meta-llama/Llama-3.1-8B-Instruct" {
+		t.Errorf("Expected configured model, got '%s'", client.Model)
+	}
+
+	client = NewCompatibleClient(CompatibleConfig{BaseURL: "https://api.groq.com/openai/v1/chat/completions"})
+	if client.BaseURL != "https://api.groq.com/openai/v1/chat/completions" {
+		t.Errorf("Expected URL to be kept as is, got '%s'", client.BaseURL)
+	}
+}
+
+// TestCompatibleClientQuirks tests that headers and quirks are applied to requests
+func TestCompatibleClientQuirks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("Expected no Authorization header, got '%s'", r.Header.Get("Authorization"))
+		}
+
+		if r.Header.Get("x-api-key") != "secret" {
+			t.Errorf("Expected x-api-key header, got '%s'", r.Header.Get("x-api-key"))
+		}
+
+		if r.Header.Get("X-Title") != "bond" {
+			t.Errorf("Expected extra X-Title header, got '%s'", r.Header.Get("X-Title"))
+		}
+
+		var request map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+			return
+		}
+
+		if _, ok := request["tool_choice"]; ok {
+			t.Errorf("Expected tool_choice to be omitted")
+		}
+
+		if _, ok := request["max_tokens"]; ok {
+			t.Errorf("Expected max_tokens to be omitted")
+		}
+
+		if request["max_completion_tokens"] != float64(500) {
+			t.Errorf("Expected max_completion_tokens 500, got %v", request["max_completion_tokens"])
+		}
+
+		messages := request["messages"].([]any)
+		if len(messages) != 1 {
+			t.Errorf("Expected system prompt merged into a single message, got %v", messages)
+			return
+		}
+
+		first := messages[0].(map[string]any)
+		if first["role"] != models.RoleUser || first["content"] != "Be brief.\n\nHello" {
+			t.Errorf("Unexpected merged message: %v", first)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Hi!"}, "finish_reason": "stop"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewCompatibleClient(CompatibleConfig{
+		BaseURL:    server.URL,
+		APIKey:     "secret",
+		AuthStyle:  AuthHeader,
+		AuthHeader: "x-api-key",
+		Headers:    map[string]string{"X-Title": "bond"},
+		Quirks: Quirks{
+			NoToolChoice:           true,
+			NoSystemRole:           true,
+			UseMaxCompletionTokens: true,
+		},
+	})
+	client.SetSystemPrompt("Be brief.")
+	client.SetMaxTokens(500)
+	client.RegisterTool(&MockTool{name: "noop", schema: models.InputSchema{Type: "object"}})
+
+	response, err := client.SendMessageWithTools(context.Background(), models.Message{
+		Role:    models.RoleUser,
+		Content: "Hello",
+	})
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	if response != "Hi!" {
+		t.Errorf("Expected response 'Hi!', got '%s'", response)
+	}
+}
@@ -0,0 +1,139 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/devOpifex/bond/models"
+	"github.com/devOpifex/bond/providers/common"
+)
+
+// maxEmbeddingInputs is the number of texts OpenAI accepts per embeddings request
+const maxEmbeddingInputs = 2048
+
+// nativeDimensions is the length of the vectors of OpenAI's embedding models
+var nativeDimensions = map[string]int{
+	"text-embedding-3-small": 1536,
+	"text-embedding-3-large": 3072,
+	"text-embedding-ada-002": 1536,
+}
+
+// Client must satisfy the models.Embedder interface
+var _ models.Embedder = (*Client)(nil)
+
+// EmbeddingRequest represents a request to the embeddings endpoint
+type EmbeddingRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	EncodingFormat string   `json:"encoding_format"`
+	Dimensions     int      `json:"dimensions,omitempty"`
+	User           string   `json:"user,omitempty"`
+}
+
+// EmbeddingResponse represents a response from the embeddings endpoint
+type EmbeddingResponse struct {
+	Model string          `json:"model"`
+	Data  []EmbeddingData `json:"data"`
+	Usage *OpenAIUsage    `json:"usage,omitempty"`
+}
+
+// EmbeddingData is the vector of one input text
+type EmbeddingData struct {
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// Embed returns the embeddings of the texts, computed by the model in
+// Embedding.Model at most Embedding.BatchSize texts per request. Usage is
+// reported for every request. The user ID of the generation options in the
+// context is sent along.
+// This implements the models.Embedder interface.
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return common.EmbedBatches(ctx, texts, c.Embedding.BatchSize, c.embedBatch)
+}
+
+// Dimensions returns Embedding.Dimensions if set, otherwise the length of the
+// vectors last returned by Embed or the native length of OpenAI's models.
+// This implements the models.Embedder interface.
+func (c *Client) Dimensions() int {
+	if c.Embedding.Dimensions > 0 {
+		return c.Embedding.Dimensions
+	}
+	if dimensions := c.embeddingDimensions.Load(); dimensions > 0 {
+		return int(dimensions)
+	}
+	return nativeDimensions[c.Embedding.Model]
+}
+
+// EmbeddingModel returns the name of the embedding model.
+// This implements the models.Embedder interface.
+func (c *Client) EmbeddingModel() string {
+	return c.Embedding.Model
+}
+
+// embedBatch sends a single embeddings request and returns the vectors in the
+// order of the texts
+func (c *Client) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(EmbeddingRequest{
+		Model:          c.Embedding.Model,
+		Input:          texts,
+		EncodingFormat: "float",
+		Dimensions:     c.Embedding.Dimensions,
+		User:           models.OptionsFrom(ctx).UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.DoHTTPRequest(ctx, common.HTTPRequest{
+		Method:  "POST",
+		URL:     c.embeddingsURL(),
+		Headers: c.headers(),
+		Body:    jsonData,
+	})
+	if err != nil {
+		return nil, convertError(err)
+	}
+
+	var response EmbeddingResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	model := response.Model
+	if model == "" {
+		model = c.Embedding.Model
+	}
+	c.reportUsage(ctx, model, response.Usage)
+
+	// The data is ordered by index, but nothing guarantees it
+	vectors := make([][]float32, len(texts))
+	for _, data := range response.Data {
+		if data.Index >= 0 && data.Index < len(vectors) {
+			vectors[data.Index] = data.Embedding
+		}
+	}
+	for i, vector := range vectors {
+		if vector == nil {
+			return nil, fmt.Errorf("OpenAI returned no embedding for text %d", i)
+		}
+	}
+
+	if len(vectors) > 0 {
+		c.embeddingDimensions.Store(int64(len(vectors[0])))
+	}
+	return vectors, nil
+}
+
+// embeddingsURL derives the embeddings endpoint from the chat completions
+// endpoint in BaseURL, keeping the query string, such as Azure's api-version
+func (c *Client) embeddingsURL() string {
+	endpoint, query, found := strings.Cut(c.BaseURL, "?")
+	endpoint = strings.TrimSuffix(endpoint, "/chat/completions") + "/embeddings"
+	if found {
+		endpoint += "?" + query
+	}
+	return endpoint
+}
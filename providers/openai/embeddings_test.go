@@ -0,0 +1,113 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// TestEmbed tests that texts are embedded in batches, in order, with usage reported
+func TestEmbed(t *testing.T) {
+	var batches [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer test-api-key" {
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+
+		var request EmbeddingRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		batches = append(batches, request.Input)
+		if request.Model != "text-embedding-3-small" || request.Dimensions != 2 {
+			t.Errorf("Unexpected model %s or dimensions %d", request.Model, request.Dimensions)
+		}
+
+		// Answer in reverse order, each vector encoding the length of its text
+		var data []string
+		for i := len(request.Input) - 1; i >= 0; i-- {
+			data = append(data, fmt.Sprintf(`{"index": %d, "embedding": [%d, 0.5]}`, i, len(request.Input[i])))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"model": "text-embedding-3-small", "data": [%s], "usage": {"prompt_tokens": %d, "total_tokens": %d}}`,
+			strings.Join(data, ", "), len(request.Input), len(request.Input))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-api-key")
+	client.BaseURL = server.URL + "/v1/chat/completions"
+	client.Embedding.Dimensions = 2
+	client.Embedding.BatchSize = 2
+
+	tracker := models.NewUsageTracker()
+	ctx := models.WithUsageTracker(context.Background(), tracker)
+
+	vectors, err := client.Embed(ctx, []string{"a", "bb", "ccc"})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 {
+		t.Errorf("Expected batches of 2 and 1 texts, got %v", batches)
+	}
+	for i, vector := range vectors {
+		if len(vector) != 2 || vector[0] != float32(i+1) {
+			t.Errorf("Expected vector %d to embed text %d, got %v", i, i, vector)
+		}
+	}
+	if usage := tracker.ByModel()["text-embedding-3-small"]; usage.InputTokens != 3 {
+		t.Errorf("Expected 3 input tokens over both requests, got %+v", usage)
+	}
+	if client.Dimensions() != 2 || client.EmbeddingModel() != "text-embedding-3-small" {
+		t.Errorf("Unexpected dimensions %d or model %s", client.Dimensions(), client.EmbeddingModel())
+	}
+}
+
+// TestEmbedRetries tests that failed embedding requests are retried
+func TestEmbedRetries(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			w.Header().Set("retry-after-ms", "5")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": {"message": "Rate limit reached", "type": "requests"}}`))
+			return
+		}
+		w.Write([]byte(`{"data": [{"index": 0, "embedding": [0.1, 0.2, 0.3]}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-api-key")
+	client.BaseURL = server.URL
+
+	if client.Dimensions() != 1536 {
+		t.Errorf("Expected the native dimensions before the first call, got %d", client.Dimensions())
+	}
+
+	vectors, err := client.Embed(context.Background(), []string{"hello"})
+	if err != nil {
+		t.Fatalf("Expected request to succeed after retry, got %v", err)
+	}
+	if requests != 2 || len(vectors) != 1 || client.Dimensions() != 3 {
+		t.Errorf("Expected one vector of 3 dimensions after 2 requests, got %v after %d", vectors, requests)
+	}
+}
+
+// TestEmbeddingsURL tests that the embeddings endpoint is derived from the chat endpoint
+func TestEmbeddingsURL(t *testing.T) {
+	azure := NewAzureClient("https://example.openai.azure.com", "embeddings", "", "key")
+	expected := "https://example.openai.azure.com/openai/deployments/embeddings/embeddings?api-version=" + DefaultAzureAPIVersion
+	if url := azure.embeddingsURL(); url != expected {
+		t.Errorf("Expected %s, got %s", expected, url)
+	}
+
+	if url := NewClient("key").embeddingsURL(); url != "https://api.openai.com/v1/embeddings" {
+		t.Errorf("Unexpected OpenAI embeddings URL %s", url)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync/atomic"
 
 	"github.com/devOpifex/bond/models"
 	"github.com/devOpifex/bond/providers/common"
@@ -15,18 +16,75 @@ import (
 
 // OpenAIRequest represents a request to the OpenAI API
 type OpenAIRequest struct {
-	Model       string          `json:"model"`
-	MaxTokens   int             `json:"max_tokens"`
-	Messages    []OpenAIMessage `json:"messages"`
-	Tools       []OpenAITool    `json:"tools,omitempty"`
-	ToolChoice  string          `json:"tool_choice,omitempty"`
-	Temperature float64         `json:"temperature"`
+	Model               string            `json:"model"`
+	MaxTokens           int               `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int               `json:"max_completion_tokens,omitempty"`
+	Messages            []OpenAIMessage   `json:"messages"`
+	Tools               []OpenAITool      `json:"tools,omitempty"`
+	ToolChoice          any               `json:"tool_choice,omitempty"`
+	Temperature         float64           `json:"temperature"`
+	TopP                *float64          `json:"top_p,omitempty"`
+	Stop                []string          `json:"stop,omitempty"`
+	Seed                *int              `json:"seed,omitempty"`
+	User                string            `json:"user,omitempty"`
+	Metadata            map[string]string `json:"metadata,omitempty"`
+	Store               bool              `json:"store,omitempty"`
+	Stream              bool              `json:"stream,omitempty"`
+	StreamOptions       *StreamOptions    `json:"stream_options,omitempty"`
+	ResponseFormat      *ResponseFormat   `json:"response_format,omitempty"`
 }
 
+// StreamOptions configures streamed responses
+type StreamOptions struct {
+	// IncludeUsage requests a final chunk reporting the usage of the request
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// roleTool is the role OpenAI uses for messages carrying tool results
+const roleTool = "tool"
+
 // OpenAIMessage represents a message in OpenAI format
 type OpenAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []OpenAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+
+	// Parts replaces Content with a list of content parts when set, for
+	// messages carrying images or files
+	Parts []ContentPart `json:"-"`
+}
+
+// MarshalJSON sends the content as a list of parts when the message has any.
+func (m OpenAIMessage) MarshalJSON() ([]byte, error) {
+	type message OpenAIMessage
+	if len(m.Parts) == 0 {
+		return json.Marshal(message(m))
+	}
+
+	return json.Marshal(struct {
+		message
+		Content []ContentPart `json:"content"`
+	}{message(m), m.Parts})
+}
+
+// ContentPart is a part of a message with mixed content
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+	File     *FilePart `json:"file,omitempty"`
+}
+
+// ImageURL references an image by URL, including data URLs
+type ImageURL struct {
+	URL string `json:"url"`
+}
+
+// FilePart carries a file, such as a PDF, inline
+type FilePart struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data"`
 }
 
 // OpenAITool represents a tool in OpenAI format
@@ -49,20 +107,49 @@ type OpenAIResponse struct {
 	Created int64          `json:"created"`
 	Model   string         `json:"model"`
 	Choices []OpenAIChoice `json:"choices"`
-	Usage   map[string]int `json:"usage"`
+	Usage   *OpenAIUsage   `json:"usage,omitempty"`
+}
+
+// OpenAIUsage reports the tokens billed for a request
+type OpenAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details,omitempty"`
+}
+
+// convert converts the reported usage to Bond's format. OpenAI counts cached
+// tokens in prompt_tokens, so they are moved to CacheReadTokens.
+func (u *OpenAIUsage) convert() models.Usage {
+	usage := models.Usage{
+		InputTokens:  u.PromptTokens,
+		OutputTokens: u.CompletionTokens,
+	}
+	if u.PromptTokensDetails != nil {
+		usage.CacheReadTokens = u.PromptTokensDetails.CachedTokens
+		usage.InputTokens -= u.PromptTokensDetails.CachedTokens
+	}
+	return usage
 }
 
 // OpenAIChoice represents a choice in an OpenAI response
 type OpenAIChoice struct {
-	Index        int               `json:"index"`
-	Message      OpenAIRespMessage `json:"message"`
-	FinishReason string            `json:"finish_reason"`
+	Index                int                        `json:"index"`
+	Message              OpenAIRespMessage          `json:"message"`
+	FinishReason         string                     `json:"finish_reason"`
+	ContentFilterResults map[string]json.RawMessage `json:"content_filter_results,omitempty"`
 }
 
+// finishContentFilter is the finish reason of completions cut off by a content filter
+const finishContentFilter = "content_filter"
+
 // OpenAIRespMessage represents a message in an OpenAI response
 type OpenAIRespMessage struct {
 	Role      string           `json:"role"`
 	Content   string           `json:"content"`
+	Refusal   string           `json:"refusal,omitempty"`
 	ToolCalls []OpenAIToolCall `json:"tool_calls,omitempty"`
 }
 
@@ -84,8 +171,29 @@ type OpenAIFuncCall struct {
 // request formatting, and response parsing. It implements the models.Provider interface.
 type Client struct {
 	common.BaseClient
+
+	// AuthStyle selects how the API key is sent, AuthBearer for OpenAI itself.
+	AuthStyle AuthStyle
+
+	// AuthHeader names the header carrying the API key when AuthStyle is AuthHeader.
+	AuthHeader string
+
+	// Headers are extra headers sent with every request.
+	Headers map[string]string
+
+	// Quirks adapts requests to OpenAI-compatible servers that differ from OpenAI.
+	Quirks Quirks
+
+	// Embedding configures the embeddings computed by Embed.
+	Embedding common.EmbeddingConfig
+
+	// embeddingDimensions is the length of the last vectors returned by Embed
+	embeddingDimensions atomic.Int64
 }
 
+// Client must satisfy the models.Provider interface
+var _ models.Provider = (*Client)(nil)
+
 // NewClient creates a new OpenAI client with the provided API key.
 // It initializes the client with default settings for the OpenAI API,
 // including the base URL and default model (gpt-4o).
@@ -95,9 +203,15 @@ func NewClient(apiKey string) *Client {
 		"https://api.openai.com/v1/chat/completions",
 		"gpt-4o",
 	)
+	baseClient.Provider = "OpenAI"
 
 	return &Client{
 		BaseClient: baseClient,
+		Headers:    make(map[string]string),
+		Embedding: common.EmbeddingConfig{
+			Model:     "text-embedding-3-small",
+			BatchSize: maxEmbeddingInputs,
+		},
 	}
 }
 
@@ -105,30 +219,8 @@ func NewClient(apiKey string) *Client {
 // This implements part of the models.Provider interface for basic message exchange
 // without tool capabilities.
 func (c *Client) SendMessage(ctx context.Context, message models.Message) (string, error) {
-	var messages []OpenAIMessage
-
-	// Add system prompt if set
-	if c.SystemPrompt != "" {
-		messages = append(messages, OpenAIMessage{
-			Role:    models.RoleSystem,
-			Content: c.SystemPrompt,
-		})
-	}
-
-	// Add the user/assistant message
-	messages = append(messages, OpenAIMessage{
-		Role:    message.Role,
-		Content: message.Content,
-	})
-
-	request := OpenAIRequest{
-		Model:       c.Model,
-		MaxTokens:   c.MaxTokens,
-		Messages:    messages,
-		Temperature: c.Temperature,
-	}
-
-	return c.sendRequest(ctx, request)
+	conversation := models.NewConversation("").Append(message)
+	return c.sendRequest(ctx, conversation, false)
 }
 
 // convertToolSchema converts our schema format to OpenAI's JSON Schema format.
@@ -162,101 +254,377 @@ func convertToolSchema(schema models.InputSchema) (json.RawMessage, error) {
 // This implements part of the models.Provider interface for advanced interactions
 // where the model may need to call tools during its reasoning process.
 func (c *Client) SendMessageWithTools(ctx context.Context, message models.Message) (string, error) {
+	conversation := models.NewConversation("").Append(message)
+	return c.sendRequest(ctx, conversation, true)
+}
+
+// SendConversation sends the whole conversation to OpenAI with registered tools.
+// The model's turns, including tool calls and their results, are appended to the
+// conversation. This implements part of the models.Provider interface.
+func (c *Client) SendConversation(ctx context.Context, conversation *models.Conversation) (string, error) {
+	return c.sendRequest(ctx, conversation, true)
+}
+
+// buildRequest creates the chat completion request for a conversation with the
+// given generation options. Registered tools are converted to OpenAI's format
+// when withTools is set.
+func (c *Client) buildRequest(conversation *models.Conversation, withTools bool, opts models.GenerationOptions) (OpenAIRequest, error) {
+	request := OpenAIRequest{
+		Model:       c.Model,
+		MaxTokens:   c.MaxTokens,
+		Messages:    c.convertMessages(conversation),
+		Temperature: c.Temperature,
+	}
+
+	if !withTools {
+		if err := c.applyOptions(&request, opts); err != nil {
+			return OpenAIRequest{}, err
+		}
+		c.applyQuirks(&request)
+		return request, nil
+	}
+
 	// Convert registered tools to OpenAI tool format
-	var tools []OpenAITool
 	for _, tool := range c.Tools {
-		// Convert our schema to OpenAI schema
-		parametersJSON, err := convertToolSchema(tool.GetSchema())
+		converted, err := convertTool(tool)
 		if err != nil {
-			return "", fmt.Errorf("failed to convert tool schema: %w", err)
+			return OpenAIRequest{}, err
 		}
+		request.Tools = append(request.Tools, converted)
+	}
 
-		tools = append(tools, OpenAITool{
-			Type: "function",
-			Function: OpenAIFunction{
-				Name:        tool.GetName(),
-				Description: tool.GetDescription(),
-				Parameters:  parametersJSON,
-			},
-		})
+	if len(request.Tools) > 0 {
+		request.ToolChoice = "auto"
 	}
 
+	if err := c.applyOptions(&request, opts); err != nil {
+		return OpenAIRequest{}, err
+	}
+	c.applyQuirks(&request)
+	return request, nil
+}
+
+// convertTool converts a tool to OpenAI's function tool format.
+func convertTool(tool models.ToolExecutor) (OpenAITool, error) {
+	// Convert our schema to OpenAI schema
+	parametersJSON, err := convertToolSchema(tool.GetSchema())
+	if err != nil {
+		return OpenAITool{}, fmt.Errorf("failed to convert tool schema: %w", err)
+	}
+
+	return OpenAITool{
+		Type: "function",
+		Function: OpenAIFunction{
+			Name:        tool.GetName(),
+			Description: tool.GetDescription(),
+			Parameters:  parametersJSON,
+		},
+	}, nil
+}
+
+// convertMessages transforms a conversation to OpenAI's message format.
+// Tool calls become assistant tool_calls and tool results become tool messages
+// carrying the ID of the call they answer.
+func (c *Client) convertMessages(conversation *models.Conversation) []OpenAIMessage {
 	var messages []OpenAIMessage
 
-	// Add system prompt if set
-	if c.SystemPrompt != "" {
+	// Add system prompt if set, preferring the conversation's own
+	systemPrompt := c.SystemPrompt
+	if conversation.SystemPrompt != "" {
+		systemPrompt = conversation.SystemPrompt
+	}
+	if systemPrompt != "" {
 		messages = append(messages, OpenAIMessage{
 			Role:    models.RoleSystem,
-			Content: c.SystemPrompt,
+			Content: systemPrompt,
 		})
 	}
+	messages = append(messages, convertMessageList(conversation.Messages)...)
 
-	// Add the user/assistant message
-	messages = append(messages, OpenAIMessage{
-		Role:    message.Role,
-		Content: message.Content,
-	})
+	if c.Quirks.NoSystemRole {
+		return mergeSystemMessages(messages)
+	}
 
-	request := OpenAIRequest{
-		Model:       c.Model,
-		MaxTokens:   c.MaxTokens,
-		Messages:    messages,
-		Tools:       tools,
-		ToolChoice:  "auto",
-		Temperature: c.Temperature,
+	return messages
+}
+
+// toolUseArguments returns the input of a models.ToolUse as the JSON string
+// OpenAI expects, keeping input that is already a JSON string as it is.
+func toolUseArguments(input any) string {
+	if text, ok := input.(string); ok && json.Valid([]byte(text)) {
+		return text
+	}
+	if input == nil {
+		return "{}"
+	}
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// convertMessageList converts messages to OpenAI's format, without a system prompt.
+func convertMessageList(conversation []models.Message) []OpenAIMessage {
+	var messages []OpenAIMessage
+
+	for _, msg := range conversation {
+		// Function results linked to a tool call become tool messages,
+		// other function messages are described in a user message
+		if msg.Role == models.RoleFunction {
+			switch {
+			case msg.ToolResult != nil && msg.ToolResult.ToolUseID != "":
+				messages = append(messages, OpenAIMessage{
+					Role:       roleTool,
+					Content:    msg.Content,
+					ToolCallID: msg.ToolResult.ToolUseID,
+				})
+			case msg.ToolResult != nil:
+				messages = append(messages, OpenAIMessage{
+					Role:    models.RoleUser,
+					Content: fmt.Sprintf("Tool '%s' returned: %s", msg.ToolResult.Name, msg.Content),
+				})
+			default:
+				messages = append(messages, OpenAIMessage{
+					Role:    models.RoleUser,
+					Content: fmt.Sprintf("Function returned: %s", msg.Content),
+				})
+			}
+			continue
+		}
+
+		if len(msg.Blocks) == 0 {
+			converted := OpenAIMessage{Role: msg.Role, Content: msg.Content}
+
+			// Tool calls recorded on the message itself become tool calls
+			if msg.ToolUse != nil && msg.ToolUse.ID != "" {
+				converted.ToolCalls = append(converted.ToolCalls, OpenAIToolCall{
+					ID:   msg.ToolUse.ID,
+					Type: "function",
+					Function: OpenAIFuncCall{
+						Name:      msg.ToolUse.Name,
+						Arguments: toolUseArguments(msg.ToolUse.Input),
+					},
+				})
+			}
+
+			messages = append(messages, converted)
+			continue
+		}
+
+		converted := OpenAIMessage{Role: msg.Role}
+		var toolMessages []OpenAIMessage
+		var parts []ContentPart
+		hasMedia := false
+		for _, block := range msg.Blocks {
+			switch block.Type {
+			case models.BlockText:
+				converted.Content += block.Text
+				parts = append(parts, ContentPart{Type: "text", Text: block.Text})
+			case models.BlockImage:
+				hasMedia = true
+				parts = append(parts, ContentPart{
+					Type:     "image_url",
+					ImageURL: &ImageURL{URL: dataURL(block)},
+				})
+			case models.BlockDocument:
+				// Files must be PDFs, text documents are sent as text
+				if text, ok := block.DocumentText(); ok {
+					if block.Name != "" {
+						text = block.Name + ":\n" + text
+					}
+					if converted.Content != "" {
+						converted.Content += "\n\n"
+					}
+					converted.Content += text
+					parts = append(parts, ContentPart{Type: "text", Text: text})
+					continue
+				}
+				hasMedia = true
+				parts = append(parts, ContentPart{
+					Type: "file",
+					File: &FilePart{Filename: block.Name, FileData: dataURL(block)},
+				})
+			case models.BlockToolUse:
+				converted.ToolCalls = append(converted.ToolCalls, OpenAIToolCall{
+					ID:   block.ID,
+					Type: "function",
+					Function: OpenAIFuncCall{
+						Name:      block.Name,
+						Arguments: string(block.Input),
+					},
+				})
+			case models.BlockToolResult:
+				toolMessages = append(toolMessages, OpenAIMessage{
+					Role:       roleTool,
+					Content:    block.Content,
+					ToolCallID: block.ToolUseID,
+				})
+			}
+		}
+
+		// Images and files require the content to be sent as parts
+		if hasMedia {
+			converted.Parts = parts
+		}
+
+		// Tool messages must directly follow the assistant message that made the calls
+		messages = append(messages, toolMessages...)
+		if converted.Content != "" || len(converted.ToolCalls) > 0 || len(converted.Parts) > 0 {
+			messages = append(messages, converted)
+		}
 	}
 
-	return c.sendRequest(ctx, request)
+	return messages
 }
 
 // sendRequest sends a request to the OpenAI API and processes the response.
 // It handles the HTTP communication, error handling, and response parsing.
-// If OpenAI requests a tool, it manages the tool execution and returns the result.
-func (c *Client) sendRequest(ctx context.Context, request OpenAIRequest) (string, error) {
+// If OpenAI requests tools, every call is executed and the results are sent back
+// as tool messages until the model produces a final answer. Every turn is
+// appended to the conversation. Generation options in the context apply to every
+// request, see models.GenerationOptions.FollowUp.
+func (c *Client) sendRequest(ctx context.Context, conversation *models.Conversation, withTools bool) (string, error) {
+	opts := models.OptionsFrom(ctx)
+
+	for round := 0; ; round++ {
+		if err := models.CheckBudget(ctx, conversation.Messages); err != nil {
+			return "", err
+		}
+
+		if err := models.FitConversation(ctx, conversation); err != nil {
+			return "", err
+		}
+
+		request, err := c.buildRequest(conversation, withTools, opts)
+		if err != nil {
+			return "", err
+		}
+		opts = opts.FollowUp()
+
+		openaiResp, err := c.createCompletion(ctx, request)
+		if err != nil {
+			return "", err
+		}
+		c.reportUsage(ctx, openaiResp.Model, openaiResp.Usage)
+
+		// Check if we have choices
+		if len(openaiResp.Choices) == 0 {
+			return "", fmt.Errorf("no choices in response")
+		}
+
+		// Get the first choice
+		choice := openaiResp.Choices[0]
+		if choice.FinishReason == finishContentFilter {
+			return "", filteredCompletionError(choice.ContentFilterResults)
+		}
+
+		conversation.Append(assistantMessage(choice.Message))
+
+		// Without tool calls the model has produced its answer
+		if len(choice.Message.ToolCalls) == 0 {
+			return choice.Message.Content, nil
+		}
+
+		if common.ToolRoundsExceeded(round, c.MaxToolRounds) {
+			return "", common.ErrMaxToolRounds
+		}
+
+		conversation.Append(c.runToolCalls(ctx, choice.Message.ToolCalls))
+	}
+}
+
+// reportUsage reports the usage of a request to the usage tracker in the context.
+func (c *Client) reportUsage(ctx context.Context, model string, usage *OpenAIUsage) {
+	if usage == nil {
+		return
+	}
+	if model == "" {
+		model = c.Model
+	}
+	models.ReportUsage(ctx, model, usage.convert())
+}
+
+// createCompletion sends a single chat completion request and parses the response.
+func (c *Client) createCompletion(ctx context.Context, request OpenAIRequest) (*OpenAIResponse, error) {
 	jsonData, err := json.Marshal(request)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Prepare HTTP request
-	headers := map[string]string{
-		"Content-Type":  "application/json",
-		"Authorization": "Bearer " + c.ApiKey,
-	}
-
 	httpReq := common.HTTPRequest{
 		Method:  "POST",
 		URL:     c.BaseURL,
-		Headers: headers,
+		Headers: c.headers(),
 		Body:    jsonData,
 	}
 
 	// Send the request
 	body, err := c.DoHTTPRequest(ctx, httpReq)
 	if err != nil {
-		return "", err
+		return nil, convertError(err)
 	}
 
 	var openaiResp OpenAIResponse
 	if err := json.Unmarshal(body, &openaiResp); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// Check if we have choices
-	if len(openaiResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
-	}
+	return &openaiResp, nil
+}
 
-	// Get the first choice
-	choice := openaiResp.Choices[0]
+// runToolCalls executes every tool call requested by the model and returns a
+// message carrying a tool_result block for each of them. Failed calls are
+// reported to the model rather than aborting the request.
+func (c *Client) runToolCalls(ctx context.Context, toolCalls []OpenAIToolCall) models.Message {
+	calls := make([]common.ToolCall, 0, len(toolCalls))
+	for _, toolCall := range toolCalls {
+		calls = append(calls, common.ToolCall{
+			ID:    toolCall.ID,
+			Name:  toolCall.Function.Name,
+			Input: json.RawMessage(toolCall.Function.Arguments),
+		})
+	}
 
-	// Handle tool calls if OpenAI wants to use a tool
-	if len(choice.Message.ToolCalls) > 0 {
-		toolCall := choice.Message.ToolCalls[0]
-		return c.HandleToolCall(ctx, toolCall.Function.Name, []byte(toolCall.Function.Arguments))
+	results := models.Message{Role: models.RoleUser}
+	for _, result := range c.HandleToolCalls(ctx, calls) {
+		if result.Err != nil {
+			results.Blocks = append(results.Blocks, models.ToolResultBlock(result.ID, fmt.Sprintf("Error: %v", result.Err), true))
+			continue
+		}
+		results.Blocks = append(results.Blocks, models.ToolResultBlock(result.ID, result.Output, false))
 	}
 
-	// Otherwise return the text content
-	return choice.Message.Content, nil
+	return results
+}
+
+// dataURL encodes the content of an image or document block as a data URL
+func dataURL(block models.ContentBlock) string {
+	return "data:" + block.MimeType + ";base64," + block.Data
 }
 
+// assistantMessage converts a response message to a Bond message, keeping
+// tool calls as tool_use blocks so they can be replayed in later requests.
+func assistantMessage(message OpenAIRespMessage) models.Message {
+	if len(message.ToolCalls) == 0 {
+		return models.Message{Role: models.RoleAssistant, Content: message.Content}
+	}
+
+	converted := models.Message{Role: models.RoleAssistant}
+	if message.Content != "" {
+		converted.Blocks = append(converted.Blocks, models.TextBlock(message.Content))
+	}
+
+	for _, toolCall := range message.ToolCalls {
+		// Keep the arguments valid JSON even if the model produced a malformed payload
+		input := json.RawMessage(toolCall.Function.Arguments)
+		if !json.Valid(input) {
+			input, _ = json.Marshal(toolCall.Function.Arguments)
+		}
+		converted.Blocks = append(converted.Blocks, models.ToolUseBlock(toolCall.ID, toolCall.Function.Name, input))
+	}
+
+	return converted
+}
@@ -3,11 +3,18 @@ package openai
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/devOpifex/bond/mcp"
 	"github.com/devOpifex/bond/models"
+	"github.com/devOpifex/bond/providers/common"
+	"github.com/devOpifex/bond/tools"
 )
 
 // MockTool is a simple tool implementation for testing
@@ -17,6 +24,16 @@ type MockTool struct {
 	schema      models.InputSchema
 }
 
+// IsNamespaced implements the ToolExecutor interface
+func (t *MockTool) IsNamespaced() bool {
+	return strings.Contains(t.name, "__")
+}
+
+// Namespace implements the ToolExecutor interface
+func (t *MockTool) Namespace(namespace string) {
+	t.name = namespace + "__" + t.name
+}
+
 // GetName implements the ToolExecutor interface
 func (t *MockTool) GetName() string {
 	return t.name
@@ -164,4 +181,577 @@ func TestSendMessageWithTemperature(t *testing.T) {
 	if response != expected {
 		t.Errorf("Expected response '%s', got '%s'", expected, response)
 	}
-}
\ No newline at end of file
+}
+
+// TestSendMessageStream tests that streamed chunks and tool call fragments are reassembled
+func TestSendMessageStream(t *testing.T) {
+	chunks := []string{
+		`{"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","content":"Checking"},"finish_reason":null}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"mock_tool","arguments":""}}]},"finish_reason":null}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]},"finish_reason":null}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Brussels\"}"}}]},"finish_reason":null}]}`,
+		`{"id":"c1","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		var requestBody OpenAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+
+		if !requestBody.Stream {
+			t.Error("Expected stream to be true")
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		// Answer the follow-up request carrying the tool result
+		if requests > 1 {
+			last := requestBody.Messages[len(requestBody.Messages)-1]
+			if last.Role != "tool" || last.ToolCallID != "call_1" {
+				t.Errorf("Expected tool message for call_1, got %+v", last)
+			}
+			w.Write([]byte(`data: {"id":"c2","choices":[{"index":0,"delta":{"content":" Done."},"finish_reason":"stop"}]}` + "\n\n"))
+			w.Write([]byte("data: [DONE]\n\n"))
+			return
+		}
+
+		for _, chunk := range chunks {
+			w.Write([]byte("data: " + chunk + "\n\n"))
+		}
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-api-key")
+	client.BaseURL = server.URL
+	client.RegisterTool(&MockTool{
+		name:        "mock_tool",
+		description: "A mock tool",
+		schema:      models.InputSchema{Type: "object"},
+	})
+
+	events, err := client.SendMessageStream(context.Background(), models.Message{
+		Role:    models.RoleUser,
+		Content: "What's the weather in Brussels?",
+	})
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+
+	var text, arguments strings.Builder
+	var toolName string
+	var last models.StreamEvent
+	for event := range events {
+		switch event.Type {
+		case models.StreamText:
+			text.WriteString(event.Text)
+		case models.StreamToolUse:
+			toolName = event.ToolName
+		case models.StreamToolInput:
+			arguments.WriteString(event.PartialInput)
+		case models.StreamError:
+			t.Fatalf("Unexpected stream error: %v", event.Err)
+		}
+		last = event
+	}
+
+	if text.String() != "Checking Done." {
+		t.Errorf("Expected streamed text 'Checking Done.', got '%s'", text.String())
+	}
+
+	if toolName != "mock_tool" {
+		t.Errorf("Expected tool call to 'mock_tool', got '%s'", toolName)
+	}
+
+	if arguments.String() != `{"city":"Brussels"}` {
+		t.Errorf("Expected reassembled arguments, got '%s'", arguments.String())
+	}
+
+	if last.Type != models.StreamStop || last.StopReason != "stop" {
+		t.Errorf("Expected final stop event with reason 'stop', got %+v", last)
+	}
+}
+
+// TestSendMessageStreamOutlivesTimeout tests that the client's total timeout
+// does not cut off a response streaming for longer
+func TestSendMessageStreamOutlivesTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"id":"c1","choices":[{"index":0,"delta":{"content":"Slow"},"finish_reason":null}]}` + "\n\n"))
+		w.(http.Flusher).Flush()
+
+		time.Sleep(150 * time.Millisecond)
+		w.Write([]byte(`data: {"id":"c1","choices":[{"index":0,"delta":{"content":" answer."},"finish_reason":"stop"}]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-api-key")
+	client.BaseURL = server.URL
+	client.HttpClient.Timeout = 50 * time.Millisecond
+
+	events, err := client.SendMessageStream(context.Background(), models.Message{Role: models.RoleUser, Content: "Hello"})
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+
+	var text strings.Builder
+	for event := range events {
+		switch event.Type {
+		case models.StreamText:
+			text.WriteString(event.Text)
+		case models.StreamError:
+			t.Fatalf("Unexpected stream error: %v", event.Err)
+		}
+	}
+
+	if text.String() != "Slow answer." {
+		t.Errorf("Expected the whole answer, got '%s'", text.String())
+	}
+}
+
+// TestSendMessageStreamIncomplete tests that streams cut short or failing mid-response are reported as errors
+func TestSendMessageStreamIncomplete(t *testing.T) {
+	partial := `data: {"id":"c1","choices":[{"index":0,"delta":{"content":"Partial"},"finish_reason":null}]}` + "\n\n"
+
+	tests := []struct {
+		name  string
+		body  string
+		check func(err error) bool
+	}{
+		{
+			name:  "truncated",
+			body:  partial,
+			check: func(err error) bool { return errors.Is(err, io.ErrUnexpectedEOF) },
+		},
+		{
+			name: "error chunk",
+			body: partial + `data: {"error":{"type":"server_error","message":"The server had an error"}}` + "\n\n",
+			check: func(err error) bool {
+				var apiErr *common.APIError
+				return errors.As(err, &apiErr) && apiErr.Provider == "OpenAI" && apiErr.Message == "The server had an error"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := NewClient("test-api-key")
+			client.BaseURL = server.URL
+
+			events, err := client.SendMessageStream(context.Background(), models.Message{Role: models.RoleUser, Content: "Hello"})
+			if err != nil {
+				t.Fatalf("Failed to open stream: %v", err)
+			}
+
+			var last models.StreamEvent
+			for event := range events {
+				last = event
+			}
+
+			if last.Type != models.StreamError || !tt.check(last.Err) {
+				t.Errorf("Unexpected last event %+v", last)
+			}
+		})
+	}
+}
+
+// TestSendMessageWithToolsLoop tests that every tool call is answered before the final response
+func TestSendMessageWithToolsLoop(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		var requestBody OpenAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			w.Write([]byte(`{
+				"id": "test-id",
+				"choices": [{
+					"index": 0,
+					"message": {
+						"role": "assistant",
+						"content": "",
+						"tool_calls": [
+							{"id": "call_1", "type": "function", "function": {"name": "mock_tool", "arguments": "{\"city\":\"Brussels\"}"}},
+							{"id": "call_2", "type": "function", "function": {"name": "mock_tool", "arguments": "{\"city\":\"Paris\"}"}}
+						]
+					},
+					"finish_reason": "tool_calls"
+				}]
+			}`))
+			return
+		}
+
+		// The follow-up must contain the assistant turn and one tool message per call
+		messages := requestBody.Messages
+		if len(messages) != 4 {
+			t.Errorf("Expected 4 messages in follow-up request, got %d", len(messages))
+			return
+		}
+
+		if len(messages[1].ToolCalls) != 2 {
+			t.Errorf("Expected assistant message with 2 tool calls, got %+v", messages[1])
+		}
+
+		for i, id := range []string{"call_1", "call_2"} {
+			message := messages[2+i]
+			if message.Role != "tool" || message.ToolCallID != id || message.Content != "MockTool executed successfully" {
+				t.Errorf("Unexpected tool message: %+v", message)
+			}
+		}
+
+		w.Write([]byte(`{
+			"id": "test-id",
+			"choices": [{"index": 0, "message": {"role": "assistant", "content": "Both are rainy."}, "finish_reason": "stop"}]
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-api-key")
+	client.BaseURL = server.URL
+	client.SetParallelToolCalls(true)
+	// An unset limit, as in a client built as a struct literal, means the default
+	client.MaxToolRounds = 0
+	client.RegisterTool(&MockTool{
+		name:        "mock_tool",
+		description: "A mock tool",
+		schema:      models.InputSchema{Type: "object"},
+	})
+
+	response, err := client.SendMessageWithTools(context.Background(), models.Message{
+		Role:    models.RoleUser,
+		Content: "What's the weather in Brussels and Paris?",
+	})
+	if err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	if response != "Both are rainy." {
+		t.Errorf("Expected final answer 'Both are rainy.', got '%s'", response)
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+}
+
+// TestHandleToolCallMCP tests that namespaced tools are routed to their MCP client
+func TestHandleToolCallMCP(t *testing.T) {
+	// An MCP client that is not running executes tools from its own registry
+	server := mcp.New("weather", nil)
+	server.RegisterTool(tools.NewTool(
+		"forecast",
+		"Get the forecast",
+		models.InputSchema{Type: "object"},
+		func(params map[string]any) (string, error) {
+			return "Rain in " + params["city"].(string), nil
+		},
+	))
+
+	// The provider only knows the namespaced tool, which must not be executed directly
+	tool := tools.NewTool(
+		"forecast",
+		"Get the forecast",
+		models.InputSchema{Type: "object"},
+		func(params map[string]any) (string, error) {
+			t.Error("Expected namespaced tool to be dispatched to its MCP client")
+			return "", nil
+		},
+	)
+	tool.Namespace("weather")
+
+	client := NewClient("test-api-key")
+	client.MCPs["weather"] = server
+	client.RegisterTool(tool)
+
+	result, err := client.HandleToolCall(context.Background(), "weather__forecast", json.RawMessage(`{"city":"Brussels"}`))
+	if err != nil {
+		t.Fatalf("Failed to call MCP tool: %v", err)
+	}
+
+	if result != "Rain in Brussels" {
+		t.Errorf("Expected result 'Rain in Brussels', got '%s'", result)
+	}
+}
+
+// TestSendConversation tests that the whole conversation is sent and the answer appended
+func TestSendConversation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestBody OpenAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+
+		messages := requestBody.Messages
+		if len(messages) != 4 {
+			t.Errorf("Expected system prompt and 3 messages, got %d messages", len(messages))
+		} else if messages[0].Role != models.RoleSystem || messages[0].Content != "Be brief." {
+			t.Errorf("Expected conversation system prompt first, got %+v", messages[0])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "test-id",
+			"choices": [{"index": 0, "message": {"role": "assistant", "content": "Your name is Ada."}, "finish_reason": "stop"}]
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-api-key")
+	client.BaseURL = server.URL
+	client.SetSystemPrompt("Overridden by the conversation")
+
+	conversation := models.NewConversation("Be brief.")
+	conversation.Append(
+		models.Message{Role: models.RoleUser, Content: "My name is Ada."},
+		models.Message{Role: models.RoleAssistant, Content: "Nice to meet you, Ada."},
+		models.Message{Role: models.RoleUser, Content: "What is my name?"},
+	)
+
+	response, err := client.SendConversation(context.Background(), conversation)
+	if err != nil {
+		t.Fatalf("Failed to send conversation: %v", err)
+	}
+
+	last, _ := conversation.Last()
+	if response != "Your name is Ada." || last.Content != response || last.Role != models.RoleAssistant {
+		t.Errorf("Expected the answer to be appended to the conversation, got %+v", last)
+	}
+}
+
+// TestSendMessageUsage tests that usage, including cached tokens, is reported
+func TestSendMessageUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"model": "gpt-4o-2024-08-06",
+			"choices": [{"message": {"role": "assistant", "content": "Hi!"}, "finish_reason": "stop"}],
+			"usage": {
+				"prompt_tokens": 1200,
+				"completion_tokens": 30,
+				"total_tokens": 1230,
+				"prompt_tokens_details": {"cached_tokens": 1024, "audio_tokens": 0},
+				"completion_tokens_details": {"reasoning_tokens": 0}
+			}
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-api-key")
+	client.BaseURL = server.URL
+
+	tracker := models.NewUsageTracker()
+	ctx := models.WithUsageTracker(context.Background(), tracker)
+
+	if _, err := client.SendMessage(ctx, models.Message{Role: models.RoleUser, Content: "Hello"}); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	expected := models.Usage{InputTokens: 176, OutputTokens: 30, CacheReadTokens: 1024}
+	if tracker.ByModel()["gpt-4o-2024-08-06"] != expected {
+		t.Errorf("Expected usage %+v, got %+v", expected, tracker.ByModel())
+	}
+}
+
+// TestConvertToolUseMessages tests that tool calls recorded on messages rather
+// than in blocks are sent as tool calls
+func TestConvertToolUseMessages(t *testing.T) {
+	messages := convertMessageList([]models.Message{
+		{Role: models.RoleUser, Content: "Weather in Brussels and Paris?"},
+		{Role: models.RoleAssistant, ToolUse: &models.ToolUse{ID: "call_1", Name: "get_weather", Input: map[string]any{"location": "Brussels"}}},
+		{Role: models.RoleFunction, Content: "5°C", ToolResult: &models.ToolResult{ToolUseID: "call_1", Name: "get_weather"}},
+		{Role: models.RoleAssistant, ToolUse: &models.ToolUse{ID: "call_2", Name: "get_weather", Input: `{"location":"Paris"}`}},
+		{Role: models.RoleFunction, Content: "8°C", ToolResult: &models.ToolResult{ToolUseID: "call_2", Name: "get_weather"}},
+	})
+
+	if len(messages) != 5 {
+		t.Fatalf("Expected 5 messages, got %d", len(messages))
+	}
+
+	for i, arguments := range map[int]string{1: `{"location":"Brussels"}`, 3: `{"location":"Paris"}`} {
+		calls := messages[i].ToolCalls
+		if len(calls) != 1 || calls[0].Function.Name != "get_weather" || calls[0].Function.Arguments != arguments {
+			t.Errorf("Expected a get_weather call with %s, got %+v", arguments, calls)
+			continue
+		}
+		if result := messages[i+1]; result.Role != roleTool || result.ToolCallID != calls[0].ID {
+			t.Errorf("Expected the result to answer %s, got %+v", calls[0].ID, result)
+		}
+	}
+}
+
+// TestConvertMediaBlocks tests that images and documents are sent as content parts
+func TestConvertMediaBlocks(t *testing.T) {
+	client := NewClient("test-api-key")
+	conversation := models.NewConversation("").Append(
+		models.Message{Role: models.RoleUser, Content: "Hello"},
+		models.Message{
+			Role: models.RoleUser,
+			Blocks: []models.ContentBlock{
+				models.TextBlock("What is in these?"),
+				models.ImageBlock("image/png", "iVBORw0KGgo="),
+				models.DocumentBlock("application/pdf", "JVBERi0xLjc=", "report.pdf"),
+				models.DocumentBlock("text/markdown", "IyBOb3Rlcw==", "notes.md"),
+			},
+		},
+		models.Message{
+			Role:   models.RoleUser,
+			Blocks: []models.ContentBlock{models.DocumentBlock("text/plain", "SGk=", "")},
+		},
+	)
+
+	data, err := json.Marshal(client.convertMessages(conversation))
+	if err != nil {
+		t.Fatalf("Failed to marshal messages: %v", err)
+	}
+
+	expected := `[{"role":"user","content":"Hello"},{"role":"user","content":[` +
+		`{"type":"text","text":"What is in these?"},` +
+		`{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw0KGgo="}},` +
+		`{"type":"file","file":{"filename":"report.pdf","file_data":"data:application/pdf;base64,JVBERi0xLjc="}},` +
+		`{"type":"text","text":"notes.md:\n# Notes"}]},` +
+		`{"role":"user","content":"Hi"}]`
+	if string(data) != expected {
+		t.Errorf("Unexpected messages:\n%s", data)
+	}
+}
+
+// TestSendMessageWithToolsOptions tests that generation options are mapped onto
+// the request and that a forced tool choice only applies to the first request
+func TestSendMessageWithToolsOptions(t *testing.T) {
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requestBody map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		requests = append(requests, requestBody)
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(requests) == 1 {
+			w.Write([]byte(`{
+				"id": "test-id",
+				"choices": [{
+					"index": 0,
+					"message": {
+						"role": "assistant",
+						"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "mock_tool", "arguments": "{}"}}]
+					},
+					"finish_reason": "tool_calls"
+				}]
+			}`))
+			return
+		}
+		w.Write([]byte(`{
+			"id": "test-id",
+			"choices": [{"index": 0, "message": {"role": "assistant", "content": "Done."}, "finish_reason": "stop"}]
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-api-key")
+	client.BaseURL = server.URL
+	client.RegisterTool(&MockTool{
+		name:        "mock_tool",
+		description: "A mock tool",
+		schema:      models.InputSchema{Type: "object"},
+	})
+
+	ctx := models.WithOptions(context.Background(), models.GenerationOptions{
+		Model:         "gpt-4o-mini",
+		Temperature:   models.Float(0.2),
+		TopP:          models.Float(0.9),
+		StopSequences: []string{"END"},
+		Seed:          models.Int(42),
+		UserID:        "user-1",
+		Metadata:      map[string]string{"team": "search"},
+		ToolChoice:    models.UseTool("mock_tool"),
+	})
+
+	if _, err := client.SendMessageWithTools(ctx, models.Message{Role: models.RoleUser, Content: "Go"}); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+
+	first := requests[0]
+	if first["model"] != "gpt-4o-mini" || first["temperature"] != 0.2 || first["top_p"] != 0.9 || first["seed"] != float64(42) {
+		t.Errorf("Expected model and sampling options in request, got %+v", first)
+	}
+	if stop, _ := first["stop"].([]any); len(stop) != 1 || stop[0] != "END" {
+		t.Errorf("Expected stop sequences in request, got %v", first["stop"])
+	}
+	if first["user"] != "user-1" {
+		t.Errorf("Expected user ID in request, got %v", first["user"])
+	}
+	if metadata, _ := first["metadata"].(map[string]any); metadata["team"] != "search" {
+		t.Errorf("Expected metadata in request, got %v", first["metadata"])
+	}
+	if first["store"] != true {
+		t.Errorf("Expected the completion to be stored along with its metadata, got %v", first["store"])
+	}
+
+	choice, _ := first["tool_choice"].(map[string]any)
+	function, _ := choice["function"].(map[string]any)
+	if choice["type"] != "function" || function["name"] != "mock_tool" {
+		t.Errorf("Expected forced tool choice, got %v", first["tool_choice"])
+	}
+
+	if requests[1]["tool_choice"] != "auto" {
+		t.Errorf("Expected tool choice to be relaxed in follow-up, got %v", requests[1]["tool_choice"])
+	}
+}
+
+// TestBuildRequestOptions tests tool choice mapping and unsupported options
+func TestBuildRequestOptions(t *testing.T) {
+	client := NewClient("test-api-key")
+	client.RegisterTool(&MockTool{name: "mock_tool", schema: models.InputSchema{Type: "object"}})
+	conversation := models.NewConversation("").Append(models.Message{Role: models.RoleUser, Content: "Go"})
+
+	request, err := client.buildRequest(conversation, true, models.GenerationOptions{
+		ToolChoice: models.ToolChoice{Mode: models.ToolChoiceAny},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	if request.ToolChoice != "required" {
+		t.Errorf("Expected tool choice any to map to required, got %v", request.ToolChoice)
+	}
+	if body, _ := json.Marshal(request); strings.Contains(string(body), `"store"`) {
+		t.Errorf("Expected completions without metadata not to be stored, got %s", body)
+	}
+
+	for _, opts := range []models.GenerationOptions{
+		{TopK: models.Int(40)},
+		{StopSequences: []string{"a", "b", "c", "d", "e"}},
+		{ToolChoice: models.UseTool("unknown_tool")},
+	} {
+		if _, err := client.buildRequest(conversation, true, opts); err == nil {
+			t.Errorf("Expected %+v to be rejected", opts)
+		}
+	}
+
+	client.Quirks.NoToolChoice = true
+	if _, err := client.buildRequest(conversation, true, models.GenerationOptions{
+		ToolChoice: models.ToolChoice{Mode: models.ToolChoiceNone},
+	}); err == nil {
+		t.Errorf("Expected tool choice to be rejected by a server without tool_choice")
+	}
+}
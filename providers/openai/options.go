@@ -0,0 +1,72 @@
+package openai
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// maxStopSequences is the number of stop sequences OpenAI accepts
+const maxStopSequences = 4
+
+// applyOptions applies the per-request generation options to the request,
+// rejecting those OpenAI does not support. Requests with metadata are stored,
+// as OpenAI only accepts metadata on stored completions.
+func (c *Client) applyOptions(request *OpenAIRequest, opts models.GenerationOptions) error {
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("invalid generation options: %w", err)
+	}
+
+	switch {
+	case opts.TopK != nil:
+		return errors.New("OpenAI does not support top_k")
+	case len(opts.StopSequences) > maxStopSequences:
+		return fmt.Errorf("OpenAI accepts at most %d stop sequences, got %d", maxStopSequences, len(opts.StopSequences))
+	case opts.ToolChoice.Mode != "" && c.Quirks.NoToolChoice:
+		return errors.New("the server does not support tool_choice")
+	}
+
+	if opts.Model != "" {
+		request.Model = opts.Model
+	}
+	if opts.MaxTokens > 0 {
+		request.MaxTokens = opts.MaxTokens
+	}
+	if opts.Temperature != nil {
+		request.Temperature = *opts.Temperature
+	}
+	request.TopP = opts.TopP
+	request.Stop = opts.StopSequences
+	request.Seed = opts.Seed
+	request.User = opts.UserID
+	request.Metadata = opts.Metadata
+	request.Store = len(opts.Metadata) > 0
+
+	var offered []string
+	for _, tool := range request.Tools {
+		offered = append(offered, tool.Function.Name)
+	}
+	if err := opts.ValidateToolChoice(offered); err != nil {
+		return err
+	}
+
+	// Without tools there is nothing to choose from
+	if len(request.Tools) == 0 {
+		return nil
+	}
+
+	switch opts.ToolChoice.Mode {
+	case models.ToolChoiceAuto, models.ToolChoiceNone:
+		request.ToolChoice = opts.ToolChoice.Mode
+	case models.ToolChoiceAny:
+		request.ToolChoice = "required"
+	case models.ToolChoiceTool:
+		request.ToolChoice = map[string]any{
+			"type":     "function",
+			"function": map[string]string{"name": opts.ToolChoice.Name},
+		}
+	}
+
+	return nil
+}
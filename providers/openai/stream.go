@@ -0,0 +1,266 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/devOpifex/bond/models"
+	"github.com/devOpifex/bond/providers/common"
+)
+
+// OpenAIStreamChunk represents a single chunk of a streamed OpenAI response
+type OpenAIStreamChunk struct {
+	ID      string               `json:"id"`
+	Object  string               `json:"object"`
+	Created int64                `json:"created"`
+	Model   string               `json:"model"`
+	Choices []OpenAIStreamChoice `json:"choices"`
+	Usage   *OpenAIUsage         `json:"usage,omitempty"`
+	Error   *OpenAIStreamError   `json:"error,omitempty"`
+}
+
+// OpenAIStreamError is an error sent in place of a chunk in the middle of a
+// streamed response
+type OpenAIStreamError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// OpenAIStreamChoice represents a choice in a streamed OpenAI response chunk
+type OpenAIStreamChoice struct {
+	Index                int                        `json:"index"`
+	Delta                OpenAIStreamDelta          `json:"delta"`
+	FinishReason         string                     `json:"finish_reason"`
+	ContentFilterResults map[string]json.RawMessage `json:"content_filter_results,omitempty"`
+}
+
+// OpenAIStreamDelta represents the incremental message content in a stream chunk
+type OpenAIStreamDelta struct {
+	Role      string                `json:"role,omitempty"`
+	Content   string                `json:"content,omitempty"`
+	ToolCalls []OpenAIToolCallDelta `json:"tool_calls,omitempty"`
+}
+
+// OpenAIToolCallDelta represents a fragment of a tool call in a stream chunk.
+// The ID and function name are only sent in the first fragment of each call,
+// later fragments carry pieces of the arguments and are matched by Index.
+type OpenAIToolCallDelta struct {
+	Index    int            `json:"index"`
+	ID       string         `json:"id,omitempty"`
+	Type     string         `json:"type,omitempty"`
+	Function OpenAIFuncCall `json:"function"`
+}
+
+// SendMessageStream sends a message to OpenAI with registered tools and streams the response.
+// Text and tool argument fragments are delivered on the returned channel as they arrive,
+// and tool calls are reassembled from their fragments before being executed.
+// This implements the models.Streamer interface.
+func (c *Client) SendMessageStream(ctx context.Context, message models.Message) (<-chan models.StreamEvent, error) {
+	conversation := models.NewConversation("").Append(message)
+
+	resp, err := c.openStream(ctx, conversation, models.OptionsFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan models.StreamEvent)
+	go func() {
+		defer close(events)
+		c.streamResponses(ctx, conversation, resp, events)
+	}()
+
+	return events, nil
+}
+
+// openStream sends a streaming chat completion request to the OpenAI API for
+// the given conversation and generation options, unless the budget in the
+// context is spent. The conversation is fitted first, see models.FitConversation.
+func (c *Client) openStream(ctx context.Context, conversation *models.Conversation, opts models.GenerationOptions) (*http.Response, error) {
+	if err := models.CheckBudget(ctx, conversation.Messages); err != nil {
+		return nil, err
+	}
+
+	if err := models.FitConversation(ctx, conversation); err != nil {
+		return nil, err
+	}
+
+	request, err := c.buildRequest(conversation, true, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Stream = true
+	if !c.Quirks.NoStreamOptions {
+		request.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.DoStreamRequest(ctx, common.HTTPRequest{
+		Method:  "POST",
+		URL:     c.BaseURL,
+		Headers: c.headers(),
+		Body:    jsonData,
+	})
+	if err != nil {
+		return nil, convertError(err)
+	}
+
+	return resp, nil
+}
+
+// streamResponses forwards the events of a streamed response to the events channel.
+// When the model finishes by calling tools, the calls are executed and their results
+// are sent back in a new streaming request on the same channel.
+func (c *Client) streamResponses(ctx context.Context, conversation *models.Conversation, resp *http.Response, events chan<- models.StreamEvent) {
+	for round := 0; ; round++ {
+		assistant, finishReason, err := c.readStream(ctx, resp.Body, events)
+		resp.Body.Close()
+		if err != nil {
+			common.SendStreamEvent(ctx, events, models.StreamEvent{Type: models.StreamError, Err: err})
+			return
+		}
+
+		conversation.Append(assistantMessage(assistant))
+
+		if len(assistant.ToolCalls) == 0 {
+			common.SendStreamEvent(ctx, events, models.StreamEvent{Type: models.StreamStop, StopReason: finishReason})
+			return
+		}
+
+		if common.ToolRoundsExceeded(round, c.MaxToolRounds) {
+			common.SendStreamEvent(ctx, events, models.StreamEvent{Type: models.StreamError, Err: common.ErrMaxToolRounds})
+			return
+		}
+
+		conversation.Append(c.runToolCalls(ctx, assistant.ToolCalls))
+
+		resp, err = c.openStream(ctx, conversation, models.OptionsFrom(ctx).FollowUp())
+		if err != nil {
+			common.SendStreamEvent(ctx, events, models.StreamEvent{Type: models.StreamError, Err: err})
+			return
+		}
+	}
+}
+
+// readStream parses the "data:" chunks of a streamed response, forwarding text and
+// tool argument fragments as they arrive. It returns the reassembled assistant
+// message, with tool calls in the order the model made them, and the finish reason.
+// The usage reported in the final chunk is sent to the context's usage tracker.
+// An error chunk fails the stream, and so does a stream ending without [DONE]
+// or a finish reason, with an error wrapping io.ErrUnexpectedEOF.
+func (c *Client) readStream(ctx context.Context, body io.Reader, events chan<- models.StreamEvent) (OpenAIRespMessage, string, error) {
+	reader := common.NewSSEReader(body)
+	calls := make(map[int]*OpenAIToolCall)
+
+	var content strings.Builder
+	var finishReason string
+
+	for {
+		sse, err := reader.Next()
+		if err == io.EOF {
+			if finishReason == "" {
+				return OpenAIRespMessage{}, "", common.NewTruncatedStreamError("OpenAI")
+			}
+			break
+		}
+		if err != nil {
+			return OpenAIRespMessage{}, "", fmt.Errorf("failed to read OpenAI stream: %w", err)
+		}
+
+		if strings.TrimSpace(sse.Data) == "[DONE]" {
+			break
+		}
+
+		var chunk OpenAIStreamChunk
+		if err := json.Unmarshal([]byte(sse.Data), &chunk); err != nil {
+			return OpenAIRespMessage{}, "", fmt.Errorf("failed to parse OpenAI stream chunk: %w", err)
+		}
+
+		if chunk.Error != nil {
+			return OpenAIRespMessage{}, "", common.NewStreamError("OpenAI", chunk.Error.Type, chunk.Error.Message)
+		}
+
+		// The usage arrives in a final chunk without choices
+		if chunk.Usage != nil {
+			c.reportUsage(ctx, chunk.Model, chunk.Usage)
+		}
+
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		choice := chunk.Choices[0]
+
+		if choice.Delta.Content != "" {
+			content.WriteString(choice.Delta.Content)
+			if !common.SendStreamEvent(ctx, events, models.StreamEvent{Type: models.StreamText, Text: choice.Delta.Content}) {
+				return OpenAIRespMessage{}, "", ctx.Err()
+			}
+		}
+
+		for _, delta := range choice.Delta.ToolCalls {
+			call, exists := calls[delta.Index]
+			if !exists {
+				call = &OpenAIToolCall{ID: delta.ID, Type: "function"}
+				calls[delta.Index] = call
+			}
+			if delta.Function.Name != "" {
+				call.Function.Name += delta.Function.Name
+			}
+
+			if !exists {
+				if !common.SendStreamEvent(ctx, events, models.StreamEvent{
+					Type:     models.StreamToolUse,
+					ToolID:   call.ID,
+					ToolName: call.Function.Name,
+				}) {
+					return OpenAIRespMessage{}, "", ctx.Err()
+				}
+			}
+
+			if delta.Function.Arguments != "" {
+				call.Function.Arguments += delta.Function.Arguments
+				if !common.SendStreamEvent(ctx, events, models.StreamEvent{
+					Type:         models.StreamToolInput,
+					ToolID:       call.ID,
+					PartialInput: delta.Function.Arguments,
+				}) {
+					return OpenAIRespMessage{}, "", ctx.Err()
+				}
+			}
+		}
+
+		if choice.FinishReason == finishContentFilter {
+			return OpenAIRespMessage{}, "", filteredCompletionError(choice.ContentFilterResults)
+		}
+
+		if choice.FinishReason != "" {
+			finishReason = choice.FinishReason
+		}
+	}
+
+	// Return the reassembled calls in the order the model made them
+	indexes := make([]int, 0, len(calls))
+	for index := range calls {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	assistant := OpenAIRespMessage{
+		Role:    models.RoleAssistant,
+		Content: content.String(),
+	}
+	for _, index := range indexes {
+		assistant.ToolCalls = append(assistant.ToolCalls, *calls[index])
+	}
+
+	return assistant, finishReason, nil
+}
@@ -0,0 +1,78 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// ResponseFormat constrains the format of a chat completion
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+// JSONSchema is the schema of a json_schema response format
+type JSONSchema struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Schema      models.InputSchema `json:"schema"`
+	Strict      bool               `json:"strict,omitempty"`
+}
+
+// Client must satisfy the models.StructuredResponder interface
+var _ models.StructuredResponder = (*Client)(nil)
+
+// SendStructured sends the conversation with a json_schema response format, so
+// that the model answers with JSON conforming to the schema. Tools are not
+// offered. The answer is appended to the conversation and returned as it is,
+// even when it is not valid JSON, such as when it was cut at the token limit,
+// leaving its validation to the caller.
+// This implements the models.StructuredResponder interface.
+func (c *Client) SendStructured(ctx context.Context, conversation *models.Conversation, format models.ResponseFormat) (string, error) {
+	if err := models.CheckBudget(ctx, conversation.Messages); err != nil {
+		return "", err
+	}
+
+	if err := models.FitConversation(ctx, conversation); err != nil {
+		return "", err
+	}
+
+	request, err := c.buildRequest(conversation, false, models.OptionsFrom(ctx))
+	if err != nil {
+		return "", err
+	}
+
+	request.ResponseFormat = &ResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &JSONSchema{
+			Name:        format.Name,
+			Description: format.Description,
+			Schema:      format.Schema,
+			Strict:      format.Strict,
+		},
+	}
+
+	openaiResp, err := c.createCompletion(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	c.reportUsage(ctx, openaiResp.Model, openaiResp.Usage)
+
+	if len(openaiResp.Choices) == 0 {
+		return "", fmt.Errorf("no choices in response")
+	}
+
+	choice := openaiResp.Choices[0]
+	if choice.FinishReason == finishContentFilter {
+		return "", filteredCompletionError(choice.ContentFilterResults)
+	}
+
+	if choice.Message.Refusal != "" {
+		return "", fmt.Errorf("model refused to respond: %s", choice.Message.Refusal)
+	}
+
+	conversation.Append(assistantMessage(choice.Message))
+	return choice.Message.Content, nil
+}
@@ -0,0 +1,65 @@
+//go:build ignore
+
+// gen downloads the rank files of the encodings into the ranks directory and
+// checks them against the hashes pinned by OpenAI's tiktoken library.
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// rankFiles maps the encodings to their URL and SHA-256 hash
+var rankFiles = map[string]struct {
+	url  string
+	hash string
+}{
+	"o200k_base": {
+		url:  "https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken",
+		hash: "446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d",
+	},
+	"cl100k_base": {
+		url:  "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken",
+		hash: "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7",
+	},
+}
+
+func main() {
+	for name, file := range rankFiles {
+		if err := download(name, file.url, file.hash); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// download fetches a rank file and writes it to ranks/<name>.tiktoken if its
+// hash matches
+func download(name, url, hash string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", name, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", name, err)
+	}
+
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != hash {
+		return fmt.Errorf("unexpected hash for %s: %s", name, got)
+	}
+
+	return os.WriteFile(filepath.Join("ranks", name+".tiktoken"), data, 0o644)
+}
@@ -0,0 +1,271 @@
+// Package tokenizer counts tokens offline the way OpenAI's models count them.
+// It implements the byte pair encoding of OpenAI's tiktoken library with the
+// o200k_base encoding of the GPT-4o, GPT-4.1, GPT-5 and o-series models and the
+// cl100k_base encoding of the GPT-4 and GPT-3.5 models.
+//
+// The rank files of the encodings are embedded from the ranks directory, where
+// go generate downloads them from OpenAI and checks their hashes. Get fails
+// with ErrNoRanks for an encoding whose rank file is missing.
+package tokenizer
+
+//go:generate go run gen.go
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Names of the supported encodings
+const (
+	O200kBase  = "o200k_base"
+	Cl100kBase = "cl100k_base"
+)
+
+// ErrNoRanks is returned when the rank file of an encoding is not bundled
+var ErrNoRanks = errors.New("BPE ranks are not bundled, run go generate in providers/openai/tokenizer")
+
+//go:embed ranks
+var ranksFS embed.FS
+
+// The encodings split text with the patterns of tiktoken, anchored to the start
+// of the text. \s is written out as the Unicode white space it matches there,
+// and \s+(?!\S), which RE2 cannot express, is handled by nextPiece.
+const (
+	space    = `\t\n\v\f\r\x{85}\p{Z}`
+	contract = `(?i:'s|'t|'re|'ve|'m|'ll|'d)`
+
+	cl100kPattern = contract +
+		`|[^\r\n\p{L}\p{N}]?\p{L}+` +
+		`|\p{N}{1,3}` +
+		`| ?[^` + space + `\p{L}\p{N}]+[\r\n]*` +
+		`|[` + space + `]*[\r\n]+` +
+		`|[` + space + `]+`
+
+	o200kPattern = `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+` + contract + `?` +
+		`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*` + contract + `?` +
+		`|\p{N}{1,3}` +
+		`| ?[^` + space + `\p{L}\p{N}]+[\r\n/]*` +
+		`|[` + space + `]*[\r\n]+` +
+		`|[` + space + `]+`
+)
+
+// patterns maps the encodings to their pre-tokenization pattern
+var patterns = map[string]*regexp.Regexp{
+	O200kBase:  regexp.MustCompile(`^(?:` + o200kPattern + `)`),
+	Cl100kBase: regexp.MustCompile(`^(?:` + cl100kPattern + `)`),
+}
+
+// modelPrefixes maps model name prefixes to their encoding, the more specific
+// prefixes first
+var modelPrefixes = []struct {
+	prefix   string
+	encoding string
+}{
+	{"gpt-4o", O200kBase},
+	{"chatgpt-4o", O200kBase},
+	{"gpt-4.1", O200kBase},
+	{"gpt-4.5", O200kBase},
+	{"gpt-5", O200kBase},
+	{"gpt-oss", O200kBase},
+	{"o1", O200kBase},
+	{"o3", O200kBase},
+	{"o4", O200kBase},
+	{"gpt-4", Cl100kBase},
+	{"gpt-3.5", Cl100kBase},
+	{"gpt-35", Cl100kBase},
+	{"text-embedding-3", Cl100kBase},
+	{"text-embedding-ada-002", Cl100kBase},
+}
+
+// Encoding splits text into the tokens of one of OpenAI's encodings
+type Encoding struct {
+	name    string
+	pattern *regexp.Regexp
+	ranks   map[string]int
+}
+
+// loaded holds an encoding once its rank file has been read
+type loaded struct {
+	once     sync.Once
+	encoding *Encoding
+	err      error
+}
+
+var encodings = map[string]*loaded{
+	O200kBase:  {},
+	Cl100kBase: {},
+}
+
+// EncodingForModel returns the name of the encoding of the model. Unknown
+// models, such as fine-tuned or Azure deployment names, get o200k_base, the
+// encoding of every model released since GPT-4o.
+func EncodingForModel(model string) string {
+	model = strings.TrimPrefix(model, "ft:")
+	for _, m := range modelPrefixes {
+		if strings.HasPrefix(model, m.prefix) {
+			return m.encoding
+		}
+	}
+	return O200kBase
+}
+
+// ForModel returns the encoding of the model, see EncodingForModel.
+func ForModel(model string) (*Encoding, error) {
+	return Get(EncodingForModel(model))
+}
+
+// Get returns the encoding with the given name, reading its rank file on first
+// use. It fails with ErrNoRanks when the rank file is not bundled.
+func Get(name string) (*Encoding, error) {
+	l, ok := encodings[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+
+	l.once.Do(func() {
+		data, err := ranksFS.ReadFile("ranks/" + name + ".tiktoken")
+		if errors.Is(err, fs.ErrNotExist) {
+			l.err = fmt.Errorf("%s: %w", name, ErrNoRanks)
+			return
+		}
+		if err != nil {
+			l.err = err
+			return
+		}
+
+		ranks, err := parseRanks(data)
+		if err != nil {
+			l.err = fmt.Errorf("%s: %w", name, err)
+			return
+		}
+		l.encoding = &Encoding{name: name, pattern: patterns[name], ranks: ranks}
+	})
+
+	return l.encoding, l.err
+}
+
+// parseRanks reads a tiktoken rank file, made of lines holding a base64 token
+// and its rank
+func parseRanks(data []byte) (map[string]int, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		encoded, rank, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid rank line %q", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token %q: %w", encoded, err)
+		}
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank %q: %w", rank, err)
+		}
+		ranks[string(token)] = n
+	}
+	return ranks, scanner.Err()
+}
+
+// Name returns the name of the encoding, e.g. "o200k_base".
+func (e *Encoding) Name() string {
+	return e.name
+}
+
+// Encode returns the tokens of the text. Special tokens such as <|endoftext|>
+// are encoded as ordinary text.
+func (e *Encoding) Encode(text string) []int {
+	var tokens []int
+	for text != "" {
+		n := e.nextPiece(text)
+		tokens = e.appendPiece(tokens, text[:n])
+		text = text[n:]
+	}
+	return tokens
+}
+
+// Count returns the number of tokens of the text.
+func (e *Encoding) Count(text string) int {
+	return len(e.Encode(text))
+}
+
+// nextPiece returns the length in bytes of the piece the text starts with.
+// A run of spaces followed by other text leaves its last space to that text,
+// as \s+(?!\S) does in tiktoken's patterns.
+func (e *Encoding) nextPiece(text string) int {
+	loc := e.pattern.FindStringIndex(text)
+	if loc == nil {
+		// Every character matches one of the alternatives, this is never reached
+		_, n := utf8.DecodeRuneInString(text)
+		return n
+	}
+
+	piece := text[:loc[1]]
+	if loc[1] == len(text) || !isSpace(piece) || strings.HasSuffix(piece, "\n") || strings.HasSuffix(piece, "\r") {
+		return loc[1]
+	}
+	_, last := utf8.DecodeLastRuneInString(piece)
+	if last == len(piece) {
+		return loc[1]
+	}
+	return loc[1] - last
+}
+
+// appendPiece appends the tokens of a piece, merging its bytes pair by pair
+// in the order of their rank
+func (e *Encoding) appendPiece(tokens []int, piece string) []int {
+	if rank, ok := e.ranks[piece]; ok {
+		return append(tokens, rank)
+	}
+
+	// bounds holds the start of every part, then the end of the piece
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+
+	for len(bounds) > 2 {
+		best, bestRank := -1, 0
+		for i := 0; i+2 < len(bounds); i++ {
+			rank, ok := e.ranks[piece[bounds[i]:bounds[i+2]]]
+			if ok && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+
+	for i := 0; i+1 < len(bounds); i++ {
+		tokens = append(tokens, e.ranks[piece[bounds[i]:bounds[i+1]]])
+	}
+	return tokens
+}
+
+// isSpace reports whether the text is made of white space only
+func isSpace(text string) bool {
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+package tokenizer
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// split returns the pieces the encoding splits text into
+func split(e *Encoding, text string) []string {
+	var pieces []string
+	for text != "" {
+		n := e.nextPiece(text)
+		pieces = append(pieces, text[:n])
+		text = text[n:]
+	}
+	return pieces
+}
+
+// TestEncodingForModel tests that models get the encoding they were trained with
+func TestEncodingForModel(t *testing.T) {
+	tests := map[string]string{
+		"gpt-4o":                 O200kBase,
+		"gpt-4o-mini-2024-07-18": O200kBase,
+		"gpt-4.1":                O200kBase,
+		"o3-mini":                O200kBase,
+		"gpt-4":                  Cl100kBase,
+		"gpt-4-turbo":            Cl100kBase,
+		"gpt-3.5-turbo":          Cl100kBase,
+		"ft:gpt-3.5-turbo:acme":  Cl100kBase,
+		"text-embedding-3-small": Cl100kBase,
+		"my-azure-deployment":    O200kBase,
+	}
+
+	for model, encoding := range tests {
+		if got := EncodingForModel(model); got != encoding {
+			t.Errorf("Expected %s to use %s, got %s", model, encoding, got)
+		}
+	}
+}
+
+// TestSplit tests that text is split into the pieces of tiktoken's patterns
+func TestSplit(t *testing.T) {
+	cl100k := &Encoding{pattern: patterns[Cl100kBase]}
+	o200k := &Encoding{pattern: patterns[O200kBase]}
+
+	tests := []struct {
+		encoding *Encoding
+		text     string
+		pieces   []string
+	}{
+		{cl100k, "Hello, world!", []string{"Hello", ",", " world", "!"}},
+		{cl100k, "I'll pay 12345 dollars", []string{"I", "'ll", " pay", " ", "123", "45", " dollars"}},
+		{cl100k, "if (x) {\n\treturn\n}", []string{"if", " (", "x", ")", " {\n", "\treturn", "\n", "}"}},
+		{cl100k, "one  two\n\n  three  ", []string{"one", " ", " two", "\n\n", " ", " three", "  "}},
+		{cl100k, "a  b", []string{"a", " ", " b"}},
+		{cl100k, "don't HelloWorld", []string{"don", "'t", " HelloWorld"}},
+		{o200k, "don't HelloWorld", []string{"don't", " Hello", "World"}},
+		{o200k, "path/to/\nfile", []string{"path", "/to", "/\n", "file"}},
+	}
+
+	for _, test := range tests {
+		pieces := split(test.encoding, test.text)
+		if !slices.Equal(pieces, test.pieces) {
+			t.Errorf("Expected %q to split into %q, got %q", test.text, test.pieces, pieces)
+		}
+	}
+}
+
+// TestEncode tests that the bytes of a piece are merged in the order of their rank
+func TestEncode(t *testing.T) {
+	e := &Encoding{
+		pattern: patterns[Cl100kBase],
+		ranks: map[string]int{
+			"a": 0, "b": 1, "c": 2, " ": 3,
+			"bc": 4, "ab": 5, "abc": 6, " a": 7,
+		},
+	}
+
+	tests := []struct {
+		text   string
+		tokens []int
+	}{
+		{"", nil},
+		{"abc", []int{6}},
+		// bc merges first, then ab, then the two parts making abc
+		{"abcab", []int{6, 5}},
+		{"cab abc", []int{2, 5, 3, 6}},
+	}
+
+	for _, test := range tests {
+		tokens := e.Encode(test.text)
+		if !slices.Equal(tokens, test.tokens) {
+			t.Errorf("Expected %q to encode to %v, got %v", test.text, test.tokens, tokens)
+		}
+	}
+}
+
+// TestParseRanks tests that rank files are read
+func TestParseRanks(t *testing.T) {
+	ranks, err := parseRanks([]byte("IQ== 0\naGVsbG8= 1\nIHdvcmxk 2\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse ranks: %v", err)
+	}
+	if ranks["!"] != 0 || ranks["hello"] != 1 || ranks[" world"] != 2 || len(ranks) != 3 {
+		t.Errorf("Unexpected ranks %v", ranks)
+	}
+
+	if _, err := parseRanks([]byte("IQ==0\n")); err == nil {
+		t.Errorf("Expected an error for a line without a rank")
+	}
+}
+
+// TestKnownTokens tests the encodings against tokens computed by tiktoken
+func TestKnownTokens(t *testing.T) {
+	tests := []struct {
+		encoding string
+		text     string
+		tokens   []int
+		count    int
+	}{
+		{Cl100kBase, "hello world", []int{15339, 1917}, 2},
+		{Cl100kBase, "tiktoken is great!", []int{83, 1609, 5963, 374, 2294, 0}, 6},
+		{O200kBase, "hello world", []int{24912, 2375}, 2},
+		{O200kBase, "tiktoken is great!", []int{83, 8251, 2488, 382, 2212, 0}, 6},
+	}
+
+	for _, test := range tests {
+		e, err := Get(test.encoding)
+		if errors.Is(err, ErrNoRanks) {
+			t.Skipf("Skipping, %v", err)
+		}
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", test.encoding, err)
+		}
+
+		if tokens := e.Encode(test.text); !slices.Equal(tokens, test.tokens) {
+			t.Errorf("Expected %s to encode %q to %v, got %v", test.encoding, test.text, test.tokens, tokens)
+		}
+		if count := e.Count(test.text); count != test.count {
+			t.Errorf("Expected %s to count %d tokens in %q, got %d", test.encoding, test.count, test.text, count)
+		}
+	}
+}
+
+// TestGetUnknown tests that unknown encodings are refused
+func TestGetUnknown(t *testing.T) {
+	if _, err := Get("p50k_base"); err == nil {
+		t.Errorf("Expected an error for an unsupported encoding")
+	}
+}
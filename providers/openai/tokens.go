@@ -0,0 +1,226 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"unicode"
+
+	"github.com/devOpifex/bond/models"
+	"github.com/devOpifex/bond/providers/openai/tokenizer"
+)
+
+// Token costs of chat completions, from OpenAI's guide to counting tokens
+const (
+	// tokensPerMessage frames every message with its role
+	tokensPerMessage = 3
+
+	// tokensPerToolCall frames every tool call of an assistant message
+	tokensPerToolCall = 3
+
+	// replyPriming primes the assistant's reply
+	replyPriming = 3
+
+	// imageTokens is what a high detail 1024x1024 image costs
+	imageTokens = 765
+
+	// bytesPerFileToken is the average number of bytes of a file per token
+	bytesPerFileToken = 4
+
+	// lettersPerToken is the length of the ASCII words covered by one token
+	lettersPerToken = 8
+)
+
+// CountTokens counts the input tokens of the messages and tool definitions
+// offline, with the tokenizer encoding of the client's model. Images count as
+// a high detail 1024x1024 image and files by their size. When the encoding's
+// ranks are not bundled, text is estimated with EstimateTextTokens instead.
+// This implements part of the models.Provider interface.
+func (c *Client) CountTokens(ctx context.Context, messages []models.Message, tools []models.ToolExecutor) (int, error) {
+	count := EstimateTextTokens
+	if encoding, err := tokenizer.ForModel(c.Model); err == nil {
+		count = encoding.Count
+	}
+
+	total := replyPriming
+	for _, message := range convertMessageList(messages) {
+		total += messageTokens(message, count)
+	}
+
+	for _, tool := range tools {
+		converted, err := convertTool(tool)
+		if err != nil {
+			return 0, err
+		}
+		definition, err := json.Marshal(converted)
+		if err != nil {
+			return 0, err
+		}
+		total += count(string(definition))
+	}
+
+	return total, nil
+}
+
+// messageTokens counts the tokens of a message in OpenAI's format, counting
+// its text with count
+func messageTokens(message OpenAIMessage, count func(string) int) int {
+	total := tokensPerMessage + count(message.Role)
+
+	if len(message.Parts) == 0 {
+		total += count(message.Content)
+	}
+	for _, part := range message.Parts {
+		switch {
+		case part.ImageURL != nil:
+			total += imageTokens
+		case part.File != nil:
+			_, data, _ := strings.Cut(part.File.FileData, ",")
+			total += count(part.File.Filename) + base64.StdEncoding.DecodedLen(len(data))/bytesPerFileToken
+		default:
+			total += count(part.Text)
+		}
+	}
+
+	for _, call := range message.ToolCalls {
+		total += tokensPerToolCall + count(call.Function.Name) + count(call.Function.Arguments)
+	}
+
+	return total
+}
+
+// EstimateTextTokens estimates the tokens of a piece of text for the GPT-4o
+// and GPT-4 families without their vocabulary. CountTokens uses it when the
+// ranks of the tokenizer package are not bundled. The text is split like
+// OpenAI's encodings split it, into contractions, words with their leading
+// space, groups of up to three digits, runs of punctuation and runs of
+// whitespace, and pieces are costed: one token per eight letters of ASCII
+// words, one per letter outside ASCII and one per two punctuation marks.
+// Estimates are close for English prose and err on the high side for long
+// words, code and other languages.
+func EstimateTextTokens(text string) int {
+	total := 0
+	for _, piece := range splitPieces(text) {
+		total += pieceTokens([]rune(piece))
+	}
+	return total
+}
+
+// splitPieces splits text into the pieces tokens never cross
+func splitPieces(text string) []string {
+	var pieces []string
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		n := pieceLength(runes[i:])
+		pieces = append(pieces, string(runes[i:i+n]))
+		i += n
+	}
+	return pieces
+}
+
+// pieceLength returns the length in runes of the piece the text starts with,
+// trying the alternatives of the encodings' pattern in order
+func pieceLength(r []rune) int {
+	if n := contractionLength(r); n > 0 {
+		return n
+	}
+
+	// A word, optionally preceded by a space or punctuation
+	start := 0
+	if len(r) > 1 && !isLineBreak(r[0]) && !unicode.IsLetter(r[0]) && !unicode.IsNumber(r[0]) && unicode.IsLetter(r[1]) {
+		start = 1
+	}
+	if unicode.IsLetter(r[start]) {
+		return start + runLength(r[start:], unicode.IsLetter)
+	}
+
+	if unicode.IsNumber(r[0]) {
+		return min(runLength(r, unicode.IsNumber), 3)
+	}
+
+	// Punctuation, optionally preceded by a space, along with the line breaks following it
+	start = 0
+	if len(r) > 1 && r[0] == ' ' && isPunctuation(r[1]) {
+		start = 1
+	}
+	if isPunctuation(r[start]) {
+		n := start + runLength(r[start:], isPunctuation)
+		return n + runLength(r[n:], isLineBreak)
+	}
+
+	// Whitespace up to the last line break, leaving the space before a word
+	// or punctuation to it
+	n := runLength(r, unicode.IsSpace)
+	for i := n - 1; i >= 0; i-- {
+		if isLineBreak(r[i]) {
+			return i + 1
+		}
+	}
+	if n > 1 && n < len(r) {
+		return n - 1
+	}
+	return n
+}
+
+// contractionLength returns the length of the contraction the text starts
+// with, such as 's or 'll, or 0
+func contractionLength(r []rune) int {
+	if len(r) < 2 || r[0] != '\'' {
+		return 0
+	}
+	if len(r) > 2 {
+		switch strings.ToLower(string(r[1:3])) {
+		case "ll", "ve", "re":
+			return 3
+		}
+	}
+	switch unicode.ToLower(r[1]) {
+	case 's', 'd', 'm', 't':
+		return 2
+	}
+	return 0
+}
+
+// pieceTokens estimates the tokens of a piece
+func pieceTokens(piece []rune) int {
+	ascii, other, punctuation := 0, 0, 0
+	for _, r := range piece {
+		switch {
+		case unicode.IsLetter(r) && r < unicode.MaxASCII:
+			ascii++
+		case unicode.IsLetter(r):
+			other++
+		case isPunctuation(r):
+			punctuation++
+		}
+	}
+
+	switch {
+	case ascii+other > 0:
+		return (ascii+lettersPerToken-1)/lettersPerToken + other
+	case punctuation > 0:
+		return (punctuation + 1) / 2
+	}
+	// Numbers and whitespace
+	return 1
+}
+
+// runLength returns the number of leading runes satisfying the predicate
+func runLength(r []rune, predicate func(rune) bool) int {
+	n := 0
+	for n < len(r) && predicate(r[n]) {
+		n++
+	}
+	return n
+}
+
+// isLineBreak reports whether r ends a line
+func isLineBreak(r rune) bool {
+	return r == '\n' || r == '\r'
+}
+
+// isPunctuation reports whether r is neither whitespace, a letter nor a digit
+func isPunctuation(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
@@ -0,0 +1,84 @@
+package openai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// TestSplitPieces tests that text is split like OpenAI's encodings split it
+func TestSplitPieces(t *testing.T) {
+	tests := []struct {
+		text   string
+		pieces []string
+	}{
+		{"Hello, world!", []string{"Hello", ",", " world", "!"}},
+		{"I'll pay 12345 dollars", []string{"I", "'ll", " pay", " ", "123", "45", " dollars"}},
+		{"if (x) {\n\treturn\n}", []string{"if", " (", "x", ")", " {\n", "\treturn", "\n", "}"}},
+		{"one  two\n\n  three", []string{"one", " ", " two", "\n\n", " ", " three"}},
+	}
+
+	for _, test := range tests {
+		pieces := splitPieces(test.text)
+		if strings.Join(pieces, "|") != strings.Join(test.pieces, "|") {
+			t.Errorf("Expected %q to split into %q, got %q", test.text, test.pieces, pieces)
+		}
+	}
+}
+
+// TestEstimateTextTokens tests the cost of the pieces. The English sentences
+// match their cl100k counts; the long word costs 3 tokens where cl100k uses
+// fewer, as estimates err on the high side.
+func TestEstimateTextTokens(t *testing.T) {
+	tests := []struct {
+		text   string
+		tokens int
+	}{
+		{"", 0},
+		{"Hello, world!", 4},
+		{"The quick brown fox jumps over the lazy dog.", 10},
+		{"internationalization", 3},
+		{"你好", 2},
+	}
+
+	for _, test := range tests {
+		if tokens := EstimateTextTokens(test.text); tokens != test.tokens {
+			t.Errorf("Expected %q to be estimated at %d tokens, got %d", test.text, test.tokens, tokens)
+		}
+	}
+}
+
+// TestCountTokens tests that messages are estimated offline with their framing
+func TestCountTokens(t *testing.T) {
+	client := NewClient("test-api-key")
+	client.SetSystemPrompt("Not counted")
+
+	tokens, err := client.CountTokens(context.Background(), []models.Message{
+		{Role: models.RoleSystem, Content: "Be brief"},
+		{Role: models.RoleUser, Content: "Hello, world!"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("CountTokens failed: %v", err)
+	}
+
+	// Each message is framed and its role counted, and the reply is primed
+	want := replyPriming + (tokensPerMessage + 1 + 2) + (tokensPerMessage + 1 + 4)
+	if tokens != want {
+		t.Errorf("Expected %d tokens, got %d", want, tokens)
+	}
+
+	withTools, err := client.CountTokens(context.Background(), []models.Message{
+		{Role: models.RoleUser, Blocks: []models.ContentBlock{
+			models.TextBlock("Hello, world!"),
+			models.ImageBlock("image/png", "iVBORw0KGgo="),
+		}},
+	}, []models.ToolExecutor{&MockTool{name: "get_weather", description: "Get the weather", schema: models.InputSchema{Type: "object"}}})
+	if err != nil {
+		t.Fatalf("CountTokens failed: %v", err)
+	}
+	if withTools <= replyPriming+tokensPerMessage+1+4+imageTokens {
+		t.Errorf("Expected the image and tool definition to be counted, got %d", withTools)
+	}
+}
@@ -0,0 +1,393 @@
+// Package router implements a composite provider that spreads requests over
+// several backends and fails over between them. A Router satisfies
+// models.Provider, so agents and chains can use it like any single provider,
+// for instance to fall back to OpenAI or a local model when Claude is overloaded.
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/devOpifex/bond/models"
+	"github.com/devOpifex/bond/providers/common"
+)
+
+// Policy decides which member of a Router receives a request first.
+// Whatever the policy, a request that fails with an error accepted by the
+// router's Failover function is retried on the remaining members.
+type Policy int
+
+const (
+	// Failover always tries the members in the order they were added
+	Failover Policy = iota
+
+	// RoundRobin rotates the first member tried on every request
+	RoundRobin
+
+	// Weighted picks the first member at random, in proportion to the weights
+	Weighted
+
+	// Latency tries the member with the lowest average response time first.
+	// Members that have not answered yet are tried before the others so that
+	// every member gets measured, and members that recently failed over are
+	// tried last until their cool-down ends.
+	Latency
+)
+
+// latencySmoothing is the weight of the newest sample in the moving average of
+// response times used by the Latency policy
+const latencySmoothing = 0.3
+
+// defaultCooldown is how long a member that failed is tried last by the Latency policy
+const defaultCooldown = 30 * time.Second
+
+// member is a provider of the router with its routing state
+type member struct {
+	provider     models.Provider
+	weight       int
+	latency      time.Duration
+	coolingUntil time.Time
+}
+
+// Router is a models.Provider that routes each request to one of its members
+// according to a Policy, failing over to the others on transient errors.
+// Tools, system prompts and MCP servers registered with the router are
+// registered with every member so that they stay interchangeable.
+// A Router is safe for concurrent use as long as it is not reconfigured
+// while requests are in flight.
+type Router struct {
+	// Policy decides which member is tried first
+	Policy Policy
+
+	// Failover reports whether an error should be retried on the next member.
+	// It defaults to ShouldFailover.
+	Failover func(error) bool
+
+	// Cooldown is how long the Latency policy tries a member last after it
+	// failed with an error accepted by Failover. It defaults to 30 seconds.
+	Cooldown time.Duration
+
+	members []*member
+	mu      sync.Mutex
+	next    int
+}
+
+// Router must satisfy the models.Provider and models.Streamer interfaces
+var (
+	_ models.Provider = (*Router)(nil)
+	_ models.Streamer = (*Router)(nil)
+)
+
+// New creates a router over the given providers, each with a weight of 1.
+// The order of the providers is the failover order.
+func New(policy Policy, providers ...models.Provider) *Router {
+	router := &Router{
+		Policy:   policy,
+		Failover: ShouldFailover,
+		Cooldown: defaultCooldown,
+	}
+
+	for _, provider := range providers {
+		router.Add(provider, 1)
+	}
+
+	return router
+}
+
+// Add appends a provider with the given weight, used by the Weighted policy,
+// and returns the router for method chaining.
+func (r *Router) Add(provider models.Provider, weight int) *Router {
+	if weight < 1 {
+		weight = 1
+	}
+	r.members = append(r.members, &member{provider: provider, weight: weight})
+	return r
+}
+
+// Providers returns the members of the router in failover order.
+func (r *Router) Providers() []models.Provider {
+	providers := make([]models.Provider, len(r.members))
+	for i, m := range r.members {
+		providers[i] = m.provider
+	}
+	return providers
+}
+
+// ShouldFailover is the default Failover function. It fails over on rate
+// limits, overloaded and failing servers, rejected credentials, and network
+// errors and responses cut short, as another backend may well succeed. Every
+// other error is returned to the caller, since no other backend would fare
+// better: invalid requests, content filters, cancelled contexts and errors
+// raised locally, such as invalid generation options, exhausted budgets,
+// runaway tool loops or failing tools, which would run again on the next member.
+func ShouldFailover(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *common.APIError
+	if !errors.As(err, &apiErr) {
+		return isTransportError(err)
+	}
+
+	var rateLimit *common.RateLimitError
+	var overloaded *common.OverloadedError
+	var auth *common.AuthError
+	switch {
+	case errors.As(err, &rateLimit), errors.As(err, &overloaded), errors.As(err, &auth):
+		return true
+	}
+
+	// Errors reported mid-stream carry no status code
+	return apiErr.StatusCode == 0 || apiErr.StatusCode >= 500
+}
+
+// isTransportError reports whether err comes from the connection to the
+// backend: network and dial errors, failed round trips and truncated responses
+func isTransportError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// order returns the members in the order they should be tried for a request
+func (r *Router) order() []*member {
+	if len(r.members) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ordered := make([]*member, 0, len(r.members))
+
+	first := 0
+	switch r.Policy {
+	case RoundRobin:
+		first = r.next % len(r.members)
+		r.next++
+	case Weighted:
+		first = r.pickWeighted()
+	case Latency:
+		now := time.Now()
+		ordered = append(ordered, r.members...)
+		sort.SliceStable(ordered, func(i, j int) bool {
+			coolingI, coolingJ := now.Before(ordered[i].coolingUntil), now.Before(ordered[j].coolingUntil)
+			if coolingI != coolingJ {
+				return coolingJ
+			}
+			return ordered[i].latency < ordered[j].latency
+		})
+		return ordered
+	}
+
+	// Start with the chosen member and fail over in order, wrapping around
+	for i := range r.members {
+		ordered = append(ordered, r.members[(first+i)%len(r.members)])
+	}
+
+	return ordered
+}
+
+// pickWeighted returns the index of a member chosen in proportion to the weights
+func (r *Router) pickWeighted() int {
+	total := 0
+	for _, m := range r.members {
+		total += m.weight
+	}
+
+	pick := rand.IntN(total)
+	for i, m := range r.members {
+		if pick < m.weight {
+			return i
+		}
+		pick -= m.weight
+	}
+
+	return 0
+}
+
+// recordLatency folds the duration of a successful request into the member's average
+func (r *Router) recordLatency(m *member, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if m.latency == 0 {
+		m.latency = elapsed
+		return
+	}
+	m.latency = time.Duration(latencySmoothing*float64(elapsed) + (1-latencySmoothing)*float64(m.latency))
+}
+
+// recordFailure starts the cool-down of a member whose request failed over
+func (r *Router) recordFailure(m *member) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m.coolingUntil = time.Now().Add(r.Cooldown)
+}
+
+// route sends a request to the members in turn until one succeeds or fails
+// with an error that should not be retried elsewhere.
+func (r *Router) route(ctx context.Context, send func(models.Provider) (string, error)) (string, error) {
+	members := r.order()
+	if len(members) == 0 {
+		return "", errors.New("router has no providers")
+	}
+
+	var err error
+	for _, m := range members {
+		start := time.Now()
+
+		var response string
+		response, err = send(m.provider)
+		if err == nil {
+			r.recordLatency(m, time.Since(start))
+			return response, nil
+		}
+
+		if !r.Failover(err) {
+			return "", err
+		}
+		r.recordFailure(m)
+	}
+
+	return "", fmt.Errorf("all providers failed, last error: %w", err)
+}
+
+// SendMessage sends the message to the first available member.
+// This implements part of the models.Provider interface.
+func (r *Router) SendMessage(ctx context.Context, message models.Message) (string, error) {
+	return r.route(ctx, func(p models.Provider) (string, error) {
+		return p.SendMessage(ctx, message)
+	})
+}
+
+// SendMessageWithTools sends the message, along with the registered tools, to
+// the first available member.
+// This implements part of the models.Provider interface.
+func (r *Router) SendMessageWithTools(ctx context.Context, message models.Message) (string, error) {
+	return r.route(ctx, func(p models.Provider) (string, error) {
+		return p.SendMessageWithTools(ctx, message)
+	})
+}
+
+// SendConversation sends the conversation to the first available member.
+// Turns appended by a member that failed part way through a tool loop are kept,
+// so the next member carries on from there without running the tools again.
+// This implements part of the models.Provider interface.
+func (r *Router) SendConversation(ctx context.Context, conversation *models.Conversation) (string, error) {
+	return r.route(ctx, func(p models.Provider) (string, error) {
+		return p.SendConversation(ctx, conversation)
+	})
+}
+
+// SendMessageStream streams the response of the first available member that
+// supports streaming. Failover only happens while opening the stream; errors
+// reported once events have been delivered end the stream as usual.
+// This implements the models.Streamer interface.
+func (r *Router) SendMessageStream(ctx context.Context, message models.Message) (<-chan models.StreamEvent, error) {
+	var err error
+	for _, m := range r.order() {
+		streamer, ok := m.provider.(models.Streamer)
+		if !ok {
+			continue
+		}
+
+		var events <-chan models.StreamEvent
+		events, err = streamer.SendMessageStream(ctx, message)
+		if err == nil {
+			return events, nil
+		}
+
+		if !r.Failover(err) {
+			return nil, err
+		}
+		r.recordFailure(m)
+	}
+
+	if err == nil {
+		return nil, errors.New("router has no provider that supports streaming")
+	}
+	return nil, fmt.Errorf("all providers failed, last error: %w", err)
+}
+
+// RegisterTool registers the tool with every member.
+// This implements part of the models.Provider interface.
+func (r *Router) RegisterTool(tool models.ToolExecutor) {
+	for _, m := range r.members {
+		m.provider.RegisterTool(tool)
+	}
+}
+
+// SetSystemPrompt sets the system prompt of every member.
+// This implements part of the models.Provider interface.
+func (r *Router) SetSystemPrompt(prompt string) {
+	for _, m := range r.members {
+		m.provider.SetSystemPrompt(prompt)
+	}
+}
+
+// SetModel sets the model of the first member only, as model names are
+// specific to each backend; configure the other members directly.
+// This implements part of the models.Provider interface.
+func (r *Router) SetModel(model string) {
+	if len(r.members) > 0 {
+		r.members[0].provider.SetModel(model)
+	}
+}
+
+// SetMaxTokens sets the maximum response length of every member.
+// This implements part of the models.Provider interface.
+func (r *Router) SetMaxTokens(tokens int) {
+	for _, m := range r.members {
+		m.provider.SetMaxTokens(tokens)
+	}
+}
+
+// SetTemperature sets the temperature of every member.
+// This implements part of the models.Provider interface.
+func (r *Router) SetTemperature(temperature float64) {
+	for _, m := range r.members {
+		m.provider.SetTemperature(temperature)
+	}
+}
+
+// CountTokens counts the tokens with the first member that succeeds, in the
+// order members were added, regardless of the strategy: counts depend on each
+// backend's tokenizer, so they are taken from the preferred backend.
+// This implements part of the models.Provider interface.
+func (r *Router) CountTokens(ctx context.Context, messages []models.Message, tools []models.ToolExecutor) (int, error) {
+	if len(r.members) == 0 {
+		return 0, errors.New("router has no providers")
+	}
+
+	var err error
+	for _, m := range r.members {
+		var count int
+		count, err = m.provider.CountTokens(ctx, messages, tools)
+		if err == nil {
+			return count, nil
+		}
+	}
+
+	return 0, fmt.Errorf("all providers failed, last error: %w", err)
+}
+
+// RegisterMCP registers the MCP server with every member, each of which starts
+// its own instance of the server. It stops at the first member that fails.
+// This implements part of the models.Provider interface.
+func (r *Router) RegisterMCP(command string, args []string) error {
+	for i, m := range r.members {
+		if err := m.provider.RegisterMCP(command, args); err != nil {
+			return fmt.Errorf("failed to register MCP with provider %d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/devOpifex/bond/models"
+	"github.com/devOpifex/bond/providers/common"
+	"github.com/devOpifex/bond/tools"
+)
+
+// stubProvider answers with its name or fails with err, recording its configuration
+type stubProvider struct {
+	name         string
+	err          error
+	delay        time.Duration
+	calls        int
+	tools        []string
+	systemPrompt string
+	model        string
+}
+
+func (s *stubProvider) SendMessage(ctx context.Context, message models.Message) (string, error) {
+	s.calls++
+	time.Sleep(s.delay)
+	if s.err != nil {
+		return "", s.err
+	}
+	return s.name, nil
+}
+
+func (s *stubProvider) SendMessageWithTools(ctx context.Context, message models.Message) (string, error) {
+	return s.SendMessage(ctx, message)
+}
+
+func (s *stubProvider) SendConversation(ctx context.Context, conversation *models.Conversation) (string, error) {
+	return s.SendMessage(ctx, models.Message{})
+}
+
+func (s *stubProvider) RegisterTool(tool models.ToolExecutor) {
+	s.tools = append(s.tools, tool.GetName())
+}
+func (s *stubProvider) SetSystemPrompt(prompt string)      { s.systemPrompt = prompt }
+func (s *stubProvider) SetModel(model string)              { s.model = model }
+func (s *stubProvider) SetMaxTokens(tokens int)            {}
+func (s *stubProvider) SetTemperature(temperature float64) {}
+func (s *stubProvider) RegisterMCP(command string, args []string) error {
+	return nil
+}
+func (s *stubProvider) CountTokens(ctx context.Context, messages []models.Message, tools []models.ToolExecutor) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	return len(messages), nil
+}
+
+func overloaded() error {
+	return common.NewAPIError("Claude", 529, nil, []byte(`{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`))
+}
+
+func TestFailover(t *testing.T) {
+	claude := &stubProvider{name: "claude", err: overloaded()}
+	openai := &stubProvider{name: "openai"}
+	ollama := &stubProvider{name: "ollama"}
+
+	router := New(Failover, claude, openai, ollama)
+
+	response, err := router.SendMessage(context.Background(), models.Message{Role: models.RoleUser, Content: "Hello"})
+	if err != nil {
+		t.Fatalf("Expected failover to succeed, got %v", err)
+	}
+
+	if response != "openai" {
+		t.Errorf("Expected response from openai, got '%s'", response)
+	}
+
+	if claude.calls != 1 || openai.calls != 1 || ollama.calls != 0 {
+		t.Errorf("Unexpected calls: claude %d, openai %d, ollama %d", claude.calls, openai.calls, ollama.calls)
+	}
+}
+
+func TestFailoverStopsOnInvalidRequest(t *testing.T) {
+	invalid := common.NewAPIError("Claude", 400, nil, []byte(`{"type": "error", "error": {"type": "invalid_request_error", "message": "messages: field required"}}`))
+	claude := &stubProvider{name: "claude", err: invalid}
+	openai := &stubProvider{name: "openai"}
+
+	router := New(Failover, claude, openai)
+
+	_, err := router.SendMessage(context.Background(), models.Message{Role: models.RoleUser, Content: "Hello"})
+
+	var invalidErr *common.InvalidRequestError
+	if !errors.As(err, &invalidErr) {
+		t.Fatalf("Expected InvalidRequestError, got %v", err)
+	}
+
+	if openai.calls != 0 {
+		t.Errorf("Expected no failover on invalid requests")
+	}
+}
+
+func TestFailoverStopsOnLocalError(t *testing.T) {
+	claude := &stubProvider{name: "claude", err: fmt.Errorf("invalid generation options: %w", errors.New("temperature must be between 0 and 2"))}
+	openai := &stubProvider{name: "openai"}
+
+	router := New(Failover, claude, openai)
+
+	if _, err := router.SendMessage(context.Background(), models.Message{Role: models.RoleUser, Content: "Hello"}); err == nil {
+		t.Fatalf("Expected the validation error to be returned")
+	}
+
+	if openai.calls != 0 {
+		t.Errorf("Expected no failover on errors raised locally")
+	}
+}
+
+func TestAllProvidersFail(t *testing.T) {
+	refused := &url.Error{Op: "Post", URL: "http://localhost:11434/api/chat", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}
+	router := New(Failover, &stubProvider{err: overloaded()}, &stubProvider{err: refused})
+
+	_, err := router.SendMessage(context.Background(), models.Message{Role: models.RoleUser, Content: "Hello"})
+	if err == nil || !strings.HasPrefix(err.Error(), "all providers failed, last error: ") || !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	router := New(RoundRobin, &stubProvider{name: "a"}, &stubProvider{name: "b"}, &stubProvider{name: "c"})
+
+	var responses []string
+	for i := 0; i < 4; i++ {
+		response, err := router.SendMessage(context.Background(), models.Message{Role: models.RoleUser, Content: "Hello"})
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		responses = append(responses, response)
+	}
+
+	expected := []string{"a", "b", "c", "a"}
+	for i := range expected {
+		if responses[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, responses)
+		}
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	heavy := &stubProvider{name: "heavy"}
+	light := &stubProvider{name: "light"}
+
+	router := New(Weighted).Add(heavy, 9).Add(light, 1)
+
+	for i := 0; i < 1000; i++ {
+		if _, err := router.SendMessage(context.Background(), models.Message{Role: models.RoleUser, Content: "Hello"}); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	if heavy.calls < 800 || light.calls < 50 {
+		t.Errorf("Expected roughly a 9:1 split, got %d:%d", heavy.calls, light.calls)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	slow := &stubProvider{name: "slow", delay: 20 * time.Millisecond}
+	fast := &stubProvider{name: "fast", delay: time.Millisecond}
+
+	router := New(Latency, slow, fast)
+
+	// Both members are measured first, after which the fastest is preferred
+	for i := 0; i < 4; i++ {
+		if _, err := router.SendMessage(context.Background(), models.Message{Role: models.RoleUser, Content: "Hello"}); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	if slow.calls != 1 || fast.calls != 3 {
+		t.Errorf("Expected 1 call to slow and 3 to fast, got %d and %d", slow.calls, fast.calls)
+	}
+}
+
+// TestLatencyCooldown tests that a failing member, never measured, is pushed down the order
+func TestLatencyCooldown(t *testing.T) {
+	failing := &stubProvider{name: "failing", err: overloaded()}
+	healthy := &stubProvider{name: "healthy", delay: time.Millisecond}
+
+	router := New(Latency, failing, healthy)
+	router.Cooldown = 100 * time.Millisecond
+
+	for i := 0; i < 3; i++ {
+		response, err := router.SendMessage(context.Background(), models.Message{Role: models.RoleUser, Content: "Hello"})
+		if err != nil || response != "healthy" {
+			t.Fatalf("Expected healthy to answer, got '%s', %v", response, err)
+		}
+	}
+
+	if failing.calls != 1 || healthy.calls != 3 {
+		t.Errorf("Expected 1 call to failing and 3 to healthy, got %d and %d", failing.calls, healthy.calls)
+	}
+
+	// Once the cool-down is over the failing member is tried again
+	time.Sleep(router.Cooldown)
+	router.SendMessage(context.Background(), models.Message{Role: models.RoleUser, Content: "Hello"})
+	if failing.calls != 2 {
+		t.Errorf("Expected failing to be tried again after its cool-down, got %d calls", failing.calls)
+	}
+}
+
+func TestForwardsConfiguration(t *testing.T) {
+	first := &stubProvider{}
+	second := &stubProvider{}
+
+	router := New(Failover, first, second)
+	router.SetSystemPrompt("Be brief")
+	router.SetModel("claude-3-5-haiku-latest")
+	router.RegisterTool(tools.NewTool("search", "Search the web", models.InputSchema{Type: "object"}, func(params map[string]any) (string, error) {
+		return "", nil
+	}))
+
+	for _, p := range []*stubProvider{first, second} {
+		if p.systemPrompt != "Be brief" {
+			t.Errorf("Expected system prompt to be forwarded, got '%s'", p.systemPrompt)
+		}
+		if len(p.tools) != 1 || p.tools[0] != "search" {
+			t.Errorf("Expected tool to be forwarded, got %v", p.tools)
+		}
+	}
+
+	if first.model != "claude-3-5-haiku-latest" || second.model != "" {
+		t.Errorf("Expected the model to be set on the first provider only")
+	}
+}
+
+// TestCountTokens tests that tokens are counted by the first member that succeeds
+func TestCountTokens(t *testing.T) {
+	router := New(RoundRobin, &stubProvider{err: overloaded()}, &stubProvider{})
+
+	tokens, err := router.CountTokens(context.Background(), []models.Message{{Role: models.RoleUser, Content: "Hello"}}, nil)
+	if err != nil || tokens != 1 {
+		t.Errorf("Expected the second member's count, got %d, %v", tokens, err)
+	}
+}
@@ -2,7 +2,10 @@ package reasoning
 
 import (
 	"context"
+	"errors"
 	"testing"
+
+	"github.com/devOpifex/bond/models"
 )
 
 func TestChain(t *testing.T) {
@@ -97,4 +100,84 @@ func TestWorkflowCompatibility(t *testing.T) {
 	if result != expected {
 		t.Errorf("Expected result to be '%s', got '%s'", expected, result)
 	}
-}
\ No newline at end of file
+}
+
+func TestChainUsage(t *testing.T) {
+	chain := NewChain()
+
+	chain.Add(WithProcessor("First", "Reports usage", func(ctx context.Context, input string) (string, error) {
+		models.ReportUsage(ctx, "gpt-4o", models.Usage{InputTokens: 10, OutputTokens: 5})
+		models.ReportUsage(ctx, "gpt-4o", models.Usage{InputTokens: 20, OutputTokens: 5})
+		return input, nil
+	})).Then(WithProcessor("Second", "Reports usage", func(ctx context.Context, input string) (string, error) {
+		models.ReportUsage(ctx, "claude-3-5-haiku", models.Usage{InputTokens: 1, OutputTokens: 1})
+		return input, nil
+	}))
+
+	outer := models.NewUsageTracker()
+	if _, err := chain.Execute(models.WithUsageTracker(context.Background(), outer), "Hello"); err != nil {
+		t.Fatalf("Chain execution failed: %v", err)
+	}
+
+	if chain.Usage().Total() != (models.Usage{InputTokens: 31, OutputTokens: 11}) {
+		t.Errorf("Unexpected chain usage %+v", chain.Usage().Total())
+	}
+
+	if chain.StepUsage()["First"] != (models.Usage{InputTokens: 30, OutputTokens: 10}) {
+		t.Errorf("Unexpected usage for first step %+v", chain.StepUsage()["First"])
+	}
+
+	if outer.Total() != chain.Usage().Total() {
+		t.Errorf("Expected usage to be reported to the caller's tracker, got %+v", outer.Total())
+	}
+}
+
+func TestChainStepUsageSharedName(t *testing.T) {
+	chain := NewChain()
+
+	report := func(ctx context.Context, input string) (string, error) {
+		models.ReportUsage(ctx, "gpt-4o", models.Usage{InputTokens: 10, OutputTokens: 5})
+		return input, nil
+	}
+	chain.Add(WithProcessor("Refine", "Reports usage", report)).
+		Then(WithProcessor("Refine", "Reports usage", report))
+
+	if _, err := chain.Execute(context.Background(), "Hello"); err != nil {
+		t.Fatalf("Chain execution failed: %v", err)
+	}
+
+	if chain.StepUsage()["Refine"] != chain.Usage().Total() {
+		t.Errorf("Expected steps sharing a name to add up to %+v, got %+v", chain.Usage().Total(), chain.StepUsage()["Refine"])
+	}
+}
+
+func TestChainBudget(t *testing.T) {
+	chain := NewChain()
+
+	ran := 0
+	spend := func(ctx context.Context, input string) (string, error) {
+		ran++
+		models.ReportUsage(ctx, "gpt-4o", models.Usage{InputTokens: 80, OutputTokens: 20})
+		return input + "!", nil
+	}
+
+	chain.Add(WithProcessor("First", "Spends tokens", spend)).
+		Then(WithProcessor("Second", "Spends tokens", spend)).
+		Then(WithProcessor("Third", "Spends tokens", spend))
+
+	ctx := models.WithBudget(context.Background(), &models.Budget{MaxTokens: 200})
+	_, err := chain.Execute(ctx, "Hello")
+
+	var exceeded *models.BudgetExceededError
+	if !errors.As(err, &exceeded) {
+		t.Fatalf("Expected BudgetExceededError, got %v", err)
+	}
+
+	if ran != 2 {
+		t.Errorf("Expected 2 steps to run, got %d", ran)
+	}
+
+	if len(exceeded.Transcript) != 3 || exceeded.Transcript[2].Content != "Hello!!" {
+		t.Errorf("Expected the input and the completed steps' outputs, got %+v", exceeded.Transcript)
+	}
+}
@@ -30,8 +30,11 @@ type ReactAgent struct {
 	// systemPrompt contains instructions that guide the AI model's behavior
 	systemPrompt string
 
-	// messages stores the conversation history for context
-	messages []models.Message
+	// conversation stores the conversation history for context
+	conversation *models.Conversation
+
+	// onThinking receives the model's reasoning, see SetThinkingHandler
+	onThinking func(iteration int, thinking string)
 }
 
 // NewReactAgent creates a new React agent with the specified provider.
@@ -42,7 +45,7 @@ func NewReactAgent(provider models.Provider) *ReactAgent {
 		provider:      provider,
 		tools:         make(map[string]models.ToolExecutor),
 		maxIterations: 10,
-		messages:      []models.Message{},
+		conversation:  models.NewConversation(defaultReactPrompt),
 		systemPrompt:  defaultReactPrompt,
 	}
 }
@@ -68,51 +71,54 @@ func (ra *ReactAgent) SetSystemPrompt(prompt string) {
 	ra.systemPrompt = prompt
 }
 
+// SetThinkingHandler registers a function receiving the reasoning the model
+// produced in each iteration, for providers with extended thinking enabled.
+// The reasoning is kept out of the responses parsed for tool calls and answers.
+func (ra *ReactAgent) SetThinkingHandler(handler func(iteration int, thinking string)) {
+	ra.onThinking = handler
+}
+
+// Conversation returns the conversation of the most recent Process call,
+// including the model's responses and the results of the tools it used.
+func (ra *ReactAgent) Conversation() *models.Conversation {
+	return ra.conversation
+}
+
 // Process implements the Agent interface and can be used as a step in a Chain.
 // It executes the React pattern, alternating between model reasoning and tool execution
 // until a final response is reached or the maximum iterations limit is hit.
 // This method handles the entire conversation flow, tool execution, and context management.
+// When the context carries a budget, it is checked before every request and the
+// executed tools count against it.
 func (ra *ReactAgent) Process(ctx context.Context, input string) (string, error) {
-	// Reset messages for this new conversation
-	ra.messages = []models.Message{
-		// Don't include system message in the messages array
-		// System prompt is set separately on the provider
-		{
-			Role:    models.RoleUser,
-			Content: input,
-		},
-	}
+	// Start a new conversation, the system prompt travels with it
+	// so the provider's own configuration is left untouched
+	ra.conversation = models.NewConversation(ra.systemPrompt).Append(models.Message{
+		Role:    models.RoleUser,
+		Content: input,
+	})
 
 	// Register all tools with the provider
 	for _, tool := range ra.tools {
 		ra.provider.RegisterTool(tool)
 	}
 
-	// Set the system prompt for the provider
-	ra.provider.SetSystemPrompt(ra.systemPrompt)
-
 	var finalResponse string
 
 	// Main React loop
 	for i := 0; i < ra.maxIterations; i++ {
-		// Get the last message to send to the provider
-		lastMessage := ra.messages[len(ra.messages)-1]
-
-		// Create a context that includes the full message history
-		ctxWithHistory := context.WithValue(ctx, "message_history", ra.messages)
+		// Stop before sending another request once the budget is spent
+		if err := models.CheckBudget(ctx, ra.conversation.Messages); err != nil {
+			return "", err
+		}
 
-		// Get next thought from the model
-		response, err := ra.provider.SendMessageWithTools(ctxWithHistory, lastMessage)
+		// Get next thought from the model, which appends its response to the conversation
+		sent := len(ra.conversation.Messages)
+		response, err := ra.provider.SendConversation(ctx, ra.conversation)
 		if err != nil {
 			return "", fmt.Errorf("provider error: %w", err)
 		}
-
-		// Add the assistant's response to the message history
-		assistantMessage := models.Message{
-			Role:    models.RoleAssistant,
-			Content: response,
-		}
-		ra.messages = append(ra.messages, assistantMessage)
+		ra.reportThinking(i, ra.conversation.Messages[sent:])
 
 		// Parse response to extract tool calls
 		toolUse, actionText, isFinalResponse := parseResponse(response)
@@ -129,7 +135,7 @@ func (ra *ReactAgent) Process(ctx context.Context, input string) (string, error)
 			tool, exists := ra.tools[toolUse.Name]
 			if !exists {
 				toolResult := fmt.Sprintf("Error: Tool '%s' not found", toolUse.Name)
-				ra.messages = append(ra.messages, models.Message{
+				ra.conversation.Append(models.Message{
 					Role:       models.RoleFunction,
 					Content:    toolResult,
 					ToolResult: &models.ToolResult{Name: toolUse.Name, Result: toolResult},
@@ -142,7 +148,7 @@ func (ra *ReactAgent) Process(ctx context.Context, input string) (string, error)
 			inputBytes, err := json.Marshal(toolUse.Input)
 			if err != nil {
 				toolResult := fmt.Sprintf("Error: Invalid tool input: %v", err)
-				ra.messages = append(ra.messages, models.Message{
+				ra.conversation.Append(models.Message{
 					Role:       models.RoleFunction,
 					Content:    toolResult,
 					ToolResult: &models.ToolResult{Name: toolUse.Name, Result: toolResult},
@@ -152,7 +158,7 @@ func (ra *ReactAgent) Process(ctx context.Context, input string) (string, error)
 
 			if err := json.Unmarshal(inputBytes, &inputJSON); err != nil {
 				toolResult := fmt.Sprintf("Error: Invalid tool input JSON: %v", err)
-				ra.messages = append(ra.messages, models.Message{
+				ra.conversation.Append(models.Message{
 					Role:       models.RoleFunction,
 					Content:    toolResult,
 					ToolResult: &models.ToolResult{Name: toolUse.Name, Result: toolResult},
@@ -160,11 +166,14 @@ func (ra *ReactAgent) Process(ctx context.Context, input string) (string, error)
 				continue
 			}
 
-			// Execute the tool
+			// Execute the tool, unless the budget allows no more tool calls
+			if err := models.StartToolCall(ctx); err != nil {
+				return "", err
+			}
 			result, err := tool.Execute(inputJSON)
 			if err != nil {
 				toolResult := fmt.Sprintf("Error executing tool: %v", err)
-				ra.messages = append(ra.messages, models.Message{
+				ra.conversation.Append(models.Message{
 					Role:       models.RoleFunction,
 					Content:    toolResult,
 					ToolResult: &models.ToolResult{Name: toolUse.Name, Result: toolResult},
@@ -172,22 +181,37 @@ func (ra *ReactAgent) Process(ctx context.Context, input string) (string, error)
 				continue
 			}
 
-			// Store the assistant message for context
-			_ = context.WithValue(ctx, "original_message", assistantMessage)
-
 			// Add the tool result to the message history
 			functionMessage := models.Message{
 				Role:       models.RoleFunction,
 				Content:    result,
 				ToolResult: &models.ToolResult{Name: toolUse.Name, Result: result},
 			}
-			ra.messages = append(ra.messages, functionMessage)
+			ra.conversation.Append(functionMessage)
 		}
 	}
 
 	return finalResponse, nil
 }
 
+// reportThinking passes the reasoning of the assistant's new turns to the thinking handler
+func (ra *ReactAgent) reportThinking(iteration int, messages []models.Message) {
+	if ra.onThinking == nil {
+		return
+	}
+
+	var thinking string
+	for _, msg := range messages {
+		if msg.Role == models.RoleAssistant {
+			thinking += msg.Thinking()
+		}
+	}
+
+	if thinking != "" {
+		ra.onThinking(iteration, thinking)
+	}
+}
+
 // AsStep returns the ReactAgent as a Chain Step for easy integration into workflows.
 // This allows the React agent to be used as a component in a larger reasoning pipeline.
 // The name and description parameters are used to identify the step in the chain.
@@ -0,0 +1,40 @@
+package reasoning
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devOpifex/bond/bondtest"
+)
+
+// TestReactAgentThinking tests that the model's reasoning is passed to the
+// thinking handler and kept out of the answer
+func TestReactAgentThinking(t *testing.T) {
+	provider := bondtest.NewFakeProvider(bondtest.Response{
+		Text:     "Brussels is in Belgium.",
+		Thinking: "The user asks about geography.",
+	})
+
+	agent := NewReactAgent(provider)
+
+	var logged []string
+	agent.SetThinkingHandler(func(iteration int, thinking string) {
+		if iteration != 0 {
+			t.Errorf("Expected thinking from the first iteration, got %d", iteration)
+		}
+		logged = append(logged, thinking)
+	})
+
+	answer, err := agent.Process(context.Background(), "Where is Brussels?")
+	if err != nil {
+		t.Fatalf("Failed to process: %v", err)
+	}
+
+	if answer != "Brussels is in Belgium." {
+		t.Errorf("Expected the answer without reasoning, got '%s'", answer)
+	}
+
+	if len(logged) != 1 || logged[0] != "The user asks about geography." {
+		t.Errorf("Expected the reasoning to be logged once, got %v", logged)
+	}
+}
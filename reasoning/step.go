@@ -3,6 +3,8 @@ package reasoning
 import (
 	"context"
 	"fmt"
+
+	"github.com/devOpifex/bond/models"
 )
 
 // StepResult represents the result of a reasoning step
@@ -24,6 +26,10 @@ type StepExecutor = func(ctx context.Context, input string) (string, error)
 // Chain represents a sequence of steps that can be executed in order
 type Chain struct {
 	steps []*Step
+
+	// usage and stepUsage track the tokens used by the most recent execution
+	usage     *models.UsageTracker
+	stepUsage map[string]models.Usage
 }
 
 // NewChain creates a new reasoning chain
@@ -39,23 +45,56 @@ func (c *Chain) Add(step *Step) *Chain {
 	return c
 }
 
-// Execute runs all steps in the chain in sequence
+// Execute runs all steps in the chain in sequence.
+// The tokens used by providers called from the steps are tracked and can be
+// read with Usage and StepUsage once Execute returns.
+// When the context carries a budget, it is checked before every step; the
+// transcript of the budget error holds the input and the outputs of the steps
+// that completed.
 func (c *Chain) Execute(ctx context.Context, input string) (string, error) {
 	currentInput := input
+	transcript := []models.Message{{Role: models.RoleUser, Content: input}}
+
+	c.usage = models.NewUsageTracker()
+	c.stepUsage = make(map[string]models.Usage, len(c.steps))
+	ctx = models.WithUsageTracker(ctx, c.usage)
 
 	for _, step := range c.steps {
-		output, err := step.Execute(ctx, currentInput)
+		if err := models.CheckBudget(ctx, transcript); err != nil {
+			return "", err
+		}
+
+		stepTracker := models.NewUsageTracker()
+		output, err := step.Execute(models.WithUsageTracker(ctx, stepTracker), currentInput)
+		usage := c.stepUsage[step.Name]
+		usage.Add(stepTracker.Total())
+		c.stepUsage[step.Name] = usage
 		if err != nil {
 			return "", fmt.Errorf("error executing step %s: %w", step.Name, err)
 		}
 
 		// Use this step's output as input to the next step
 		currentInput = output
+		transcript = append(transcript, models.Message{Role: models.RoleAssistant, Content: output})
 	}
 
 	return currentInput, nil
 }
 
+// Usage returns the tracker holding the tokens used by the most recent
+// execution of the chain, aggregated over all steps and tool loops.
+// Use its Cost method with a price table to compute what the run cost.
+// It returns nil if the chain has not been executed.
+func (c *Chain) Usage() *models.UsageTracker {
+	return c.usage
+}
+
+// StepUsage returns the tokens used by each step of the most recent execution,
+// keyed by step name. Steps sharing a name share an entry, adding up their usage.
+func (c *Chain) StepUsage() map[string]models.Usage {
+	return c.stepUsage
+}
+
 // Then is an alias for Add to provide a fluent API
 func (c *Chain) Then(step *Step) *Chain {
 	return c.Add(step)
@@ -0,0 +1,230 @@
+package structured
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/devOpifex/bond/models"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// SchemaFor derives the JSON Schema of T, which must be a struct. Fields are
+// named after their json tags and are required unless they are pointers or
+// tagged omitempty. Additional properties are never allowed. Two more tags
+// refine the schema:
+//
+//	type Review struct {
+//		Summary string   `json:"summary" description:"One sentence summary"`
+//		Rating  string   `json:"rating" enum:"good,neutral,bad"`
+//		Tags    []string `json:"tags,omitempty"`
+//	}
+func SchemaFor[T any]() (models.InputSchema, error) {
+	return Schema(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// Schema derives the JSON Schema of a struct type, see SchemaFor.
+func Schema(t reflect.Type) (models.InputSchema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t == timeType {
+		return models.InputSchema{}, fmt.Errorf("structured output must be a struct, got %s", t)
+	}
+
+	object, err := objectProperty(t, map[reflect.Type]bool{})
+	if err != nil {
+		return models.InputSchema{}, err
+	}
+
+	return models.InputSchema{
+		Type:                 "object",
+		Properties:           object.Properties,
+		Required:             object.Required,
+		AdditionalProperties: object.AdditionalProperties,
+	}, nil
+}
+
+// property derives the schema of a single value. Types being expanded are
+// tracked in seen, since recursive types cannot be described without references.
+func property(t reflect.Type, seen map[reflect.Type]bool) (models.Property, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return models.Property{Type: "string", Format: "date-time"}, nil
+	case t == rawMessageType:
+		return models.Property{}, fmt.Errorf("json.RawMessage has no schema")
+	case t.Kind() != reflect.Struct && t.Implements(textMarshalerType):
+		return models.Property{Type: "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return models.Property{Type: "string"}, nil
+	case reflect.Bool:
+		return models.Property{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return models.Property{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return models.Property{Type: "number"}, nil
+	case reflect.Slice, reflect.Array:
+		// Byte slices are encoded as base64 strings
+		if t.Elem().Kind() == reflect.Uint8 {
+			return models.Property{Type: "string"}, nil
+		}
+		items, err := property(t.Elem(), seen)
+		if err != nil {
+			return models.Property{}, err
+		}
+		return models.Property{Type: "array", Items: &items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return models.Property{}, fmt.Errorf("map keys must be strings, got %s", t)
+		}
+		return models.Property{Type: "object"}, nil
+	case reflect.Struct:
+		return objectProperty(t, seen)
+	}
+
+	return models.Property{}, fmt.Errorf("type %s has no JSON Schema", t)
+}
+
+// objectProperty derives the schema of a struct, flattening embedded structs
+// like encoding/json does
+func objectProperty(t reflect.Type, seen map[reflect.Type]bool) (models.Property, error) {
+	if seen[t] {
+		return models.Property{}, fmt.Errorf("recursive type %s is not supported", t)
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	disallow := false
+	object := models.Property{
+		Type:                 "object",
+		Properties:           make(map[string]models.Property),
+		AdditionalProperties: &disallow,
+	}
+
+	if err := addFields(&object, t, seen); err != nil {
+		return models.Property{}, err
+	}
+
+	return object, nil
+}
+
+// addFields adds the fields of struct t to the object schema
+func addFields(object *models.Property, t reflect.Type, seen map[reflect.Type]bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name, omitEmpty, skip := jsonName(field)
+		if skip {
+			continue
+		}
+
+		fieldType := field.Type
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			for fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				if err := addFields(object, fieldType, seen); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		prop, err := property(fieldType, seen)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+
+		prop.Description = field.Tag.Get("description")
+		if enum := field.Tag.Get("enum"); enum != "" {
+			for _, value := range strings.Split(enum, ",") {
+				prop.Enum = append(prop.Enum, strings.TrimSpace(value))
+			}
+		}
+
+		object.Properties[name] = prop
+		if !omitEmpty && field.Type.Kind() != reflect.Pointer {
+			object.Required = append(object.Required, name)
+		}
+	}
+
+	return nil
+}
+
+// jsonName returns the JSON name of a field, whether it is omitted when empty,
+// and whether it is skipped altogether
+func jsonName(field reflect.StructField) (string, bool, bool) {
+	if !field.IsExported() && !field.Anonymous {
+		return "", false, true
+	}
+
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+
+	omitEmpty := false
+	for _, option := range strings.Split(options, ",") {
+		if option == "omitempty" || option == "omitzero" {
+			omitEmpty = true
+		}
+	}
+
+	return name, omitEmpty, false
+}
+
+// isStrict reports whether every property of the schema is required and no
+// object allows additional properties, as strict schema adherence requires
+func isStrict(schema models.InputSchema) bool {
+	object := models.Property{
+		Type:                 schema.Type,
+		Properties:           schema.Properties,
+		Required:             schema.Required,
+		AdditionalProperties: schema.AdditionalProperties,
+	}
+	return strictProperty(object)
+}
+
+// strictProperty checks a property of the schema for strictness
+func strictProperty(prop models.Property) bool {
+	switch prop.Type {
+	case "array":
+		return prop.Items == nil || strictProperty(*prop.Items)
+	case "object":
+		if prop.AdditionalProperties == nil || *prop.AdditionalProperties {
+			return false
+		}
+		if len(prop.Required) != len(prop.Properties) {
+			return false
+		}
+		for _, nested := range prop.Properties {
+			if !strictProperty(nested) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,212 @@
+// Package structured decodes model responses into Go values. It derives a JSON
+// Schema from the target type, asks the model for JSON conforming to it using
+// the provider's native mechanism where there is one, validates the response
+// and re-prompts the model with the problems found until it gets it right.
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// DefaultMaxAttempts is the number of responses requested before giving up
+// when Options.MaxAttempts is not set
+const DefaultMaxAttempts = 3
+
+// defaultDescription describes the response format to the model
+const defaultDescription = "Respond with the requested information in this format."
+
+// validName matches the names providers accept for response formats and tools
+var validName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Options configures how a structured response is generated
+type Options struct {
+	// Name identifies the response format, defaults to the name of the type
+	Name string
+
+	// Description tells the model what the response is for
+	Description string
+
+	// MaxAttempts is the number of responses requested, including re-prompts,
+	// before failing with a *ValidationError. Defaults to DefaultMaxAttempts.
+	MaxAttempts int
+}
+
+// ValidationError is returned when the model did not produce a valid response
+// within the allowed number of attempts
+type ValidationError struct {
+	// Attempts is the number of responses requested
+	Attempts int
+
+	// Response is the last response received
+	Response string
+
+	// Problems lists what was wrong with the last response
+	Problems []string
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("no valid response after %d attempts: %s", e.Attempts, strings.Join(e.Problems, "; "))
+}
+
+// Generate sends the prompt to the provider and decodes the response into a T,
+// which must be a struct. See GenerateFrom.
+func Generate[T any](ctx context.Context, provider models.Provider, prompt string) (T, error) {
+	conversation := models.NewConversation("").Append(models.Message{
+		Role:    models.RoleUser,
+		Content: prompt,
+	})
+	return GenerateFrom[T](ctx, provider, conversation, Options{})
+}
+
+// GenerateFrom continues the conversation and decodes the response into a T,
+// which must be a struct; see SchemaFor for how its schema is derived.
+//
+// Providers implementing models.StructuredResponder, such as OpenAI and Claude,
+// constrain the response natively. Other providers are sent the schema with
+// instructions to respond with JSON only. Responses that are not valid JSON,
+// do not conform to the schema or fail T's Validate method are sent back to the
+// model with the problems found, up to opts.MaxAttempts times.
+// Every turn is appended to the conversation.
+func GenerateFrom[T any](ctx context.Context, provider models.Provider, conversation *models.Conversation, opts Options) (T, error) {
+	var result T
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	schema, err := Schema(t)
+	if err != nil {
+		return result, err
+	}
+
+	format := models.ResponseFormat{
+		Name:        opts.Name,
+		Description: opts.Description,
+		Schema:      schema,
+		Strict:      isStrict(schema),
+	}
+	if format.Name == "" {
+		format.Name = formatName(t)
+	}
+	if format.Description == "" {
+		format.Description = defaultDescription
+	}
+
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	responder, native := provider.(models.StructuredResponder)
+	if !native {
+		instructions, err := formatInstructions(format)
+		if err != nil {
+			return result, err
+		}
+		conversation.Append(models.Message{Role: models.RoleUser, Content: instructions})
+	}
+
+	var response string
+	var problems []string
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			conversation.Append(feedback(conversation, format.Name, problems))
+		}
+
+		if native {
+			response, err = responder.SendStructured(ctx, conversation, format)
+		} else {
+			response, err = provider.SendConversation(ctx, conversation)
+			response = extractJSON(response)
+		}
+		if err != nil {
+			return result, err
+		}
+
+		var decoded T
+		problems = decode(response, schema, &decoded)
+		if len(problems) == 0 {
+			return decoded, nil
+		}
+	}
+
+	return result, &ValidationError{Attempts: maxAttempts, Response: response, Problems: problems}
+}
+
+// decode validates the response and decodes it into target, returning the
+// problems found
+func decode(response string, schema models.InputSchema, target any) []string {
+	if problems := validateJSON(response, schema); len(problems) > 0 {
+		return problems
+	}
+
+	if err := json.Unmarshal([]byte(response), target); err != nil {
+		return []string{err.Error()}
+	}
+
+	if validator, ok := target.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return []string{err.Error()}
+		}
+	}
+
+	return nil
+}
+
+// feedback builds the message asking the model to correct its response. When
+// the response was a forced tool call, the feedback is that call's result.
+func feedback(conversation *models.Conversation, name string, problems []string) models.Message {
+	text := "Your response was invalid:\n- " + strings.Join(problems, "\n- ") +
+		"\nRespond again, correcting these problems."
+
+	if last, ok := conversation.Last(); ok && last.Role == models.RoleAssistant {
+		for _, block := range last.Blocks {
+			if block.Type == models.BlockToolUse && block.Name == name {
+				return models.Message{
+					Role:   models.RoleUser,
+					Blocks: []models.ContentBlock{models.ToolResultBlock(block.ID, text, true)},
+				}
+			}
+		}
+	}
+
+	return models.Message{Role: models.RoleUser, Content: text}
+}
+
+// formatInstructions asks providers without a native mechanism for JSON output
+func formatInstructions(format models.ResponseFormat) (string, error) {
+	schema, err := json.MarshalIndent(format.Schema, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s\nRespond with a single JSON object matching this JSON Schema, without any other text:\n%s",
+		format.Description, schema), nil
+}
+
+// extractJSON strips text around the JSON object of a response, such as
+// markdown code fences
+func extractJSON(response string) string {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end < start {
+		return strings.TrimSpace(response)
+	}
+	return response[start : end+1]
+}
+
+// formatName names the response format after the type, if it makes a valid name
+func formatName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if validName.MatchString(t.Name()) {
+		return t.Name()
+	}
+	return "response"
+}
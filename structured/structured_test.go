@@ -0,0 +1,279 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/devOpifex/bond/bondtest"
+	"github.com/devOpifex/bond/models"
+	"github.com/devOpifex/bond/providers/claude"
+	"github.com/devOpifex/bond/providers/openai"
+)
+
+type Address struct {
+	City    string `json:"city"`
+	Country string `json:"country" description:"ISO 3166 country code"`
+}
+
+type Person struct {
+	Name    string   `json:"name"`
+	Age     int      `json:"age"`
+	Role    string   `json:"role" enum:"admin,member"`
+	Emails  []string `json:"emails,omitempty"`
+	Address *Address `json:"address"`
+	secret  string
+}
+
+// Validate rejects implausible ages
+func (p Person) Validate() error {
+	if p.Age > 150 {
+		return errors.New("age must be at most 150")
+	}
+	return nil
+}
+
+type Review struct {
+	Summary string `json:"summary"`
+	Score   int    `json:"score"`
+}
+
+func TestSchemaFor(t *testing.T) {
+	schema, err := SchemaFor[Person]()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if strings.Join(schema.Required, ",") != "name,age,role" {
+		t.Errorf("Unexpected required properties %v", schema.Required)
+	}
+
+	if schema.Properties["age"].Type != "integer" || schema.Properties["emails"].Items.Type != "string" {
+		t.Errorf("Unexpected property types %+v", schema.Properties)
+	}
+
+	if len(schema.Properties["role"].Enum) != 2 {
+		t.Errorf("Expected the enum tag to be used, got %v", schema.Properties["role"].Enum)
+	}
+
+	address := schema.Properties["address"]
+	if address.Type != "object" || address.Properties["country"].Description != "ISO 3166 country code" {
+		t.Errorf("Unexpected nested schema %+v", address)
+	}
+
+	if _, ok := schema.Properties["secret"]; ok {
+		t.Errorf("Expected unexported fields to be skipped")
+	}
+
+	if isStrict(schema) {
+		t.Errorf("Expected a schema with optional properties not to be strict")
+	}
+
+	if _, err := SchemaFor[[]string](); err == nil {
+		t.Errorf("Expected an error for non-struct types")
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	schema, _ := SchemaFor[Person]()
+
+	problems := validateJSON(`{"name": "Ada", "age": 36.5, "role": "owner", "nickname": "A"}`, schema)
+
+	expected := []string{
+		`$.age: expected an integer, got 36.5`,
+		`$: unknown property "nickname"`,
+		`$.role: must be one of [admin member], got string "owner"`,
+	}
+	if strings.Join(problems, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected problems:\n%s", strings.Join(problems, "\n"))
+	}
+
+	if problems := validateJSON(`{"name": "Ada", "age": 36, "role": "admin", "address": null}`, schema); len(problems) > 0 {
+		t.Errorf("Expected a valid document, got %v", problems)
+	}
+}
+
+// TestGenerateFallback tests prompting providers without native support and re-prompting on errors
+func TestGenerateFallback(t *testing.T) {
+	fake := bondtest.NewFakeProvider(
+		bondtest.Reply("Sure! ```json\n{\"name\": \"Ada\", \"age\": 236, \"role\": \"admin\"}\n```"),
+		bondtest.Reply(`{"name": "Ada", "age": 36, "role": "admin"}`),
+	)
+
+	person, err := Generate[Person](context.Background(), fake, "Who wrote the first program?")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if person.Name != "Ada" || person.Age != 36 {
+		t.Errorf("Unexpected person %+v", person)
+	}
+
+	fake.AssertRequests(t, 2)
+	fake.AssertReceived(t, "JSON Schema")
+	fake.AssertReceived(t, "age must be at most 150")
+}
+
+// TestGenerateGivesUp tests the error returned once the attempts are exhausted
+func TestGenerateGivesUp(t *testing.T) {
+	fake := bondtest.NewFakeProvider(bondtest.Reply("I don't know"), bondtest.Reply(`{"name": "Ada"}`))
+
+	conversation := models.NewConversation("").Append(models.Message{Role: models.RoleUser, Content: "Who?"})
+	_, err := GenerateFrom[Person](context.Background(), fake, conversation, Options{MaxAttempts: 2})
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+
+	if validationErr.Attempts != 2 || validationErr.Response != `{"name": "Ada"}` {
+		t.Errorf("Unexpected error %+v", validationErr)
+	}
+}
+
+// TestGenerateOpenAI tests that OpenAI is sent a strict json_schema response format
+func TestGenerateOpenAI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request openai.OpenAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			return
+		}
+
+		format := request.ResponseFormat
+		if format == nil || format.Type != "json_schema" || format.JSONSchema.Name != "Review" || !format.JSONSchema.Strict {
+			t.Errorf("Unexpected response format %+v", format)
+		}
+
+		if len(request.Tools) != 0 {
+			t.Errorf("Expected no tools to be offered")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"model": "gpt-4o",
+			"choices": [{"message": {"role": "assistant", "content": "{\"summary\": \"Great\", \"score\": 5}"}, "finish_reason": "stop"}]
+		}`))
+	}))
+	defer server.Close()
+
+	client := openai.NewClient("test-api-key")
+	client.BaseURL = server.URL
+
+	review, err := Generate[Review](context.Background(), client, "Review the film")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if review != (Review{Summary: "Great", Score: 5}) {
+		t.Errorf("Unexpected review %+v", review)
+	}
+}
+
+// TestGenerateOpenAIInvalidJSON tests that a truncated OpenAI response is sent
+// back with the problem rather than failing the call
+func TestGenerateOpenAIInvalidJSON(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		var request openai.OpenAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			w.Write([]byte(`{
+				"model": "gpt-4o",
+				"choices": [{"message": {"role": "assistant", "content": "{\"summary\": \"Gre"}, "finish_reason": "length"}]
+			}`))
+			return
+		}
+
+		last := request.Messages[len(request.Messages)-1]
+		if last.Role != models.RoleUser || !strings.Contains(last.Content, "not valid JSON") {
+			t.Errorf("Expected the problem to be sent back, got %+v", last)
+		}
+
+		w.Write([]byte(`{
+			"model": "gpt-4o",
+			"choices": [{"message": {"role": "assistant", "content": "{\"summary\": \"Great\", \"score\": 5}"}, "finish_reason": "stop"}]
+		}`))
+	}))
+	defer server.Close()
+
+	client := openai.NewClient("test-api-key")
+	client.BaseURL = server.URL
+
+	review, err := Generate[Review](context.Background(), client, "Review the film")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if review != (Review{Summary: "Great", Score: 5}) || requests != 2 {
+		t.Errorf("Expected the review after 2 requests, got %+v after %d", review, requests)
+	}
+}
+
+// TestGenerateClaude tests the forced tool call and that corrections are sent as its result
+func TestGenerateClaude(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+
+		var payload struct {
+			ToolChoice map[string]string `json:"tool_choice"`
+			Messages   []struct {
+				Content []map[string]any `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			return
+		}
+
+		if payload.ToolChoice["type"] != "tool" || payload.ToolChoice["name"] != "Review" {
+			t.Errorf("Unexpected tool choice %v", payload.ToolChoice)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			w.Write([]byte(`{
+				"content": [{"type": "tool_use", "id": "toolu_1", "name": "Review", "input": {"summary": "Great"}}],
+				"stop_reason": "tool_use"
+			}`))
+			return
+		}
+
+		last := payload.Messages[len(payload.Messages)-1].Content[0]
+		if last["type"] != "tool_result" || last["tool_use_id"] != "toolu_1" || last["is_error"] != true {
+			t.Errorf("Expected the correction as an error tool_result, got %v", last)
+		}
+
+		w.Write([]byte(`{
+			"content": [{"type": "tool_use", "id": "toolu_2", "name": "Review", "input": {"summary": "Great", "score": 5}}],
+			"stop_reason": "tool_use"
+		}`))
+	}))
+	defer server.Close()
+
+	provider := claude.NewClient("test-api-key")
+	provider.BaseURL = server.URL
+
+	review, err := Generate[Review](context.Background(), provider, "Review the film")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if review != (Review{Summary: "Great", Score: 5}) || requests != 2 {
+		t.Errorf("Unexpected review %+v after %d requests", review, requests)
+	}
+}
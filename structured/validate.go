@@ -0,0 +1,166 @@
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// Validator is implemented by output types with rules beyond what the schema
+// expresses. Validate is called on every decoded response, and its error is
+// sent back to the model so it can correct the response.
+type Validator interface {
+	Validate() error
+}
+
+// validateJSON checks a JSON document against the schema and returns the
+// problems found, one per entry, in the order they were found
+func validateJSON(data string, schema models.InputSchema) []string {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return []string{fmt.Sprintf("the response is not valid JSON: %v", err)}
+	}
+
+	root := models.Property{
+		Type:                 "object",
+		Properties:           schema.Properties,
+		Required:             schema.Required,
+		AdditionalProperties: schema.AdditionalProperties,
+	}
+
+	var problems []string
+	validateValue(value, root, "$", &problems)
+	return problems
+}
+
+// validateValue checks a decoded value against a property of the schema
+func validateValue(value any, prop models.Property, path string, problems *[]string) {
+	fail := func(format string, args ...any) {
+		*problems = append(*problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch prop.Type {
+	case "string":
+		if _, ok := value.(string); !ok {
+			fail("expected a string, got %s", describe(value))
+			return
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("expected a boolean, got %s", describe(value))
+			return
+		}
+	case "number", "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			fail("expected a %s, got %s", prop.Type, describe(value))
+			return
+		}
+		if prop.Type == "integer" {
+			if f, err := number.Float64(); err != nil || f != math.Trunc(f) {
+				fail("expected an integer, got %s", number)
+				return
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			fail("expected an array, got %s", describe(value))
+			return
+		}
+		if prop.Items != nil {
+			for i, item := range items {
+				validateValue(item, *prop.Items, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			fail("expected an object, got %s", describe(value))
+			return
+		}
+		validateObject(object, prop, path, problems)
+	}
+
+	if len(prop.Enum) > 0 && !inEnum(value, prop.Enum) {
+		fail("must be one of %v, got %s", prop.Enum, describe(value))
+	}
+}
+
+// validateObject checks the required, known and nested properties of an object
+func validateObject(object map[string]any, prop models.Property, path string, problems *[]string) {
+	for _, name := range prop.Required {
+		if _, ok := object[name]; !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: missing required property %q", path, name))
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		nested, known := prop.Properties[name]
+		if !known {
+			if prop.AdditionalProperties != nil && !*prop.AdditionalProperties {
+				*problems = append(*problems, fmt.Sprintf("%s: unknown property %q", path, name))
+			}
+			continue
+		}
+
+		// Optional properties may be null
+		if object[name] == nil && !contains(prop.Required, name) {
+			continue
+		}
+
+		validateValue(object[name], nested, path+"."+name, problems)
+	}
+}
+
+// inEnum reports whether the value is one of the allowed values
+func inEnum(value any, enum []any) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe names the JSON type of a decoded value for error messages
+func describe(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("string %q", v)
+	case bool:
+		return fmt.Sprintf("boolean %v", v)
+	case json.Number:
+		return "number " + v.String()
+	case []any:
+		return "an array"
+	case map[string]any:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// contains reports whether names includes name
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
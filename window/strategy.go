@@ -0,0 +1,155 @@
+package window
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// Fits reports whether the turns fit within the limit
+type Fits func(ctx context.Context, turns []Turn) (bool, error)
+
+// Strategy shortens the turns of a conversation exceeding the limit. The
+// Manager checks the result and fails with ErrDoesNotFit if it still exceeds it.
+type Strategy interface {
+	Fit(ctx context.Context, turns []Turn, fits Fits) ([]Turn, error)
+}
+
+// DropOldest drops the oldest turns until the conversation fits. The latest
+// turn is always kept and the conversation resumes at a user turn, as most
+// providers require. Agents whose only user turn is the task, like ReactAgent,
+// should use KeepFirstLast instead.
+type DropOldest struct{}
+
+// Fit implements the Strategy interface.
+func (DropOldest) Fit(ctx context.Context, turns []Turn, fits Fits) ([]Turn, error) {
+	return keepTail(ctx, turns, 0, 0, fits)
+}
+
+// KeepFirstLast keeps the first turns, which usually state the task, and the
+// last turns, dropping those in between. If that is still too long, the oldest
+// of the last turns are dropped as well.
+type KeepFirstLast struct {
+	// First is the number of turns kept from the start of the conversation
+	First int
+
+	// Last is the maximum number of turns kept from the end of the conversation
+	Last int
+}
+
+// Fit implements the Strategy interface.
+func (k KeepFirstLast) Fit(ctx context.Context, turns []Turn, fits Fits) ([]Turn, error) {
+	first := max(0, min(k.First, len(turns)-1))
+	return keepTail(ctx, turns, first, max(first, len(turns)-k.Last), fits)
+}
+
+// DefaultSummaryPrompt asks the model for a summary of earlier turns
+const DefaultSummaryPrompt = "Summarise the following conversation between a user and an assistant. " +
+	"Keep the facts, decisions, tool results and open questions needed to continue it, and nothing else."
+
+// summaryPrefix introduces the summary in the shortened conversation
+const summaryPrefix = "Summary of the earlier conversation:\n"
+
+// Summarize replaces older turns with a summary written by another provider,
+// typically a cheaper model, keeping the most recent turns verbatim. If the
+// result is still too long, the oldest of the recent turns are dropped.
+// The summary is sent as a user message and is itself summarised when the
+// conversation outgrows the limit again.
+type Summarize struct {
+	// Provider writes the summary
+	Provider models.Provider
+
+	// Keep is the number of recent turns kept verbatim
+	Keep int
+
+	// Prompt asks for the summary, DefaultSummaryPrompt when empty
+	Prompt string
+}
+
+// Fit implements the Strategy interface.
+func (s Summarize) Fit(ctx context.Context, turns []Turn, fits Fits) ([]Turn, error) {
+	// The latest turn is always kept
+	split := max(0, min(len(turns)-s.Keep, len(turns)-1))
+	if split == 0 {
+		return DropOldest{}.Fit(ctx, turns, fits)
+	}
+
+	prompt := s.Prompt
+	if prompt == "" {
+		prompt = DefaultSummaryPrompt
+	}
+
+	summary, err := s.Provider.SendMessage(ctx, models.Message{
+		Role:    models.RoleUser,
+		Content: prompt + "\n\n" + transcript(Messages(turns[:split])),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarise the conversation: %w", err)
+	}
+
+	summarised := append([]Turn{{{Role: models.RoleUser, Content: summaryPrefix + summary}}}, turns[split:]...)
+	return keepTail(ctx, summarised, 1, 1, fits)
+}
+
+// keepTail keeps the first turns and the longest tail starting at or after
+// start that fits. Without first turns, the tail must start at a user turn.
+// The last turn is always kept.
+func keepTail(ctx context.Context, turns []Turn, first int, start int, fits Fits) ([]Turn, error) {
+	if len(turns) == 0 {
+		return turns, nil
+	}
+
+	last := len(turns) - 1
+	for ; start < last; start++ {
+		if first == 0 && start > 0 && !turns[start].isUser() {
+			continue
+		}
+
+		candidate := append(append([]Turn{}, turns[:first]...), turns[start:]...)
+		ok, err := fits(ctx, candidate)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return candidate, nil
+		}
+	}
+
+	return append(append([]Turn{}, turns[:first]...), turns[last:]...), nil
+}
+
+// transcript renders messages as text for the summarising model
+func transcript(messages []models.Message) string {
+	var b strings.Builder
+
+	for _, message := range messages {
+		if len(message.Blocks) == 0 {
+			if message.ToolUse != nil {
+				fmt.Fprintf(&b, "%s called tool %s\n", message.Role, message.ToolUse.Name)
+			}
+			if message.Content != "" {
+				fmt.Fprintf(&b, "%s: %s\n", message.Role, message.Content)
+			}
+			continue
+		}
+
+		for _, block := range message.Blocks {
+			switch block.Type {
+			case models.BlockText:
+				fmt.Fprintf(&b, "%s: %s\n", message.Role, block.Text)
+			case models.BlockToolUse:
+				fmt.Fprintf(&b, "%s called tool %s with %s\n", message.Role, block.Name, block.Input)
+			case models.BlockToolResult:
+				fmt.Fprintf(&b, "tool result: %s\n", block.Content)
+			case models.BlockImage:
+				fmt.Fprintf(&b, "%s: [image]\n", message.Role)
+			case models.BlockDocument:
+				fmt.Fprintf(&b, "%s: [document %s]\n", message.Role, block.Name)
+			}
+		}
+	}
+
+	return b.String()
+}
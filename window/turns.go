@@ -0,0 +1,49 @@
+package window
+
+import "github.com/devOpifex/bond/models"
+
+// Turn is a run of messages that strategies keep or drop as a whole: a message
+// followed by the tool results answering it, if any.
+type Turn []models.Message
+
+// Turns groups messages into turns, attaching tool results to the turn of the
+// tool calls they answer so that the two are never separated.
+func Turns(messages []models.Message) []Turn {
+	var turns []Turn
+	for _, message := range messages {
+		if isToolResult(message) && len(turns) > 0 {
+			turns[len(turns)-1] = append(turns[len(turns)-1], message)
+			continue
+		}
+		turns = append(turns, Turn{message})
+	}
+	return turns
+}
+
+// Messages flattens turns back into a list of messages.
+func Messages(turns []Turn) []models.Message {
+	messages := []models.Message{}
+	for _, turn := range turns {
+		messages = append(messages, turn...)
+	}
+	return messages
+}
+
+// isUser reports whether the turn starts with a message from the user, where
+// a shortened conversation may start
+func (t Turn) isUser() bool {
+	return len(t) > 0 && t[0].Role == models.RoleUser
+}
+
+// isToolResult reports whether the message carries the result of a tool call
+func isToolResult(message models.Message) bool {
+	if message.Role == models.RoleFunction || message.ToolResult != nil {
+		return true
+	}
+	for _, block := range message.Blocks {
+		if block.Type == models.BlockToolResult {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+// Package window keeps conversations within a model's context window. A
+// Manager wraps a provider and, before each request the provider sends,
+// estimates the conversation's size and applies a Strategy when it exceeds the
+// limit: dropping the oldest turns, keeping the first and last turns, or
+// summarising older turns with a cheaper model. Tool calls are never separated
+// from their results.
+package window
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/devOpifex/bond/models"
+)
+
+// ErrDoesNotFit is returned when a conversation cannot be brought within the
+// limit, typically because its latest turn alone exceeds it
+var ErrDoesNotFit = errors.New("conversation does not fit in the context window")
+
+// Counter counts the tokens of a list of messages
+type Counter func(ctx context.Context, messages []models.Message) (int, error)
+
+// Estimate counts tokens with models.EstimateTokens. It is the default Counter.
+func Estimate(ctx context.Context, messages []models.Message) (int, error) {
+	return models.EstimateTokens(messages), nil
+}
+
+// ProviderCounter counts tokens with the provider's CountTokens method. With
+// Claude, every count is a request to the token counting endpoint, and
+// strategies count several candidate conversations per fit.
+func ProviderCounter(provider models.Provider) Counter {
+	return func(ctx context.Context, messages []models.Message) (int, error) {
+		return provider.CountTokens(ctx, messages, nil)
+	}
+}
+
+// Manager is a provider that fits conversations within a token limit before
+// every request the wrapped provider sends, including each round of its tool
+// loop. Turns removed by the strategy are removed from the conversation itself,
+// so that later calls, such as the next iteration of a ReactAgent, start from
+// the fitted conversation.
+//
+// Only SendConversation is managed: single messages are sent as they are. The
+// wrapped provider applies the fit through models.FitConversation, as every
+// provider in this module does.
+type Manager struct {
+	// Provider is the wrapped provider, which handles every other method
+	models.Provider
+
+	// Limit is the number of tokens the system prompt and messages may use.
+	// Leave room for the tool definitions and the response.
+	Limit int
+
+	// Strategy shortens conversations exceeding the limit
+	Strategy Strategy
+
+	// Counter counts tokens, Estimate when nil
+	Counter Counter
+}
+
+// New wraps the provider so that conversations are fitted within limit tokens
+// with the given strategy.
+func New(provider models.Provider, limit int, strategy Strategy) *Manager {
+	return &Manager{
+		Provider: provider,
+		Limit:    limit,
+		Strategy: strategy,
+	}
+}
+
+// SendConversation sends the conversation to the wrapped provider, which fits
+// it within the limit before every request.
+// This implements part of the models.Provider interface.
+func (m *Manager) SendConversation(ctx context.Context, conversation *models.Conversation) (string, error) {
+	return m.Provider.SendConversation(models.WithFitter(ctx, m.fit), conversation)
+}
+
+// fit is the fitter applied by the wrapped provider. It detaches itself from
+// the context, so that requests made by the strategy, such as Summarize's, are
+// not fitted in turn.
+func (m *Manager) fit(ctx context.Context, conversation *models.Conversation) error {
+	return m.Fit(models.WithFitter(ctx, nil), conversation)
+}
+
+// Fit applies the strategy to the conversation if it exceeds the limit,
+// replacing its messages with the fitted ones. It fails with ErrDoesNotFit
+// when the strategy cannot bring it within the limit.
+func (m *Manager) Fit(ctx context.Context, conversation *models.Conversation) error {
+	fits := func(ctx context.Context, turns []Turn) (bool, error) {
+		tokens, err := m.count(ctx, conversation.SystemPrompt, turns)
+		return tokens <= m.Limit, err
+	}
+
+	turns := Turns(conversation.Messages)
+	if ok, err := fits(ctx, turns); err != nil || ok {
+		return err
+	}
+
+	fitted, err := m.Strategy.Fit(ctx, turns, fits)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := m.count(ctx, conversation.SystemPrompt, fitted)
+	if err != nil {
+		return err
+	}
+	if tokens > m.Limit {
+		return fmt.Errorf("%w: %d tokens for a limit of %d", ErrDoesNotFit, tokens, m.Limit)
+	}
+
+	conversation.Messages = Messages(fitted)
+	return nil
+}
+
+// count counts the tokens of the system prompt and the turns
+func (m *Manager) count(ctx context.Context, systemPrompt string, turns []Turn) (int, error) {
+	counter := m.Counter
+	if counter == nil {
+		counter = Estimate
+	}
+
+	messages := Messages(turns)
+	if systemPrompt != "" {
+		messages = append([]models.Message{{Role: models.RoleSystem, Content: systemPrompt}}, messages...)
+	}
+
+	return counter(ctx, messages)
+}
@@ -0,0 +1,208 @@
+package window
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/devOpifex/bond/bondtest"
+	"github.com/devOpifex/bond/models"
+)
+
+// countMessages counts one token per message, which makes limits easy to reason about
+func countMessages(ctx context.Context, messages []models.Message) (int, error) {
+	return len(messages), nil
+}
+
+func user(text string) models.Message {
+	return models.Message{Role: models.RoleUser, Content: text}
+}
+
+func assistant(text string) models.Message {
+	return models.Message{Role: models.RoleAssistant, Content: text}
+}
+
+// toolExchange is an assistant tool call and the user message carrying its result
+func toolExchange(id string) []models.Message {
+	return []models.Message{
+		{Role: models.RoleAssistant, Blocks: []models.ContentBlock{models.ToolUseBlock(id, "get_weather", json.RawMessage(`{}`))}},
+		{Role: models.RoleUser, Blocks: []models.ContentBlock{models.ToolResultBlock(id, "5°C", false)}},
+	}
+}
+
+// contents lists the text of the messages, or the tool call ID for tool messages
+func contents(messages []models.Message) string {
+	var parts []string
+	for _, message := range messages {
+		switch {
+		case message.Content != "":
+			parts = append(parts, message.Content)
+		case message.Blocks[0].Type == models.BlockToolUse:
+			parts = append(parts, "use:"+message.Blocks[0].ID)
+		default:
+			parts = append(parts, "result:"+message.Blocks[0].ToolUseID)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// TestTurns tests that tool results are grouped with the calls they answer
+func TestTurns(t *testing.T) {
+	messages := append([]models.Message{user("Weather?")}, toolExchange("call_1")...)
+	messages = append(messages,
+		assistant("Let me check again."),
+		models.Message{Role: models.RoleFunction, Content: "6°C", ToolResult: &models.ToolResult{Name: "get_weather"}},
+	)
+
+	turns := Turns(messages)
+	if len(turns) != 3 || len(turns[1]) != 2 || len(turns[2]) != 2 {
+		t.Fatalf("Expected turns of 1, 2 and 2 messages, got %v", turns)
+	}
+
+	if contents(Messages(turns)) != contents(messages) {
+		t.Errorf("Expected flattening to restore the messages")
+	}
+}
+
+// TestDropOldest tests that the oldest turns are dropped without splitting tool exchanges
+func TestDropOldest(t *testing.T) {
+	conversation := models.NewConversation("Be brief")
+	conversation.Append(user("one"), assistant("two"), user("three"))
+	conversation.Append(toolExchange("call_1")...)
+	conversation.Append(assistant("four"), user("five"))
+
+	manager := New(nil, 5, DropOldest{})
+	manager.Counter = countMessages
+
+	if err := manager.Fit(context.Background(), conversation); err != nil {
+		t.Fatalf("Failed to fit: %v", err)
+	}
+
+	// The system prompt counts as one token, and the conversation resumes at a user turn
+	if got := contents(conversation.Messages); got != "five" {
+		t.Errorf("Expected only the latest user turn to fit, got %s", got)
+	}
+
+	conversation = models.NewConversation("")
+	conversation.Append(user("one"), assistant("two"), user("three"))
+	conversation.Append(toolExchange("call_1")...)
+	manager.Limit = 4
+
+	if err := manager.Fit(context.Background(), conversation); err != nil {
+		t.Fatalf("Failed to fit: %v", err)
+	}
+	if got := contents(conversation.Messages); got != "three,use:call_1,result:call_1" {
+		t.Errorf("Expected the tool exchange to be kept whole, got %s", got)
+	}
+}
+
+// TestKeepFirstLast tests that the task and the latest turns of an agent run are kept
+func TestKeepFirstLast(t *testing.T) {
+	conversation := models.NewConversation("")
+	conversation.Append(user("task"))
+	for _, id := range []string{"call_1", "call_2", "call_3"} {
+		conversation.Append(toolExchange(id)...)
+	}
+
+	manager := New(nil, 5, KeepFirstLast{First: 1, Last: 3})
+	manager.Counter = countMessages
+
+	if err := manager.Fit(context.Background(), conversation); err != nil {
+		t.Fatalf("Failed to fit: %v", err)
+	}
+
+	if got := contents(conversation.Messages); got != "task,use:call_2,result:call_2,use:call_3,result:call_3" {
+		t.Errorf("Unexpected fitted conversation %s", got)
+	}
+}
+
+// TestSummarize tests that older turns are replaced with a summary from another provider
+func TestSummarize(t *testing.T) {
+	summariser := bondtest.NewFakeProvider(bondtest.Reply("The user asked about Brussels."))
+	model := bondtest.NewFakeProvider(bondtest.Reply("It is 5°C."))
+
+	conversation := models.NewConversation("")
+	conversation.Append(user("Where is Brussels?"), assistant("In Belgium."), user("Weather?"))
+	conversation.Append(toolExchange("call_1")...)
+	conversation.Append(user("And tomorrow?"))
+
+	manager := New(model, 4, Summarize{Provider: summariser, Keep: 2})
+	manager.Counter = countMessages
+
+	response, err := manager.SendConversation(context.Background(), conversation)
+	if err != nil {
+		t.Fatalf("Failed to send conversation: %v", err)
+	}
+	if response != "It is 5°C." {
+		t.Errorf("Unexpected response '%s'", response)
+	}
+
+	summariser.AssertReceived(t, "user: Where is Brussels?")
+	summariser.AssertReceived(t, "assistant: In Belgium.")
+
+	request, _ := model.LastRequest()
+	if got := contents(request.Messages); got != summaryPrefix+"The user asked about Brussels.,use:call_1,result:call_1,And tomorrow?" {
+		t.Errorf("Unexpected summarised conversation %s", got)
+	}
+}
+
+// TestToolRounds tests that every round of the provider's tool loop is fitted, not only the first request
+func TestToolRounds(t *testing.T) {
+	model := bondtest.NewFakeProvider(
+		bondtest.CallTool("get_weather", map[string]any{"city": "Paris"}),
+		bondtest.CallTool("get_weather", map[string]any{"city": "Rome"}),
+		bondtest.CallTool("get_weather", map[string]any{"city": "Oslo"}),
+		bondtest.Reply("Done."),
+	)
+
+	conversation := models.NewConversation("")
+	conversation.Append(user("Weather?"))
+
+	manager := New(model, 4, KeepFirstLast{First: 1, Last: 1})
+	manager.Counter = countMessages
+
+	if _, err := manager.SendConversation(context.Background(), conversation); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	model.AssertRequests(t, 4)
+	for i, request := range model.Requests() {
+		if len(request.Messages) > 4 {
+			t.Errorf("Expected request %d to be fitted, got %d messages", i, len(request.Messages))
+		}
+	}
+
+	// The task, the last tool exchange and the answer
+	if conversation.Len() != 4 || conversation.Messages[0].Content != "Weather?" {
+		t.Errorf("Expected the fitted conversation to be kept, got %+v", conversation.Messages)
+	}
+}
+
+// TestFitTooLarge tests that a conversation whose latest turn exceeds the limit is rejected
+func TestFitTooLarge(t *testing.T) {
+	model := bondtest.NewFakeProvider()
+
+	conversation := models.NewConversation("")
+	conversation.Append(user("Weather?"))
+	conversation.Append(toolExchange("call_1")...)
+
+	manager := New(model, 1, DropOldest{})
+	manager.Counter = countMessages
+
+	if _, err := manager.SendConversation(context.Background(), conversation); !errors.Is(err, ErrDoesNotFit) {
+		t.Errorf("Expected ErrDoesNotFit, got %v", err)
+	}
+	model.AssertRequests(t, 0)
+}
+
+// TestEstimate tests that the default counter grows with the conversation
+func TestEstimate(t *testing.T) {
+	short, _ := Estimate(context.Background(), []models.Message{user("Hi")})
+	long, _ := Estimate(context.Background(), []models.Message{user(strings.Repeat("weather ", 100))})
+
+	if short <= 0 || long <= short || long < 200 {
+		t.Errorf("Unexpected estimates %d and %d", short, long)
+	}
+}